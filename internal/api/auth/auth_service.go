@@ -2,7 +2,6 @@ package auth
 
 import (
 	"errors"
-	"fmt"
 	"net/http"
 	"socialAPI/internal/lib"
 	"socialAPI/internal/setting/cfg"
@@ -43,7 +42,7 @@ func (a authService) generateAndStoreTokens(id uint) (*shared.TokenPair, *shared
 		return nil, shared.InternalError
 	}
 
-	err = a.cache.Set(fmt.Sprintf("access_token:%d", id), tokenPair.AccessToken, a.cfg.AccessTTL)
+	err = a.cache.Set(shared.AccessTokenCacheKey(id), tokenPair.AccessToken, a.cfg.AccessTTL)
 	if err != nil {
 		a.logger.Errorw("Error storing access token in cache", "error", err)
 		return nil, shared.InternalError
@@ -144,7 +143,7 @@ func (a authService) Revoke(r RefreshRequest) *shared.HttpError {
 		return shared.InternalError
 	}
 
-	err = a.cache.Delete(fmt.Sprintf("access_token:%d", userID))
+	err = a.cache.Delete(shared.AccessTokenCacheKey(userID))
 	if err != nil {
 		a.logger.Errorw("Error deleting access token from cache", "userID", userID, "error", err)
 		return shared.InternalError
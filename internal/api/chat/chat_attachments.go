@@ -0,0 +1,189 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"socialAPI/internal/shared"
+	"socialAPI/internal/storage/blob"
+	r "socialAPI/internal/storage/repository"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// Столько байт нужно http.DetectContentType для определения типа
+	sniffLength       = 512
+	maxFileNameLength = 255
+)
+
+// newStorageKey возвращает случайный ключ blob-а в каталоге чата, имя файла в ключ не попадает
+func newStorageKey(chatID uint) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("chats/%d/%s", chatID, hex.EncodeToString(random)), nil
+}
+
+// sanitizeFileName оставляет от имени, присланного клиентом, только последний элемент пути
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		return "file"
+	}
+
+	runes := []rune(name)
+	if len(runes) > maxFileNameLength {
+		name = string(runes[:maxFileNameLength])
+	}
+	return name
+}
+
+func (c chatService) UploadAttachment(userID, chatID uint, fileName string, size int64, file io.Reader) (*r.AttachmentDTO, *shared.HttpError) {
+	c.logger.Infow("Uploading attachment", "userID", userID, "chatID", chatID, "size", size)
+
+	isMember, err := c.chatRepo.IsMember(chatID, userID)
+	if err != nil {
+		c.logger.Errorw("Failed to check chat membership", "userID", userID, "chatID", chatID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	if !isMember {
+		c.logger.Warnw("User is not a member of the chat", "userID", userID, "chatID", chatID)
+		return nil, shared.NewHttpError("chat not found", http.StatusNotFound)
+	}
+
+	if size <= 0 {
+		c.logger.Warnw("Attachment is empty", "userID", userID, "chatID", chatID)
+		return nil, shared.NewHttpError("file is empty", http.StatusBadRequest)
+	}
+
+	if size > c.upload.MaxSize {
+		c.logger.Warnw("Attachment is too large", "userID", userID, "chatID", chatID, "size", size, "maxSize", c.upload.MaxSize)
+		return nil, shared.NewHttpError(fmt.Sprintf("file is larger than %d bytes", c.upload.MaxSize), http.StatusRequestEntityTooLarge)
+	}
+
+	// Тип определяется по содержимому, Content-Type клиента не используется
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		c.logger.Errorw("Failed to read attachment", "userID", userID, "chatID", chatID, "error", err)
+		return nil, shared.InternalError
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+
+	key, err := newStorageKey(chatID)
+	if err != nil {
+		c.logger.Errorw("Failed to generate storage key", "chatID", chatID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	ctx := context.Background()
+	if err := c.blobs.Put(ctx, key, io.MultiReader(bytes.NewReader(head), file), size, contentType); err != nil {
+		c.logger.Errorw("Failed to store attachment", "chatID", chatID, "key", key, "error", err)
+		return nil, shared.InternalError
+	}
+
+	attachment := r.Attachment{
+		ChatID:      chatID,
+		UploaderID:  userID,
+		FileName:    sanitizeFileName(fileName),
+		ContentType: contentType,
+		Size:        size,
+		StorageKey:  key,
+	}
+	if err := c.attachmentRepo.Create(&attachment); err != nil {
+		c.logger.Errorw("Failed to save attachment", "chatID", chatID, "key", key, "error", err)
+		if err := c.blobs.Delete(ctx, key); err != nil {
+			c.logger.Errorw("Failed to delete orphaned blob", "key", key, "error", err)
+		}
+		return nil, shared.InternalError
+	}
+
+	if thumbnailTypes[contentType] {
+		c.thumbnailer.Enqueue(attachment)
+	}
+
+	c.logger.Infow("Attachment successfully uploaded", "chatID", chatID, "attachmentID", attachment.ID, "contentType", contentType)
+
+	dto := attachment.ConvertToDTO()
+	return &dto, nil
+}
+
+// OpenAttachment отдаёт вложение участнику чата. Не привязанное к сообщению вложение видит только
+// загрузивший, вложения удалённых сообщений недоступны
+func (c chatService) OpenAttachment(userID, chatID, attachmentID uint, thumbnail bool) (*AttachmentFile, *shared.HttpError) {
+	c.logger.Infow("Opening attachment", "userID", userID, "chatID", chatID, "attachmentID", attachmentID, "thumbnail", thumbnail)
+
+	isMember, err := c.chatRepo.IsMember(chatID, userID)
+	if err != nil {
+		c.logger.Errorw("Failed to check chat membership", "userID", userID, "chatID", chatID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	if !isMember {
+		c.logger.Warnw("User is not a member of the chat", "userID", userID, "chatID", chatID)
+		return nil, shared.NewHttpError("chat not found", http.StatusNotFound)
+	}
+
+	notFound := shared.NewHttpError("attachment not found", http.StatusNotFound)
+
+	attachment, err := c.attachmentRepo.GetByID(attachmentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.logger.Warnw("Attachment not found", "chatID", chatID, "attachmentID", attachmentID)
+			return nil, notFound
+		}
+		c.logger.Errorw("Failed to fetch attachment", "attachmentID", attachmentID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	if attachment.ChatID != chatID || (attachment.MessageID == nil && attachment.UploaderID != userID) {
+		c.logger.Warnw("Attachment is not available to the user", "userID", userID, "chatID", chatID, "attachmentID", attachmentID)
+		return nil, notFound
+	}
+
+	if attachment.MessageID != nil {
+		message, err := c.messageRepo.GetByID(*attachment.MessageID)
+		if err != nil {
+			c.logger.Errorw("Failed to fetch attachment message", "attachmentID", attachmentID, "messageID", *attachment.MessageID, "error", err)
+			return nil, shared.InternalError
+		}
+
+		if message.DeletedAt != nil {
+			c.logger.Warnw("Attachment message is deleted", "attachmentID", attachmentID, "messageID", message.ID)
+			return nil, notFound
+		}
+	}
+
+	file := &AttachmentFile{FileName: attachment.FileName, ContentType: attachment.ContentType, Size: attachment.Size}
+	key := attachment.StorageKey
+	if thumbnail {
+		if attachment.ThumbnailKey == nil {
+			c.logger.Warnw("Attachment has no thumbnail", "attachmentID", attachmentID)
+			return nil, shared.NewHttpError("thumbnail not found", http.StatusNotFound)
+		}
+		key, file.ContentType, file.Size = *attachment.ThumbnailKey, "image/jpeg", 0
+	}
+
+	file.Body, err = c.blobs.Get(context.Background(), key)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			c.logger.Errorw("Attachment blob is missing", "attachmentID", attachmentID, "key", key)
+			return nil, notFound
+		}
+		c.logger.Errorw("Failed to read attachment blob", "attachmentID", attachmentID, "key", key, "error", err)
+		return nil, shared.InternalError
+	}
+
+	return file, nil
+}
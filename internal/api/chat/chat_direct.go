@@ -0,0 +1,55 @@
+package chat
+
+import (
+	"net/http"
+	"socialAPI/internal/shared"
+)
+
+// GetOrCreateDirect открывает личный чат с peerID, создавая его при первом обращении.
+// При CHAT_DIRECT_FRIENDS_ONLY писать можно только друзьям
+func (c chatService) GetOrCreateDirect(userID, peerID uint) (*DirectChatResponse, *shared.HttpError) {
+	c.logger.Infow("Opening direct chat", "userID", userID, "peerID", peerID)
+
+	if userID == peerID {
+		c.logger.Warnw("Attempt to open direct chat with oneself", "userID", userID)
+		return nil, shared.NewHttpError("cannot open a direct chat with yourself", http.StatusBadRequest)
+	}
+
+	exists, err := c.userRepo.IDsExists([]uint{peerID})
+	if err != nil {
+		c.logger.Errorw("Error checking user existence", "peerID", peerID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	if !exists {
+		c.logger.Warnw("User not found", "peerID", peerID)
+		return nil, shared.NewHttpError("user not found", http.StatusNotFound)
+	}
+
+	if c.chatCfg.DirectFriendsOnly {
+		friends, err := c.friendshipRepo.AreFriends(userID, peerID)
+		if err != nil {
+			c.logger.Errorw("Error checking friendship", "userID", userID, "peerID", peerID, "error", err)
+			return nil, shared.InternalError
+		}
+
+		if !friends {
+			c.logger.Warnw("Direct chat with non-friend rejected", "userID", userID, "peerID", peerID)
+			return nil, shared.NewHttpError("direct chats are only available between friends", http.StatusForbidden)
+		}
+	}
+
+	chatID, created, err := c.chatRepo.GetOrCreateDirect(userID, peerID)
+	if err != nil {
+		c.logger.Errorw("Failed to open direct chat", "userID", userID, "peerID", peerID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	// В новом личном чате ещё нет сообщений, догружать нечего
+	if created {
+		c.hub.UpdateMembership(chatID, 0, []uint{userID, peerID}, nil)
+	}
+
+	c.logger.Infow("Direct chat opened", "userID", userID, "peerID", peerID, "chatID", chatID, "created", created)
+	return &DirectChatResponse{ChatID: chatID, Created: created}, nil
+}
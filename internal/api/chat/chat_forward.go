@@ -0,0 +1,79 @@
+package chat
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"socialAPI/internal/shared"
+	r "socialAPI/internal/storage/repository"
+
+	"gorm.io/gorm"
+)
+
+// ForwardMessage пересылает сообщение чата в другие чаты. Пользователь должен состоять и в исходном,
+// и во всех целевых чатах. Пересланные сообщения рассылаются участникам целевых чатов через хаб
+func (c chatService) ForwardMessage(userID, chatID, messageID uint, req ForwardRequest) ([]ForwardedMessageDTO, *shared.HttpError) {
+	c.logger.Infow("Attempting to forward message", "userID", userID, "chatID", chatID, "messageID", messageID, "targetIDs", req.ChatIDs)
+
+	isMember, err := c.chatRepo.IsMember(chatID, userID)
+	if err != nil {
+		c.logger.Errorw("Failed to check chat membership", "userID", userID, "chatID", chatID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	if !isMember {
+		c.logger.Warnw("User is not a member of the chat", "userID", userID, "chatID", chatID)
+		return nil, shared.NewHttpError("chat not found", http.StatusNotFound)
+	}
+
+	message, err := c.messageRepo.GetByID(messageID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.logger.Errorw("Failed to fetch message", "chatID", chatID, "messageID", messageID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	if message == nil || message.ChatID != chatID {
+		c.logger.Warnw("Message not found", "chatID", chatID, "messageID", messageID)
+		return nil, shared.NewHttpError("message not found", http.StatusNotFound)
+	}
+
+	if message.DeletedAt != nil {
+		c.logger.Warnw("Message is deleted", "chatID", chatID, "messageID", messageID)
+		return nil, shared.NewHttpError("message is deleted", http.StatusGone)
+	}
+
+	if message.Kind != r.MessageKindText {
+		c.logger.Warnw("Only text messages can be forwarded", "chatID", chatID, "messageID", messageID, "kind", message.Kind)
+		return nil, shared.NewHttpError("only text messages can be forwarded", http.StatusBadRequest)
+	}
+
+	targetIDs := slices.Compact(slices.Sorted(slices.Values(req.ChatIDs)))
+	for _, targetID := range targetIDs {
+		isMember, err := c.chatRepo.IsMember(targetID, userID)
+		if err != nil {
+			c.logger.Errorw("Failed to check chat membership", "userID", userID, "chatID", targetID, "error", err)
+			return nil, shared.InternalError
+		}
+
+		if !isMember {
+			c.logger.Warnw("User is not a member of the target chat", "userID", userID, "chatID", targetID)
+			return nil, shared.NewHttpError("target chat not found", http.StatusNotFound)
+		}
+	}
+
+	forwarded, err := c.messageRepo.Forward(message, userID, targetIDs)
+	if err != nil {
+		c.logger.Errorw("Failed to forward message", "userID", userID, "chatID", chatID, "messageID", messageID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	c.hub.PublishSaved(forwarded)
+
+	dtos := make([]ForwardedMessageDTO, 0, len(forwarded))
+	for _, record := range forwarded {
+		dtos = append(dtos, ForwardedMessageDTO{ChatID: record.ChatID, MessageID: record.ID})
+	}
+
+	c.logger.Infow("Message forwarded successfully", "userID", userID, "chatID", chatID, "messageID", messageID, "count", len(dtos))
+	return dtos, nil
+}
@@ -0,0 +1,136 @@
+package chat
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"socialAPI/internal/shared"
+	r "socialAPI/internal/storage/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Длина кода приглашения в байтах до hex-кодирования: 128 бит не подобрать перебором
+const inviteCodeBytes = 16
+
+func newInviteCode() (string, error) {
+	b := make([]byte, inviteCodeBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreateInvite создаёт ссылку-приглашение в групповой чат. Доступно владельцу и админам
+func (c chatService) CreateInvite(actorID, chatID uint, req CreateInviteRequest) (*r.ChatInviteDTO, *shared.HttpError) {
+	c.logger.Infow("Attempting to create chat invite", "actorID", actorID, "chatID", chatID, "maxUses", req.MaxUses, "expiresAt", req.ExpiresAt)
+
+	if _, hErr := c.moderatorRole(actorID, chatID); hErr != nil {
+		return nil, hErr
+	}
+
+	chatType, err := c.chatRepo.GetType(chatID)
+	if err != nil {
+		c.logger.Errorw("Failed to fetch chat type", "chatID", chatID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	if chatType != r.ChatTypeGroup {
+		c.logger.Warnw("Invites are only available for group chats", "chatID", chatID, "type", chatType)
+		return nil, shared.NewHttpError("invites are only available for group chats", http.StatusBadRequest)
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.logger.Warnw("Invite expiry is in the past", "chatID", chatID, "expiresAt", req.ExpiresAt)
+		return nil, shared.NewHttpError("expires_at must be in the future", http.StatusBadRequest)
+	}
+
+	code, err := newInviteCode()
+	if err != nil {
+		c.logger.Errorw("Failed to generate invite code", "chatID", chatID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	invite := r.ChatInvite{ChatID: chatID, CreatorID: actorID, Code: code, MaxUses: req.MaxUses, ExpiresAt: req.ExpiresAt}
+	if err := c.chatRepo.CreateInvite(&invite); err != nil {
+		c.logger.Errorw("Failed to create chat invite", "chatID", chatID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	c.logger.Infow("Chat invite created", "actorID", actorID, "chatID", chatID, "inviteID", invite.ID)
+
+	dto := invite.ConvertToDTO()
+	return &dto, nil
+}
+
+// GetInvites возвращает приглашения чата вместе с отозванными и истёкшими
+func (c chatService) GetInvites(actorID, chatID uint) ([]r.ChatInviteDTO, *shared.HttpError) {
+	c.logger.Infow("Fetching chat invites", "actorID", actorID, "chatID", chatID)
+
+	if _, hErr := c.moderatorRole(actorID, chatID); hErr != nil {
+		return nil, hErr
+	}
+
+	invites, err := c.chatRepo.GetInvites(chatID)
+	if err != nil {
+		c.logger.Errorw("Failed to fetch chat invites", "chatID", chatID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	dtos := []r.ChatInviteDTO{}
+	for i := range invites {
+		dtos = append(dtos, invites[i].ConvertToDTO())
+	}
+
+	return dtos, nil
+}
+
+func (c chatService) RevokeInvite(actorID, chatID, inviteID uint) *shared.HttpError {
+	c.logger.Infow("Attempting to revoke chat invite", "actorID", actorID, "chatID", chatID, "inviteID", inviteID)
+
+	if _, hErr := c.moderatorRole(actorID, chatID); hErr != nil {
+		return hErr
+	}
+
+	revoked, err := c.chatRepo.RevokeInvite(chatID, inviteID)
+	if err != nil {
+		c.logger.Errorw("Failed to revoke chat invite", "chatID", chatID, "inviteID", inviteID, "error", err)
+		return shared.InternalError
+	}
+
+	if !revoked {
+		c.logger.Warnw("Invite not found or already revoked", "chatID", chatID, "inviteID", inviteID)
+		return shared.NewHttpError("invite not found", http.StatusNotFound)
+	}
+
+	c.logger.Infow("Chat invite revoked", "actorID", actorID, "chatID", chatID, "inviteID", inviteID)
+	return nil
+}
+
+// JoinByInvite добавляет пользователя в чат по коду. Повторный вход участника не расходует приглашение
+func (c chatService) JoinByInvite(userID uint, code string) (*JoinResponse, *shared.HttpError) {
+	c.logger.Infow("Attempting to join chat by invite", "userID", userID)
+
+	chatID, message, err := c.chatRepo.JoinByInvite(code, userID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.logger.Warnw("Invite not found", "userID", userID)
+		return nil, shared.NewHttpError("invite not found", http.StatusNotFound)
+	case errors.Is(err, r.ErrInviteUnavailable):
+		c.logger.Warnw("Invite is no longer valid", "userID", userID, "chatID", chatID)
+		return nil, shared.NewHttpError("invite is no longer valid", http.StatusGone)
+	case err != nil:
+		c.logger.Errorw("Failed to join chat by invite", "userID", userID, "chatID", chatID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	if message != nil {
+		c.hub.UpdateMembership(chatID, message.ID-1, []uint{userID}, nil)
+		c.hub.PublishMessage(*message)
+	}
+
+	c.logger.Infow("User joined chat by invite", "userID", userID, "chatID", chatID, "alreadyMember", message == nil)
+	return &JoinResponse{ChatID: chatID}, nil
+}
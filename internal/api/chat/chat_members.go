@@ -0,0 +1,217 @@
+package chat
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"socialAPI/internal/shared"
+	r "socialAPI/internal/storage/repository"
+
+	"gorm.io/gorm"
+)
+
+// moderatorRole возвращает роль пользователя, если он может управлять чатом.
+// Не участнику отвечаем 404, обычному участнику - 403
+func (c chatService) moderatorRole(userID, chatID uint) (r.ChatRole, *shared.HttpError) {
+	role, err := c.chatRepo.GetRole(chatID, userID)
+	if err != nil {
+		c.logger.Errorw("Failed to fetch chat role", "chatID", chatID, "userID", userID, "error", err)
+		return "", shared.InternalError
+	}
+
+	if role == "" {
+		c.logger.Warnw("User is not a member of the chat", "chatID", chatID, "userID", userID)
+		return "", shared.NewHttpError("chat not found", http.StatusNotFound)
+	}
+
+	if !role.CanModerate() {
+		c.logger.Warnw("User is not allowed to manage the chat", "chatID", chatID, "userID", userID, "role", role)
+		return "", shared.NewHttpError("only the owner or an admin can manage the chat", http.StatusForbidden)
+	}
+
+	return role, nil
+}
+
+// membershipError переводит ошибки репозитория, означающие, что чат или роль actorID
+// изменились после проверок сервиса. Для остальных ошибок возвращает nil
+func (c chatService) membershipError(err error, actorID, chatID uint) *shared.HttpError {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.logger.Warnw("Chat not found", "chatID", chatID)
+		return shared.NewHttpError("chat not found", http.StatusNotFound)
+	case errors.Is(err, r.ErrNotAllowed):
+		c.logger.Warnw("Member role does not allow the change", "actorID", actorID, "chatID", chatID)
+		return shared.NewHttpError("not allowed to manage this member", http.StatusForbidden)
+	}
+	return nil
+}
+
+// SetRole назначает или снимает админа. Роль владельца не меняется, понизить админа может только владелец.
+// Проверки здесь дают понятную ошибку, окончательно права проверяет репозиторий под блокировкой чата
+func (c chatService) SetRole(actorID, chatID, userID uint, req RoleRequest) *shared.HttpError {
+	c.logger.Infow("Attempting to change member role", "actorID", actorID, "chatID", chatID, "userID", userID, "role", req.Role)
+
+	actorRole, hErr := c.moderatorRole(actorID, chatID)
+	if hErr != nil {
+		return hErr
+	}
+
+	role, err := c.chatRepo.GetRole(chatID, userID)
+	if err != nil {
+		c.logger.Errorw("Failed to fetch chat role", "chatID", chatID, "userID", userID, "error", err)
+		return shared.InternalError
+	}
+
+	switch {
+	case role == "":
+		c.logger.Warnw("Member not found", "chatID", chatID, "userID", userID)
+		return shared.NewHttpError("member not found", http.StatusNotFound)
+	case role == r.ChatRoleOwner:
+		c.logger.Warnw("Attempt to change owner role", "actorID", actorID, "chatID", chatID, "userID", userID)
+		return shared.NewHttpError("owner role cannot be changed", http.StatusForbidden)
+	case role == r.ChatRoleAdmin && req.Role == r.ChatRoleMember && actorRole != r.ChatRoleOwner:
+		c.logger.Warnw("Admin attempted to demote another admin", "actorID", actorID, "chatID", chatID, "userID", userID)
+		return shared.NewHttpError("only the owner can demote admins", http.StatusForbidden)
+	case role == req.Role:
+		c.logger.Infow("Member already has this role", "chatID", chatID, "userID", userID, "role", role)
+		return nil
+	}
+
+	message, err := c.chatRepo.SetRole(chatID, actorID, userID, req.Role)
+	if hErr := c.membershipError(err, actorID, chatID); hErr != nil {
+		return hErr
+	}
+	if err != nil {
+		c.logger.Errorw("Failed to change member role", "chatID", chatID, "userID", userID, "role", req.Role, "error", err)
+		return shared.InternalError
+	}
+
+	// Роль могли поменять параллельно, тогда системного сообщения нет
+	if message != nil {
+		c.hub.PublishMessage(*message)
+	}
+
+	c.logger.Infow("Member role changed", "actorID", actorID, "chatID", chatID, "userID", userID, "role", req.Role)
+	return nil
+}
+
+// AddMembers добавляет пользователей в чат. Уже состоящие в чате пропускаются
+func (c chatService) AddMembers(actorID, chatID uint, req AddMembersRequest) *shared.HttpError {
+	userIDs := slices.Clone(req.UserIDs)
+	slices.Sort(userIDs)
+	userIDs = slices.Compact(userIDs)
+
+	c.logger.Infow("Attempting to add chat members", "actorID", actorID, "chatID", chatID, "userIDs", userIDs)
+
+	if _, hErr := c.moderatorRole(actorID, chatID); hErr != nil {
+		return hErr
+	}
+
+	if hErr := c.checkUsersExist(userIDs); hErr != nil {
+		return hErr
+	}
+
+	added, message, err := c.chatRepo.AddMembers(chatID, actorID, userIDs)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.logger.Warnw("Chat not found", "chatID", chatID)
+		return shared.NewHttpError("chat not found", http.StatusNotFound)
+	}
+	if err != nil {
+		c.logger.Errorw("Failed to add chat members", "chatID", chatID, "userIDs", userIDs, "error", err)
+		return shared.InternalError
+	}
+
+	if message != nil {
+		c.hub.UpdateMembership(chatID, message.ID-1, added, nil)
+		c.hub.PublishMessage(*message)
+	}
+
+	c.logger.Infow("Chat members added", "actorID", actorID, "chatID", chatID, "added", added)
+	return nil
+}
+
+// RemoveMember исключает участника. Владельца исключить нельзя, админов исключает только владелец
+func (c chatService) RemoveMember(actorID, chatID, userID uint) *shared.HttpError {
+	if actorID == userID {
+		return c.Leave(userID, chatID)
+	}
+
+	c.logger.Infow("Attempting to remove chat member", "actorID", actorID, "chatID", chatID, "userID", userID)
+
+	actorRole, hErr := c.moderatorRole(actorID, chatID)
+	if hErr != nil {
+		return hErr
+	}
+
+	role, err := c.chatRepo.GetRole(chatID, userID)
+	if err != nil {
+		c.logger.Errorw("Failed to fetch chat role", "chatID", chatID, "userID", userID, "error", err)
+		return shared.InternalError
+	}
+
+	switch {
+	case role == "":
+		c.logger.Warnw("Member not found", "chatID", chatID, "userID", userID)
+		return shared.NewHttpError("member not found", http.StatusNotFound)
+	case role == r.ChatRoleOwner:
+		c.logger.Warnw("Attempt to remove chat owner", "actorID", actorID, "chatID", chatID, "ownerID", userID)
+		return shared.NewHttpError("chat owner cannot be removed", http.StatusForbidden)
+	case role == r.ChatRoleAdmin && actorRole != r.ChatRoleOwner:
+		c.logger.Warnw("Admin attempted to remove another admin", "actorID", actorID, "chatID", chatID, "adminID", userID)
+		return shared.NewHttpError("only the owner can remove admins", http.StatusForbidden)
+	}
+
+	return c.removeMember(actorID, chatID, userID)
+}
+
+// Leave выводит пользователя из чата. Уходящему владельцу назначается преемник
+func (c chatService) Leave(userID, chatID uint) *shared.HttpError {
+	c.logger.Infow("Attempting to leave chat", "userID", userID, "chatID", chatID)
+
+	isMember, err := c.chatRepo.IsMember(chatID, userID)
+	if err != nil {
+		c.logger.Errorw("Failed to check chat membership", "userID", userID, "chatID", chatID, "error", err)
+		return shared.InternalError
+	}
+
+	if !isMember {
+		c.logger.Warnw("User is not a member of the chat", "userID", userID, "chatID", chatID)
+		return shared.NewHttpError("chat not found", http.StatusNotFound)
+	}
+
+	chatType, err := c.chatRepo.GetType(chatID)
+	if err != nil {
+		c.logger.Errorw("Failed to fetch chat type", "chatID", chatID, "error", err)
+		return shared.InternalError
+	}
+
+	// Личный чат существует, пока существуют оба пользователя, иначе его нельзя было бы открыть заново
+	if chatType == r.ChatTypeDirect {
+		c.logger.Warnw("Attempt to leave direct chat", "userID", userID, "chatID", chatID)
+		return shared.NewHttpError("direct chats cannot be left", http.StatusBadRequest)
+	}
+
+	return c.removeMember(userID, chatID, userID)
+}
+
+func (c chatService) removeMember(actorID, chatID, userID uint) *shared.HttpError {
+	messages, err := c.chatRepo.RemoveMember(chatID, actorID, userID)
+	if hErr := c.membershipError(err, actorID, chatID); hErr != nil {
+		return hErr
+	}
+	if err != nil {
+		c.logger.Errorw("Failed to remove chat member", "actorID", actorID, "chatID", chatID, "userID", userID, "error", err)
+		return shared.InternalError
+	}
+
+	// Пустой результат - участника уже исключили параллельным запросом
+	if len(messages) > 0 {
+		c.hub.UpdateMembership(chatID, 0, nil, []uint{userID})
+		for _, message := range messages {
+			c.hub.PublishMessage(message)
+		}
+	}
+
+	c.logger.Infow("Chat member removed", "actorID", actorID, "chatID", chatID, "userID", userID)
+	return nil
+}
@@ -0,0 +1,32 @@
+package chat
+
+import (
+	"socialAPI/internal/shared"
+	r "socialAPI/internal/storage/repository"
+)
+
+// GetMentions возвращает сообщения с упоминанием пользователя из его чатов, от новых к старым.
+// Страница заканчивается перед beforeID, 0 - первая страница
+func (c chatService) GetMentions(userID, beforeID uint, limit int) (*MentionsResponse, *shared.HttpError) {
+	c.logger.Infow("Fetching mentions", "userID", userID, "beforeID", beforeID, "limit", limit)
+
+	messages, err := c.messageRepo.ListMentions(userID, beforeID, limit+1)
+	if err != nil {
+		c.logger.Errorw("Failed to fetch mentions", "userID", userID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	response := &MentionsResponse{Mentions: make([]r.MentionDTO, 0, min(len(messages), limit))}
+	if len(messages) > limit {
+		messages = messages[:limit]
+		nextBeforeID := messages[limit-1].ID
+		response.NextBeforeID = &nextBeforeID
+	}
+
+	for i := range messages {
+		response.Mentions = append(response.Mentions, r.MentionDTO{ChatID: messages[i].ChatID, Message: messages[i].ConvertToDTO(userID)})
+	}
+
+	c.logger.Infow("Mentions successfully fetched", "userID", userID, "count", len(response.Mentions))
+	return response, nil
+}
@@ -0,0 +1,188 @@
+package chat
+
+import (
+	"errors"
+	"net/http"
+	chatWS "socialAPI/internal/api/chat/ws"
+	"socialAPI/internal/shared"
+	r "socialAPI/internal/storage/repository"
+
+	"gorm.io/gorm"
+)
+
+// authorizeMessageChange проверяет, что сообщение есть в чате, не удалено,
+// а пользователь - участник чата и его отправитель или администратор
+func (c chatService) authorizeMessageChange(userID, chatID, messageID uint) *shared.HttpError {
+	message, err := c.messageRepo.GetByID(messageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.logger.Warnw("Message not found", "chatID", chatID, "messageID", messageID)
+			return shared.NewHttpError("message not found", http.StatusNotFound)
+		}
+		c.logger.Errorw("Failed to fetch message", "chatID", chatID, "messageID", messageID, "error", err)
+		return shared.InternalError
+	}
+
+	if message.ChatID != chatID {
+		c.logger.Warnw("Message belongs to another chat", "chatID", chatID, "messageID", messageID)
+		return shared.NewHttpError("message not found", http.StatusNotFound)
+	}
+
+	if message.DeletedAt != nil {
+		c.logger.Warnw("Message is already deleted", "chatID", chatID, "messageID", messageID)
+		return shared.NewHttpError("message is deleted", http.StatusGone)
+	}
+
+	if message.Kind == r.MessageKindSystem {
+		c.logger.Warnw("System messages cannot be changed", "chatID", chatID, "messageID", messageID, "userID", userID)
+		return shared.NewHttpError("system messages cannot be changed", http.StatusForbidden)
+	}
+
+	role, err := c.chatRepo.GetRole(chatID, userID)
+	if err != nil {
+		c.logger.Errorw("Failed to fetch chat role", "chatID", chatID, "userID", userID, "error", err)
+		return shared.InternalError
+	}
+
+	if role == "" {
+		c.logger.Warnw("User is not a member of the chat", "userID", userID, "chatID", chatID)
+		return shared.NewHttpError("chat not found", http.StatusNotFound)
+	}
+
+	if message.SenderID == userID {
+		return nil
+	}
+
+	if !role.CanModerate() {
+		c.logger.Warnw("User is not allowed to change the message", "chatID", chatID, "messageID", messageID, "userID", userID)
+		return shared.NewHttpError("only the sender or a chat admin can change this message", http.StatusForbidden)
+	}
+
+	return nil
+}
+
+func (c chatService) EditMessage(userID, chatID, messageID uint, req EditMessageRequest) (*r.MessageDTO, *shared.HttpError) {
+	c.logger.Infow("Attempting to edit message", "userID", userID, "chatID", chatID, "messageID", messageID)
+
+	if hErr := c.authorizeMessageChange(userID, chatID, messageID); hErr != nil {
+		return nil, hErr
+	}
+
+	message, err := c.messageRepo.Edit(messageID, userID, req.Content)
+	if errors.Is(err, r.ErrMessageDeleted) {
+		c.logger.Warnw("Message was deleted concurrently", "chatID", chatID, "messageID", messageID)
+		return nil, shared.NewHttpError("message is deleted", http.StatusGone)
+	}
+	if err != nil {
+		c.logger.Errorw("Failed to edit message", "chatID", chatID, "messageID", messageID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	c.hub.PublishMessageChange(chatWS.EventMessageUpdated, *message)
+
+	c.logger.Infow("Message edited successfully", "userID", userID, "chatID", chatID, "messageID", messageID)
+
+	messageDTO := message.ConvertToDTO(userID)
+	return &messageDTO, nil
+}
+
+func (c chatService) DeleteMessage(userID, chatID, messageID uint) *shared.HttpError {
+	c.logger.Infow("Attempting to delete message", "userID", userID, "chatID", chatID, "messageID", messageID)
+
+	if hErr := c.authorizeMessageChange(userID, chatID, messageID); hErr != nil {
+		return hErr
+	}
+
+	message, err := c.messageRepo.SoftDelete(messageID, userID)
+	if errors.Is(err, r.ErrMessageDeleted) {
+		c.logger.Warnw("Message was deleted concurrently", "chatID", chatID, "messageID", messageID)
+		return shared.NewHttpError("message is deleted", http.StatusGone)
+	}
+	if err != nil {
+		c.logger.Errorw("Failed to delete message", "chatID", chatID, "messageID", messageID, "error", err)
+		return shared.InternalError
+	}
+
+	c.hub.PublishMessageChange(chatWS.EventMessageDeleted, *message)
+
+	c.logger.Infow("Message deleted successfully", "userID", userID, "chatID", chatID, "messageID", messageID)
+	return nil
+}
+
+func (c chatService) GetThread(userID, chatID, messageID, afterID uint, limit int) (*ThreadResponse, *shared.HttpError) {
+	c.logger.Infow("Fetching thread", "userID", userID, "chatID", chatID, "messageID", messageID, "afterID", afterID, "limit", limit)
+
+	isMember, err := c.chatRepo.IsMember(chatID, userID)
+	if err != nil {
+		c.logger.Errorw("Failed to check chat membership", "userID", userID, "chatID", chatID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	if !isMember {
+		c.logger.Warnw("User is not a member of the chat", "userID", userID, "chatID", chatID)
+		return nil, shared.NewHttpError("chat not found", http.StatusNotFound)
+	}
+
+	parent, err := c.messageRepo.GetByID(messageID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.logger.Errorw("Failed to fetch message", "chatID", chatID, "messageID", messageID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	if parent == nil || parent.ChatID != chatID {
+		c.logger.Warnw("Message not found", "chatID", chatID, "messageID", messageID)
+		return nil, shared.NewHttpError("message not found", http.StatusNotFound)
+	}
+
+	// Запрашиваем на одно сообщение больше, чтобы понять, есть ли следующая страница
+	replies, err := c.messageRepo.ListReplies(messageID, afterID, limit+1)
+	if err != nil {
+		c.logger.Errorw("Failed to fetch replies", "chatID", chatID, "messageID", messageID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	thread := &ThreadResponse{Parent: parent.ConvertToDTO(userID), Replies: []r.MessageDTO{}}
+	if len(replies) > limit {
+		replies = replies[:limit]
+		nextAfterID := replies[limit-1].ID
+		thread.NextAfterID = &nextAfterID
+	}
+
+	for _, reply := range replies {
+		thread.Replies = append(thread.Replies, reply.ConvertToDTO(userID))
+	}
+
+	c.logger.Infow("Thread successfully fetched", "chatID", chatID, "messageID", messageID, "replyCount", len(thread.Replies))
+	return thread, nil
+}
+
+func (c chatService) AddReaction(userID, chatID, messageID uint, req ReactionRequest) (*chatWS.Reaction, *shared.HttpError) {
+	return c.react(userID, chatID, messageID, req.Emoji, true)
+}
+
+func (c chatService) RemoveReaction(userID, chatID, messageID uint, emoji string) (*chatWS.Reaction, *shared.HttpError) {
+	return c.react(userID, chatID, messageID, emoji, false)
+}
+
+func (c chatService) react(userID, chatID, messageID uint, emoji string, add bool) (*chatWS.Reaction, *shared.HttpError) {
+	c.logger.Infow("Changing reaction", "userID", userID, "chatID", chatID, "messageID", messageID, "emoji", emoji, "add", add)
+
+	reaction, err := c.hub.React(userID, chatID, messageID, emoji, add)
+	switch {
+	case errors.Is(err, chatWS.ErrReactionNotAllowed):
+		c.logger.Warnw("Reaction is not allowed", "userID", userID, "emoji", emoji)
+		return nil, shared.NewHttpError("reaction is not allowed", http.StatusBadRequest)
+	case errors.Is(err, chatWS.ErrNotChatMember):
+		c.logger.Warnw("User is not a member of the chat", "userID", userID, "chatID", chatID)
+		return nil, shared.NewHttpError("chat not found", http.StatusNotFound)
+	case errors.Is(err, chatWS.ErrMessageNotFound):
+		c.logger.Warnw("Message not found", "chatID", chatID, "messageID", messageID)
+		return nil, shared.NewHttpError("message not found", http.StatusNotFound)
+	case err != nil:
+		c.logger.Errorw("Failed to change reaction", "userID", userID, "chatID", chatID, "messageID", messageID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	c.logger.Infow("Reaction successfully changed", "chatID", chatID, "messageID", messageID, "emoji", emoji, "count", reaction.Count)
+	return reaction, nil
+}
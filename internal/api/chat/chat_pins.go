@@ -0,0 +1,126 @@
+package chat
+
+import (
+	"errors"
+	"net/http"
+	chatWS "socialAPI/internal/api/chat/ws"
+	"socialAPI/internal/shared"
+	r "socialAPI/internal/storage/repository"
+
+	"gorm.io/gorm"
+)
+
+// PinMessage закрепляет сообщение в чате. Доступно владельцу и админам,
+// повторное закрепление уже закреплённого сообщения ничего не меняет
+func (c chatService) PinMessage(actorID, chatID, messageID uint) *shared.HttpError {
+	c.logger.Infow("Attempting to pin message", "actorID", actorID, "chatID", chatID, "messageID", messageID)
+
+	if _, hErr := c.moderatorRole(actorID, chatID); hErr != nil {
+		return hErr
+	}
+
+	message, err := c.messageRepo.GetByID(messageID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.logger.Errorw("Failed to fetch message", "chatID", chatID, "messageID", messageID, "error", err)
+		return shared.InternalError
+	}
+
+	if message == nil || message.ChatID != chatID {
+		c.logger.Warnw("Message not found", "chatID", chatID, "messageID", messageID)
+		return shared.NewHttpError("message not found", http.StatusNotFound)
+	}
+
+	if message.DeletedAt != nil {
+		c.logger.Warnw("Message is deleted", "chatID", chatID, "messageID", messageID)
+		return shared.NewHttpError("message is deleted", http.StatusGone)
+	}
+
+	if message.Kind == r.MessageKindSystem {
+		c.logger.Warnw("System messages cannot be pinned", "chatID", chatID, "messageID", messageID)
+		return shared.NewHttpError("system messages cannot be pinned", http.StatusBadRequest)
+	}
+
+	pin, err := c.messageRepo.Pin(chatID, messageID, actorID, c.chatCfg.MaxPins)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.logger.Warnw("Chat not found", "chatID", chatID)
+			return shared.NewHttpError("chat not found", http.StatusNotFound)
+		}
+		if errors.Is(err, r.ErrPinLimitReached) {
+			c.logger.Warnw("Pinned messages limit reached", "chatID", chatID, "limit", c.chatCfg.MaxPins)
+			return shared.NewHttpError("pinned messages limit reached", http.StatusConflict)
+		}
+		c.logger.Errorw("Failed to pin message", "chatID", chatID, "messageID", messageID, "error", err)
+		return shared.InternalError
+	}
+
+	if pin == nil {
+		c.logger.Infow("Message is already pinned", "chatID", chatID, "messageID", messageID)
+		return nil
+	}
+
+	c.hub.PublishPin(chatWS.EventPinned, chatID, chatWS.Pin{
+		MessageID: messageID,
+		UserID:    actorID,
+		PinnedAt:  &pin.PinnedAt,
+		Message:   message.ConvertToPreviewDTO(),
+	})
+
+	c.logger.Infow("Message pinned successfully", "actorID", actorID, "chatID", chatID, "messageID", messageID)
+	return nil
+}
+
+// UnpinMessage открепляет сообщение. Доступно владельцу и админам
+func (c chatService) UnpinMessage(actorID, chatID, messageID uint) *shared.HttpError {
+	c.logger.Infow("Attempting to unpin message", "actorID", actorID, "chatID", chatID, "messageID", messageID)
+
+	if _, hErr := c.moderatorRole(actorID, chatID); hErr != nil {
+		return hErr
+	}
+
+	unpinned, err := c.messageRepo.Unpin(chatID, messageID)
+	if err != nil {
+		c.logger.Errorw("Failed to unpin message", "chatID", chatID, "messageID", messageID, "error", err)
+		return shared.InternalError
+	}
+
+	if !unpinned {
+		c.logger.Warnw("Message is not pinned", "chatID", chatID, "messageID", messageID)
+		return shared.NewHttpError("message is not pinned", http.StatusNotFound)
+	}
+
+	c.hub.PublishPin(chatWS.EventUnpinned, chatID, chatWS.Pin{MessageID: messageID, UserID: actorID})
+
+	c.logger.Infow("Message unpinned successfully", "actorID", actorID, "chatID", chatID, "messageID", messageID)
+	return nil
+}
+
+// GetPins возвращает закреплённые сообщения чата, начиная с недавно закреплённых
+func (c chatService) GetPins(userID, chatID uint) ([]r.PinnedMessageDTO, *shared.HttpError) {
+	c.logger.Infow("Fetching pinned messages", "userID", userID, "chatID", chatID)
+
+	isMember, err := c.chatRepo.IsMember(chatID, userID)
+	if err != nil {
+		c.logger.Errorw("Failed to check chat membership", "userID", userID, "chatID", chatID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	if !isMember {
+		c.logger.Warnw("User is not a member of the chat", "userID", userID, "chatID", chatID)
+		return nil, shared.NewHttpError("chat not found", http.StatusNotFound)
+	}
+
+	pins, err := c.messageRepo.ListPins(chatID)
+	if err != nil {
+		c.logger.Errorw("Failed to fetch pinned messages", "chatID", chatID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	dtos := make([]r.PinnedMessageDTO, 0, len(pins))
+	for i := range pins {
+		dtos = append(dtos, pins[i].ConvertToDTO(userID))
+	}
+
+	c.logger.Infow("Pinned messages successfully fetched", "chatID", chatID, "count", len(dtos))
+	return dtos, nil
+}
@@ -0,0 +1,132 @@
+package chat
+
+import (
+	"errors"
+	"net/http"
+	chatWS "socialAPI/internal/api/chat/ws"
+	"socialAPI/internal/shared"
+	r "socialAPI/internal/storage/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CreatePoll отправляет в чат сообщение-опрос. Вопрос становится текстом сообщения,
+// опрос и варианты сохраняются вместе с ним
+func (c chatService) CreatePoll(userID, chatID uint, req CreatePollRequest) (*r.MessageDTO, *shared.HttpError) {
+	c.logger.Infow("Attempting to create poll", "userID", userID, "chatID", chatID, "options", len(req.Options))
+
+	isMember, err := c.chatRepo.IsMember(chatID, userID)
+	if err != nil {
+		c.logger.Errorw("Failed to check chat membership", "userID", userID, "chatID", chatID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	if !isMember {
+		c.logger.Warnw("User is not a member of the chat", "userID", userID, "chatID", chatID)
+		return nil, shared.NewHttpError("chat not found", http.StatusNotFound)
+	}
+
+	if req.ClosesAt != nil && !req.ClosesAt.After(time.Now()) {
+		c.logger.Warnw("Poll closing time is in the past", "chatID", chatID, "closesAt", req.ClosesAt)
+		return nil, shared.NewHttpError("closes_at must be in the future", http.StatusBadRequest)
+	}
+
+	poll := r.Poll{Multiple: req.Multiple, Anonymous: req.Anonymous, ClosesAt: req.ClosesAt}
+	seen := make(map[string]bool, len(req.Options))
+	for i, text := range req.Options {
+		if seen[text] {
+			c.logger.Warnw("Duplicate poll option", "chatID", chatID, "option", text)
+			return nil, shared.NewHttpError("poll options must be unique", http.StatusBadRequest)
+		}
+		seen[text] = true
+		poll.Options = append(poll.Options, r.PollOption{Position: i, Text: text})
+	}
+
+	message := r.Message{ChatID: chatID, SenderID: userID, Content: req.Question, Kind: r.MessageKindPoll, Poll: &poll}
+	if err := c.messageRepo.CreateBatch([]*r.Message{&message}); err != nil {
+		c.logger.Errorw("Failed to create poll", "userID", userID, "chatID", chatID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	c.hub.PublishSaved([]r.Message{message})
+
+	created, err := c.messageRepo.GetByID(message.ID)
+	if err != nil {
+		c.logger.Errorw("Failed to fetch created poll", "chatID", chatID, "messageID", message.ID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	c.logger.Infow("Poll created successfully", "userID", userID, "chatID", chatID, "messageID", message.ID)
+
+	dto := created.ConvertToDTO(userID)
+	return &dto, nil
+}
+
+// Vote заменяет голоса пользователя в опросе выбранными вариантами
+func (c chatService) Vote(userID, chatID, messageID uint, req VoteRequest) (*r.PollDTO, *shared.HttpError) {
+	return c.vote(userID, chatID, messageID, req.OptionIDs)
+}
+
+// RetractVote снимает все голоса пользователя в опросе
+func (c chatService) RetractVote(userID, chatID, messageID uint) (*r.PollDTO, *shared.HttpError) {
+	return c.vote(userID, chatID, messageID, nil)
+}
+
+func (c chatService) vote(userID, chatID, messageID uint, optionIDs []uint) (*r.PollDTO, *shared.HttpError) {
+	c.logger.Infow("Changing poll vote", "userID", userID, "chatID", chatID, "messageID", messageID, "optionIDs", optionIDs)
+
+	poll, err := c.hub.Vote(userID, chatID, messageID, optionIDs)
+	switch {
+	case errors.Is(err, chatWS.ErrNotChatMember):
+		c.logger.Warnw("User is not a member of the chat", "userID", userID, "chatID", chatID)
+		return nil, shared.NewHttpError("chat not found", http.StatusNotFound)
+	case errors.Is(err, chatWS.ErrMessageNotFound):
+		c.logger.Warnw("Message not found", "chatID", chatID, "messageID", messageID)
+		return nil, shared.NewHttpError("message not found", http.StatusNotFound)
+	case errors.Is(err, chatWS.ErrNotAPoll), errors.Is(err, gorm.ErrRecordNotFound):
+		c.logger.Warnw("Message is not a poll", "chatID", chatID, "messageID", messageID)
+		return nil, shared.NewHttpError("message is not a poll", http.StatusBadRequest)
+	case errors.Is(err, r.ErrPollClosed):
+		c.logger.Warnw("Poll is closed", "chatID", chatID, "messageID", messageID)
+		return nil, shared.NewHttpError("poll is closed", http.StatusConflict)
+	case errors.Is(err, r.ErrPollSingleChoice), errors.Is(err, r.ErrInvalidPollOption):
+		c.logger.Warnw("Invalid poll vote", "chatID", chatID, "messageID", messageID, "optionIDs", optionIDs, "error", err)
+		return nil, shared.NewHttpError(err.Error(), http.StatusBadRequest)
+	case err != nil:
+		c.logger.Errorw("Failed to change poll vote", "userID", userID, "chatID", chatID, "messageID", messageID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	c.logger.Infow("Poll vote successfully changed", "chatID", chatID, "messageID", messageID, "totalVoters", poll.TotalVoters)
+	return poll, nil
+}
+
+// ClosePoll досрочно закрывает опрос. Доступно автору опроса и админам чата
+func (c chatService) ClosePoll(actorID, chatID, messageID uint) *shared.HttpError {
+	c.logger.Infow("Attempting to close poll", "actorID", actorID, "chatID", chatID, "messageID", messageID)
+
+	if hErr := c.authorizeMessageChange(actorID, chatID, messageID); hErr != nil {
+		return hErr
+	}
+
+	poll, err := c.messageRepo.ClosePoll(messageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.logger.Warnw("Message is not a poll", "chatID", chatID, "messageID", messageID)
+			return shared.NewHttpError("message is not a poll", http.StatusBadRequest)
+		}
+		c.logger.Errorw("Failed to close poll", "chatID", chatID, "messageID", messageID, "error", err)
+		return shared.InternalError
+	}
+
+	if poll == nil {
+		c.logger.Warnw("Poll is already closed", "chatID", chatID, "messageID", messageID)
+		return shared.NewHttpError("poll is already closed", http.StatusConflict)
+	}
+
+	c.hub.PublishPoll(chatID, messageID, poll)
+
+	c.logger.Infow("Poll closed successfully", "actorID", actorID, "chatID", chatID, "messageID", messageID)
+	return nil
+}
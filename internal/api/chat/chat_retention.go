@@ -0,0 +1,85 @@
+package chat
+
+import (
+	"errors"
+	"net/http"
+	"socialAPI/internal/shared"
+	r "socialAPI/internal/storage/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SetRetention меняет сроки хранения сообщений чата. В группе это могут владелец и админы,
+// в личном чате - любой из собеседников. Сроки не могут превышать глобальный предел
+func (c chatService) SetRetention(actorID, chatID uint, req RetentionRequest) *shared.HttpError {
+	c.logger.Infow("Attempting to change chat retention", "actorID", actorID, "chatID", chatID, "retentionSeconds", req.RetentionSeconds, "messageTTLSeconds", req.MessageTTLSeconds)
+
+	if hErr := c.authorizeRetentionChange(actorID, chatID); hErr != nil {
+		return hErr
+	}
+
+	if req.RetentionSeconds == nil && req.MessageTTLSeconds == nil {
+		c.logger.Warnw("Nothing to change in chat retention", "chatID", chatID)
+		return shared.NewHttpError("nothing to update", http.StatusBadRequest)
+	}
+
+	if limit := c.chatCfg.RetentionMaxAge; limit > 0 {
+		if req.RetentionSeconds != nil && time.Duration(*req.RetentionSeconds)*time.Second > limit {
+			c.logger.Warnw("Chat retention exceeds the global limit", "chatID", chatID, "retentionSeconds", *req.RetentionSeconds, "limit", limit)
+			return shared.NewHttpError("retention exceeds the global limit", http.StatusBadRequest)
+		}
+		if req.MessageTTLSeconds != nil && time.Duration(*req.MessageTTLSeconds)*time.Second > limit {
+			c.logger.Warnw("Message TTL exceeds the global limit", "chatID", chatID, "messageTTLSeconds", *req.MessageTTLSeconds, "limit", limit)
+			return shared.NewHttpError("message ttl exceeds the global limit", http.StatusBadRequest)
+		}
+	}
+
+	message, err := c.chatRepo.SetRetention(chatID, actorID, req.RetentionSeconds, req.MessageTTLSeconds)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.logger.Warnw("Chat not found", "chatID", chatID)
+			return shared.NewHttpError("chat not found", http.StatusNotFound)
+		}
+		c.logger.Errorw("Failed to change chat retention", "chatID", chatID, "error", err)
+		return shared.InternalError
+	}
+
+	if message != nil {
+		c.hub.PublishMessage(*message)
+	}
+
+	c.logger.Infow("Chat retention changed successfully", "actorID", actorID, "chatID", chatID, "changed", message != nil)
+	return nil
+}
+
+// authorizeRetentionChange пускает модераторов группы и обоих участников личного чата
+func (c chatService) authorizeRetentionChange(actorID, chatID uint) *shared.HttpError {
+	role, err := c.chatRepo.GetRole(chatID, actorID)
+	if err != nil {
+		c.logger.Errorw("Failed to fetch chat role", "chatID", chatID, "userID", actorID, "error", err)
+		return shared.InternalError
+	}
+
+	if role == "" {
+		c.logger.Warnw("User is not a member of the chat", "chatID", chatID, "userID", actorID)
+		return shared.NewHttpError("chat not found", http.StatusNotFound)
+	}
+
+	if role.CanModerate() {
+		return nil
+	}
+
+	chatType, err := c.chatRepo.GetType(chatID)
+	if err != nil {
+		c.logger.Errorw("Failed to fetch chat type", "chatID", chatID, "error", err)
+		return shared.InternalError
+	}
+
+	if chatType != r.ChatTypeDirect {
+		c.logger.Warnw("User is not allowed to manage the chat", "chatID", chatID, "userID", actorID, "role", role)
+		return shared.NewHttpError("only the owner or an admin can manage the chat", http.StatusForbidden)
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package chat
+
+import (
+	"errors"
+	"net/http"
+	"socialAPI/internal/shared"
+	r "socialAPI/internal/storage/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ScheduleMessage откладывает сообщение до send_at. Отправит его фоновый Scheduler
+func (c chatService) ScheduleMessage(userID, chatID uint, req ScheduleMessageRequest) (*r.ScheduledMessageDTO, *shared.HttpError) {
+	c.logger.Infow("Attempting to schedule message", "userID", userID, "chatID", chatID, "sendAt", req.SendAt)
+
+	isMember, err := c.chatRepo.IsMember(chatID, userID)
+	if err != nil {
+		c.logger.Errorw("Failed to check chat membership", "userID", userID, "chatID", chatID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	if !isMember {
+		c.logger.Warnw("User is not a member of the chat", "userID", userID, "chatID", chatID)
+		return nil, shared.NewHttpError("chat not found", http.StatusNotFound)
+	}
+
+	if !req.SendAt.After(time.Now()) {
+		c.logger.Warnw("Scheduled time is in the past", "chatID", chatID, "sendAt", req.SendAt)
+		return nil, shared.NewHttpError("send_at must be in the future", http.StatusBadRequest)
+	}
+
+	if req.ReplyToID != nil {
+		parent, err := c.messageRepo.GetByID(*req.ReplyToID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.logger.Errorw("Failed to fetch message", "chatID", chatID, "messageID", *req.ReplyToID, "error", err)
+			return nil, shared.InternalError
+		}
+
+		if parent == nil || parent.ChatID != chatID {
+			c.logger.Warnw("Replied message not found", "chatID", chatID, "replyToID", *req.ReplyToID)
+			return nil, shared.NewHttpError("message not found", http.StatusNotFound)
+		}
+	}
+
+	scheduled := r.ScheduledMessage{ChatID: chatID, SenderID: userID, Content: req.Content, ReplyToID: req.ReplyToID, SendAt: req.SendAt}
+	if err := c.scheduledRepo.Create(&scheduled); err != nil {
+		c.logger.Errorw("Failed to schedule message", "userID", userID, "chatID", chatID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	c.logger.Infow("Message scheduled successfully", "userID", userID, "chatID", chatID, "scheduledID", scheduled.ID)
+
+	dto := scheduled.ConvertToDTO()
+	return &dto, nil
+}
+
+// GetScheduled возвращает неотправленные сообщения пользователя в чате
+func (c chatService) GetScheduled(userID, chatID uint) ([]r.ScheduledMessageDTO, *shared.HttpError) {
+	c.logger.Infow("Fetching scheduled messages", "userID", userID, "chatID", chatID)
+
+	scheduled, err := c.scheduledRepo.List(chatID, userID)
+	if err != nil {
+		c.logger.Errorw("Failed to fetch scheduled messages", "userID", userID, "chatID", chatID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	dtos := make([]r.ScheduledMessageDTO, 0, len(scheduled))
+	for i := range scheduled {
+		dtos = append(dtos, scheduled[i].ConvertToDTO())
+	}
+
+	c.logger.Infow("Scheduled messages successfully fetched", "userID", userID, "chatID", chatID, "count", len(dtos))
+	return dtos, nil
+}
+
+// EditScheduled меняет текст или время ещё не отправленного сообщения
+func (c chatService) EditScheduled(userID, chatID, scheduledID uint, req EditScheduledRequest) (*r.ScheduledMessageDTO, *shared.HttpError) {
+	c.logger.Infow("Attempting to edit scheduled message", "userID", userID, "chatID", chatID, "scheduledID", scheduledID)
+
+	changes := make(map[string]interface{})
+	if req.Content != nil {
+		changes["content"] = *req.Content
+	}
+	if req.SendAt != nil {
+		if !req.SendAt.After(time.Now()) {
+			c.logger.Warnw("Scheduled time is in the past", "scheduledID", scheduledID, "sendAt", req.SendAt)
+			return nil, shared.NewHttpError("send_at must be in the future", http.StatusBadRequest)
+		}
+		changes["send_at"] = *req.SendAt
+	}
+
+	if len(changes) == 0 {
+		c.logger.Warnw("Nothing to change in scheduled message", "scheduledID", scheduledID)
+		return nil, shared.NewHttpError("nothing to update", http.StatusBadRequest)
+	}
+
+	scheduled, err := c.scheduledRepo.Update(scheduledID, chatID, userID, changes)
+	if err != nil {
+		c.logger.Errorw("Failed to edit scheduled message", "scheduledID", scheduledID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	if scheduled == nil {
+		c.logger.Warnw("Scheduled message not found", "userID", userID, "chatID", chatID, "scheduledID", scheduledID)
+		return nil, shared.NewHttpError("scheduled message not found", http.StatusNotFound)
+	}
+
+	c.logger.Infow("Scheduled message edited successfully", "userID", userID, "scheduledID", scheduledID)
+
+	dto := scheduled.ConvertToDTO()
+	return &dto, nil
+}
+
+// CancelScheduled отменяет ещё не отправленное сообщение
+func (c chatService) CancelScheduled(userID, chatID, scheduledID uint) *shared.HttpError {
+	c.logger.Infow("Attempting to cancel scheduled message", "userID", userID, "chatID", chatID, "scheduledID", scheduledID)
+
+	deleted, err := c.scheduledRepo.Delete(scheduledID, chatID, userID)
+	if err != nil {
+		c.logger.Errorw("Failed to cancel scheduled message", "scheduledID", scheduledID, "error", err)
+		return shared.InternalError
+	}
+
+	if !deleted {
+		c.logger.Warnw("Scheduled message not found", "userID", userID, "chatID", chatID, "scheduledID", scheduledID)
+		return shared.NewHttpError("scheduled message not found", http.StatusNotFound)
+	}
+
+	c.logger.Infow("Scheduled message cancelled successfully", "userID", userID, "scheduledID", scheduledID)
+	return nil
+}
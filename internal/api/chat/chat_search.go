@@ -0,0 +1,69 @@
+package chat
+
+import (
+	"context"
+	"net/http"
+	"socialAPI/internal/shared"
+	"socialAPI/internal/storage/search"
+	"strings"
+	"unicode/utf8"
+)
+
+// Максимальная длина поискового запроса в символах
+const maxSearchQueryLength = 256
+
+func (c chatService) Search(userID uint, req SearchRequest) (*SearchResponse, *shared.HttpError) {
+	c.logger.Infow("Searching messages", "userID", userID, "chatID", req.ChatID, "senderID", req.SenderID, "cursor", req.Cursor, "limit", req.Limit)
+
+	text := strings.TrimSpace(req.Query)
+	if text == "" {
+		c.logger.Warnw("Empty search query", "userID", userID)
+		return nil, shared.NewHttpError("q is required", http.StatusBadRequest)
+	}
+
+	if utf8.RuneCountInString(text) > maxSearchQueryLength {
+		c.logger.Warnw("Search query is too long", "userID", userID)
+		return nil, shared.NewHttpError("q is too long", http.StatusBadRequest)
+	}
+
+	if req.From != nil && req.To != nil && !req.From.Before(*req.To) {
+		c.logger.Warnw("Invalid search date range", "userID", userID, "from", req.From, "to", req.To)
+		return nil, shared.NewHttpError("from must be before to", http.StatusBadRequest)
+	}
+
+	// Запрашиваем на один результат больше, чтобы понять, есть ли следующая страница
+	hits, err := c.searcher.Search(context.Background(), search.Query{
+		Text:     text,
+		UserID:   userID,
+		ChatID:   req.ChatID,
+		SenderID: req.SenderID,
+		From:     req.From,
+		To:       req.To,
+		BeforeID: req.Cursor,
+		Limit:    req.Limit + 1,
+	})
+	if err != nil {
+		c.logger.Errorw("Failed to search messages", "userID", userID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	response := &SearchResponse{Results: []SearchResultDTO{}}
+	if len(hits) > req.Limit {
+		hits = hits[:req.Limit]
+		nextCursor := hits[req.Limit-1].MessageID
+		response.NextCursor = &nextCursor
+	}
+
+	for _, hit := range hits {
+		response.Results = append(response.Results, SearchResultDTO{
+			MessageID: hit.MessageID,
+			ChatID:    hit.ChatID,
+			SenderID:  hit.SenderID,
+			Snippet:   hit.Snippet,
+			CreatedAt: hit.CreatedAt,
+		})
+	}
+
+	c.logger.Infow("Search completed", "userID", userID, "resultCount", len(response.Results))
+	return response, nil
+}
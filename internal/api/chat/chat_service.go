@@ -1,73 +1,111 @@
 package chat
 
 import (
+	"io"
 	"net/http"
+	"slices"
 	chatWS "socialAPI/internal/api/chat/ws"
 	"socialAPI/internal/setting/cfg"
 	"socialAPI/internal/shared"
+	"socialAPI/internal/storage/blob"
 	r "socialAPI/internal/storage/repository"
+	"socialAPI/internal/storage/search"
+	"time"
 
 	"go.uber.org/zap"
 )
 
 type ChatService interface {
-	GetOne(chatID uint) (*r.ChatDTO, *shared.HttpError)
-	GetAll() (*[]r.ChatDTO, *shared.HttpError)
-	Create(req CreateRequest) *shared.HttpError
-	Update(id uint, req CreateRequest) *shared.HttpError
-	HandleWebSocket(userID uint, w http.ResponseWriter, r *http.Request) *shared.HttpError
+	GetOne(userID, chatID uint) (*r.ChatDTO, *shared.HttpError)
+	GetAll(userID uint, archived bool) (*[]r.ChatSummaryDTO, *shared.HttpError)
+	Create(creatorID uint, req CreateRequest) *shared.HttpError
+	GetOrCreateDirect(userID, peerID uint) (*DirectChatResponse, *shared.HttpError)
+	Update(actorID, chatID uint, req UpdateRequest) *shared.HttpError
+	AddMembers(actorID, chatID uint, req AddMembersRequest) *shared.HttpError
+	RemoveMember(actorID, chatID, userID uint) *shared.HttpError
+	Leave(userID, chatID uint) *shared.HttpError
+	SetRole(actorID, chatID, userID uint, req RoleRequest) *shared.HttpError
+	SetRetention(actorID, chatID uint, req RetentionRequest) *shared.HttpError
+	CreateInvite(actorID, chatID uint, req CreateInviteRequest) (*r.ChatInviteDTO, *shared.HttpError)
+	GetInvites(actorID, chatID uint) ([]r.ChatInviteDTO, *shared.HttpError)
+	RevokeInvite(actorID, chatID, inviteID uint) *shared.HttpError
+	JoinByInvite(userID uint, code string) (*JoinResponse, *shared.HttpError)
+	MarkRead(userID, chatID uint, req ReadRequest) *shared.HttpError
+	UpdateSettings(userID, chatID uint, req SettingsRequest) (*r.ChatSettingsDTO, *shared.HttpError)
+	EditMessage(userID, chatID, messageID uint, req EditMessageRequest) (*r.MessageDTO, *shared.HttpError)
+	DeleteMessage(userID, chatID, messageID uint) *shared.HttpError
+	GetThread(userID, chatID, messageID, afterID uint, limit int) (*ThreadResponse, *shared.HttpError)
+	ScheduleMessage(userID, chatID uint, req ScheduleMessageRequest) (*r.ScheduledMessageDTO, *shared.HttpError)
+	GetScheduled(userID, chatID uint) ([]r.ScheduledMessageDTO, *shared.HttpError)
+	EditScheduled(userID, chatID, scheduledID uint, req EditScheduledRequest) (*r.ScheduledMessageDTO, *shared.HttpError)
+	CancelScheduled(userID, chatID, scheduledID uint) *shared.HttpError
+	AddReaction(userID, chatID, messageID uint, req ReactionRequest) (*chatWS.Reaction, *shared.HttpError)
+	RemoveReaction(userID, chatID, messageID uint, emoji string) (*chatWS.Reaction, *shared.HttpError)
+	PinMessage(actorID, chatID, messageID uint) *shared.HttpError
+	UnpinMessage(actorID, chatID, messageID uint) *shared.HttpError
+	GetPins(userID, chatID uint) ([]r.PinnedMessageDTO, *shared.HttpError)
+	CreatePoll(userID, chatID uint, req CreatePollRequest) (*r.MessageDTO, *shared.HttpError)
+	Vote(userID, chatID, messageID uint, req VoteRequest) (*r.PollDTO, *shared.HttpError)
+	RetractVote(userID, chatID, messageID uint) (*r.PollDTO, *shared.HttpError)
+	ClosePoll(actorID, chatID, messageID uint) *shared.HttpError
+	GetMentions(userID, beforeID uint, limit int) (*MentionsResponse, *shared.HttpError)
+	ForwardMessage(userID, chatID, messageID uint, req ForwardRequest) ([]ForwardedMessageDTO, *shared.HttpError)
+	UploadAttachment(userID, chatID uint, fileName string, size int64, file io.Reader) (*r.AttachmentDTO, *shared.HttpError)
+	OpenAttachment(userID, chatID, attachmentID uint, thumbnail bool) (*AttachmentFile, *shared.HttpError)
+	Search(userID uint, req SearchRequest) (*SearchResponse, *shared.HttpError)
+	IssueTicket(userID uint, expiresAt time.Time) (*TicketResponse, *shared.HttpError)
+	HandleWebSocket(userID uint, expiresAt time.Time, w http.ResponseWriter, r *http.Request) *shared.HttpError
 }
 
 type chatService struct {
-	userRepo   r.UserRepository
-	chatRepo   r.ChatRepository
-	hub        chatWS.Hub
-	wsUpgrader cfg.Upgrader
-	logger     *zap.SugaredLogger
+	userRepo       r.UserRepository
+	chatRepo       r.ChatRepository
+	friendshipRepo r.FriendshipRepository
+	messageRepo    r.MessageRepository
+	attachmentRepo r.AttachmentRepository
+	scheduledRepo  r.ScheduledMessageRepository
+	hub            chatWS.Hub
+	blobs          blob.BlobStore
+	thumbnailer    Thumbnailer
+	searcher       search.Searcher
+	chatCfg        cfg.ChatConfig
+	upload         cfg.UploadConfig
+	wsUpgrader     cfg.Upgrader
+	wsAuth         shared.WSAuthService
+	logger         *zap.SugaredLogger
 }
 
-func NewChatService(chatRepo r.ChatRepository, userRepo r.UserRepository, hub chatWS.Hub, wsUpgrader cfg.Upgrader, logger *zap.SugaredLogger) ChatService {
-	return &chatService{chatRepo: chatRepo, userRepo: userRepo, hub: hub, wsUpgrader: wsUpgrader, logger: logger}
+func NewChatService(chatRepo r.ChatRepository, userRepo r.UserRepository, friendshipRepo r.FriendshipRepository, messageRepo r.MessageRepository, attachmentRepo r.AttachmentRepository, scheduledRepo r.ScheduledMessageRepository, hub chatWS.Hub, blobs blob.BlobStore, thumbnailer Thumbnailer, searcher search.Searcher, chatCfg cfg.ChatConfig, upload cfg.UploadConfig, wsUpgrader cfg.Upgrader, wsAuth shared.WSAuthService, logger *zap.SugaredLogger) ChatService {
+	return &chatService{chatRepo: chatRepo, userRepo: userRepo, friendshipRepo: friendshipRepo, messageRepo: messageRepo, attachmentRepo: attachmentRepo, scheduledRepo: scheduledRepo, hub: hub, blobs: blobs, thumbnailer: thumbnailer, searcher: searcher, chatCfg: chatCfg, upload: upload, wsUpgrader: wsUpgrader, wsAuth: wsAuth, logger: logger}
 }
 
-func (c chatService) checksUsersAndChatExistense(req CreateRequest) *shared.HttpError {
-	exists, err := c.userRepo.IDsExists(req.UserIDs)
+func (c chatService) checkUsersExist(userIDs []uint) *shared.HttpError {
+	exists, err := c.userRepo.IDsExists(userIDs)
 	if err != nil {
-		c.logger.Errorw("Error checking user IDs existence", "userIDs", req.UserIDs, "name", req.Name, "error", err)
+		c.logger.Errorw("Error checking user IDs existence", "userIDs", userIDs, "error", err)
 		return shared.InternalError
 	}
 
 	if !exists {
-		c.logger.Warnw("Some user IDs do not exist", "userIDs", req.UserIDs, "name", req.Name)
+		c.logger.Warnw("Some user IDs do not exist", "userIDs", userIDs)
 		return shared.NewHttpError("some user IDs do not exist", http.StatusBadRequest)
 	}
 
-	exists, err = c.chatRepo.ExistsSetUserIDs(req.UserIDs)
-
-	if err != nil {
-		c.logger.Errorw("Error checking chat with this userIDs", "userIDs", req.UserIDs, "name", req.Name, "error", err)
-		return shared.InternalError
-	}
-
-	if exists {
-		c.logger.Warnw("Chat with the same users already exists", "userIDs", req.UserIDs, "name", req.Name)
-		return shared.NewHttpError("chat with the same users already exists", http.StatusBadRequest)
-	}
-
 	return nil
 }
 
-func (c chatService) GetOne(id uint) (*r.ChatDTO, *shared.HttpError) {
-	c.logger.Infow("Fetching chat", "chatID", id)
+func (c chatService) GetOne(userID, id uint) (*r.ChatDTO, *shared.HttpError) {
+	c.logger.Infow("Fetching chat", "chatID", id, "userID", userID)
 
-	exists, err := c.chatRepo.ExistsID(id)
+	isMember, err := c.chatRepo.IsMember(id, userID)
 	if err != nil {
-		c.logger.Errorw("Failed to check chat existence", "chatID", id, "error", err)
+		c.logger.Errorw("Failed to check chat membership", "chatID", id, "userID", userID, "error", err)
 		return nil, shared.InternalError
 	}
 
-	if !exists {
-		c.logger.Warnw("Chat not found", "chatID", id)
+	// Чужой чат неотличим от несуществующего
+	if !isMember {
+		c.logger.Warnw("Chat not found", "chatID", id, "userID", userID)
 		return nil, shared.NewHttpError("chat not found", http.StatusNotFound)
 	}
 
@@ -79,81 +117,122 @@ func (c chatService) GetOne(id uint) (*r.ChatDTO, *shared.HttpError) {
 
 	c.logger.Infow("Chat successfully fetched", "chatID", id)
 
-	chatDTO := chat.ConvertToDTO()
+	chatDTO := chat.ConvertToDTO(userID)
 	c.logger.Infow("Chat successfully converted to DTO", "chatID", id)
 
 	return chatDTO, nil
 }
 
-func (c chatService) GetAll() (*[]r.ChatDTO, *shared.HttpError) {
-	c.logger.Infow("Fetching chats")
+func (c chatService) GetAll(userID uint, archived bool) (*[]r.ChatSummaryDTO, *shared.HttpError) {
+	c.logger.Infow("Fetching chats", "userID", userID, "archived", archived)
 
-	chats, err := c.chatRepo.GetAll() // Получаем список чатов
+	summaries, err := c.chatRepo.GetSummaries(userID, archived) // Получаем чаты пользователя со счётчиками
 	if err != nil {
-		c.logger.Errorw("Failed to fetch chats", "error", err)
+		c.logger.Errorw("Failed to fetch chats", "userID", userID, "error", err)
 		return nil, shared.InternalError
 	}
 
-	c.logger.Infow("Chats successfully fetched")
+	c.logger.Infow("Chats successfully fetched", "userID", userID)
 
-	chatDTOs := []r.ChatDTO{}
-	for _, chat := range chats {
-		chatDTOs = append(chatDTOs, *chat.ConvertToDTO()) // Конвертируем каждый чат
+	chatDTOs := []r.ChatSummaryDTO{}
+	for _, summary := range summaries {
+		chatDTOs = append(chatDTOs, summary.ConvertToDTO()) // Конвертируем каждый чат
 	}
 
-	c.logger.Infow("Chats successfully converted to DTOs")
+	c.logger.Infow("Chats successfully converted to DTOs", "userID", userID)
 
 	return &chatDTOs, nil
 }
 
-func (c chatService) Create(req CreateRequest) *shared.HttpError {
-	c.logger.Infow("Attemting to create chat", "userIDs", req.UserIDs, "name", req.Name)
-	hErr := c.checksUsersAndChatExistense(req)
+func (c chatService) Create(creatorID uint, req CreateRequest) *shared.HttpError {
+	// Создатель всегда участник и владелец чата, даже если не указал себя
+	if !slices.Contains(req.UserIDs, creatorID) {
+		req.UserIDs = append(slices.Clone(req.UserIDs), creatorID)
+	}
+
+	c.logger.Infow("Attemting to create chat", "creatorID", creatorID, "userIDs", req.UserIDs, "name", req.Name)
+	// Группы с одинаковым составом допустимы, личные чаты создаются через GetOrCreateDirect
+	hErr := c.checkUsersExist(req.UserIDs)
 	if hErr != nil {
 		return hErr
 	}
 
-	err := c.chatRepo.Create(req.Name, req.UserIDs)
+	created, err := c.chatRepo.Create(creatorID, req.Name, req.UserIDs)
 	if err != nil {
-		c.logger.Errorw("Error creating chat", "userIDs", req.UserIDs, "name", req.Name, "error", err)
+		c.logger.Errorw("Error creating chat", "creatorID", creatorID, "userIDs", req.UserIDs, "name", req.Name, "error", err)
 		return shared.InternalError
 	}
 
-	c.logger.Infow("Chat created successfully", "userIDs", req.UserIDs, "name", req.Name)
+	c.hub.UpdateMembership(created.ChatID, created.ID-1, req.UserIDs, nil)
+	c.hub.PublishMessage(*created)
+
+	c.logger.Infow("Chat created successfully", "chatID", created.ChatID, "creatorID", creatorID, "userIDs", req.UserIDs, "name", req.Name)
 	return nil
 }
 
-func (c chatService) Update(id uint, req CreateRequest) *shared.HttpError {
-	c.logger.Infow("Attempting to update chat", "chatID", id, "userIDs", req.UserIDs, "name", req.Name)
+// Update переименовывает чат. Доступно владельцу и админам, состав меняется через /members
+func (c chatService) Update(actorID, chatID uint, req UpdateRequest) *shared.HttpError {
+	c.logger.Infow("Attempting to rename chat", "actorID", actorID, "chatID", chatID, "name", req.Name)
 
-	hErr := c.checksUsersAndChatExistense(req)
-	if hErr != nil {
-		c.logger.Warnw("User validation failed during chat update", "chatID", id, "userIDs", req.UserIDs, "name", req.Name, "error", hErr)
+	if _, hErr := c.moderatorRole(actorID, chatID); hErr != nil {
 		return hErr
 	}
 
-	exists, err := c.chatRepo.ExistsID(id)
+	message, err := c.chatRepo.Rename(chatID, actorID, req.Name)
+	if hErr := c.membershipError(err, actorID, chatID); hErr != nil {
+		return hErr
+	}
 	if err != nil {
-		c.logger.Errorw("Failed to check chat existence", "chatID", id, "error", err)
+		c.logger.Errorw("Error while renaming chat", "chatID", chatID, "name", req.Name, "error", err)
 		return shared.InternalError
 	}
 
-	if !exists {
-		c.logger.Warnw("Chat not found", "chatID", id)
+	if message != nil {
+		c.hub.PublishMessage(*message)
+	}
+
+	c.logger.Infow("Chat renamed successfully", "actorID", actorID, "chatID", chatID, "name", req.Name, "changed", message != nil)
+	return nil
+}
+
+func (c chatService) MarkRead(userID, chatID uint, req ReadRequest) *shared.HttpError {
+	c.logger.Infow("Marking chat as read", "userID", userID, "chatID", chatID, "messageID", req.MessageID)
+
+	isMember, err := c.chatRepo.IsMember(chatID, userID)
+	if err != nil {
+		c.logger.Errorw("Failed to check chat membership", "userID", userID, "chatID", chatID, "error", err)
+		return shared.InternalError
+	}
+
+	if !isMember {
+		c.logger.Warnw("User is not a member of the chat", "userID", userID, "chatID", chatID)
 		return shared.NewHttpError("chat not found", http.StatusNotFound)
 	}
 
-	err = c.chatRepo.Update(id, req.Name, req.UserIDs)
+	advanced, err := c.hub.MarkRead(userID, chatID, req.MessageID)
 	if err != nil {
-		c.logger.Errorw("Error while updating chat", "chatID", id, "userIDs", req.UserIDs, "name", req.Name, "error", err)
+		c.logger.Errorw("Failed to mark chat as read", "userID", userID, "chatID", chatID, "error", err)
 		return shared.InternalError
 	}
 
-	c.logger.Infow("Chat updated successfully", "chatID", id, "userIDs", req.UserIDs, "name", req.Name)
+	c.logger.Infow("Chat marked as read", "userID", userID, "chatID", chatID, "messageID", req.MessageID, "advanced", advanced)
 	return nil
 }
 
-func (c chatService) HandleWebSocket(userID uint, w http.ResponseWriter, r *http.Request) *shared.HttpError {
+func (c chatService) IssueTicket(userID uint, expiresAt time.Time) (*TicketResponse, *shared.HttpError) {
+	c.logger.Infow("Issuing WebSocket ticket", "userID", userID)
+
+	ticket, err := c.wsAuth.IssueTicket(userID, expiresAt)
+	if err != nil {
+		c.logger.Errorw("Failed to issue WebSocket ticket", "userID", userID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	c.logger.Infow("WebSocket ticket issued", "userID", userID)
+	return &TicketResponse{Ticket: ticket.Ticket}, nil
+}
+
+func (c chatService) HandleWebSocket(userID uint, expiresAt time.Time, w http.ResponseWriter, r *http.Request) *shared.HttpError {
 	c.logger.Infow("Handling WebSocket connection", "userID", userID)
 
 	chatIDs, err := c.chatRepo.GetChatIDsByUserID(userID)
@@ -162,6 +241,12 @@ func (c chatService) HandleWebSocket(userID uint, w http.ResponseWriter, r *http
 		return shared.InternalError
 	}
 
+	resume, err := parseResumeCursors(r.URL.Query(), chatIDs)
+	if err != nil {
+		c.logger.Warnw("Invalid resume cursors", "userID", userID, "error", err)
+		return shared.NewHttpError(err.Error(), http.StatusBadRequest)
+	}
+
 	conn, err := c.wsUpgrader.Upgrade(w, r, nil)
 	if err != nil {
 		c.logger.Errorw("WebSocket upgrade failed", "userID", userID, "error", err)
@@ -175,7 +260,7 @@ func (c chatService) HandleWebSocket(userID uint, w http.ResponseWriter, r *http
 		chatIDMap[id] = true
 	}
 
-	client := chatWS.NewClient(conn, make(chan chatWS.Message, 256), c.hub, userID, chatIDMap, c.logger)
+	client := chatWS.NewClient(conn, make(chan chatWS.Event, 256), c.hub, userID, chatIDMap, resume, chatWS.Session{ExpiresAt: expiresAt, Validator: c.wsAuth}, c.logger)
 
 	c.hub.RegisterClient(client)
 
@@ -2,18 +2,25 @@ package chat_test
 
 import (
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"socialAPI/internal/api/chat"
+	"socialAPI/internal/api/chat/ws"
 	"socialAPI/internal/mocks"
+	"socialAPI/internal/setting/cfg"
 	"socialAPI/internal/shared"
 	"socialAPI/internal/storage/repository"
+	"socialAPI/internal/storage/search"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 var (
@@ -23,34 +30,73 @@ var (
 	chatsExample = []*repository.Chat{
 		&chatExample,
 	}
+	lastMessageID   = uint(42)
+	lastMessageKind = repository.MessageKindText
+	summaryExample  = []repository.ChatSummary{
+		{ID: chatID, UnreadCount: 3},
+		{ID: uint(2), LastMessageID: &lastMessageID, LastMessageKind: &lastMessageKind, LastMessageContent: new(string), LastMessageSenderID: &userID,
+			LastMessageSender: new(string), LastMessageCreatedAt: &time.Time{}, LastMessageUpdatedAt: &time.Time{}},
+	}
 	createRequestExample = chat.CreateRequest{UserIDs: []uint{1, 2}}
+	systemMessageExample = repository.Message{ID: 7, ChatID: chatID, SenderID: userID, Kind: repository.MessageKindSystem,
+		System: &repository.SystemEvent{Action: repository.SystemChatCreated, UserIDs: []uint{2}}}
 )
 
 type chatServiceMocks struct {
-	userRepo   *mocks.UserRepository
-	chatRepo   *mocks.ChatRepository
-	hub        *mocks.Hub
-	wsUpgrader *mocks.Upgrader
-	logger     *zap.SugaredLogger
-	chatSrv    chat.ChatService
+	userRepo       *mocks.UserRepository
+	chatRepo       *mocks.ChatRepository
+	friendshipRepo *mocks.FriendshipRepository
+	messageRepo    *mocks.MessageRepository
+	attachmentRepo *mocks.AttachmentRepository
+	scheduledRepo  *mocks.ScheduledMessageRepository
+	hub            *mocks.Hub
+	blobs          *mocks.BlobStore
+	thumbnailer    *mocks.Thumbnailer
+	searcher       *mocks.Searcher
+	wsUpgrader     *mocks.Upgrader
+	wsAuth         *mocks.WSAuthService
+	logger         *zap.SugaredLogger
+	chatSrv        chat.ChatService
 }
 
+const maxUploadSize = int64(1024)
+
+const maxPins = 2
+
+const retentionMaxAge = 30 * 24 * time.Hour
+
 func setupChatService() chatServiceMocks {
 	userRepo := new(mocks.UserRepository)
 	chatRepo := new(mocks.ChatRepository)
+	friendshipRepo := new(mocks.FriendshipRepository)
+	messageRepo := new(mocks.MessageRepository)
+	attachmentRepo := new(mocks.AttachmentRepository)
+	scheduledRepo := new(mocks.ScheduledMessageRepository)
 	logger := zap.NewNop().Sugar()
 	hub := new(mocks.Hub)
+	blobs := new(mocks.BlobStore)
+	thumbnailer := new(mocks.Thumbnailer)
+	searcher := new(mocks.Searcher)
 	wsUpgrader := new(mocks.Upgrader)
+	wsAuth := new(mocks.WSAuthService)
 
-	chatSrv := chat.NewChatService(chatRepo, userRepo, hub, wsUpgrader, logger)
+	chatSrv := chat.NewChatService(chatRepo, userRepo, friendshipRepo, messageRepo, attachmentRepo, scheduledRepo, hub, blobs, thumbnailer, searcher, cfg.ChatConfig{DirectFriendsOnly: true, MaxPins: maxPins, RetentionMaxAge: retentionMaxAge}, cfg.UploadConfig{MaxSize: maxUploadSize}, wsUpgrader, wsAuth, logger)
 
 	return chatServiceMocks{
-		userRepo:   userRepo,
-		chatRepo:   chatRepo,
-		hub:        hub,
-		wsUpgrader: wsUpgrader,
-		logger:     logger,
-		chatSrv:    chatSrv,
+		userRepo:       userRepo,
+		chatRepo:       chatRepo,
+		friendshipRepo: friendshipRepo,
+		messageRepo:    messageRepo,
+		attachmentRepo: attachmentRepo,
+		scheduledRepo:  scheduledRepo,
+		hub:            hub,
+		blobs:          blobs,
+		thumbnailer:    thumbnailer,
+		searcher:       searcher,
+		wsUpgrader:     wsUpgrader,
+		wsAuth:         wsAuth,
+		logger:         logger,
+		chatSrv:        chatSrv,
 	}
 }
 
@@ -67,18 +113,18 @@ func TestChatService_GetOne(t *testing.T) {
 		errMessage string
 	}{
 		{
-			name: "failed to check chat existence",
+			name: "failed to check chat membership",
 			setup: func(m *chatServiceMocks) {
-				m.chatRepo.On("ExistsID", chatID).Return(false, errExample)
+				m.chatRepo.On("IsMember", chatID, userID).Return(false, errExample)
 			},
 			wantErr:    true,
 			errMessage: shared.InternalError.Error(),
 			wantChat:   false,
 		},
 		{
-			name: "chat not found",
+			name: "user is not a member of the chat",
 			setup: func(m *chatServiceMocks) {
-				m.chatRepo.On("ExistsID", chatID).Return(false, nil)
+				m.chatRepo.On("IsMember", chatID, userID).Return(false, nil)
 			},
 			wantErr:    true,
 			errMessage: "chat not found",
@@ -87,7 +133,7 @@ func TestChatService_GetOne(t *testing.T) {
 		{
 			name: "failed to fetch chat",
 			setup: func(m *chatServiceMocks) {
-				m.chatRepo.On("ExistsID", chatID).Return(true, nil)
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
 				m.chatRepo.On("GetOne", chatID).Return(nil, errExample)
 			},
 			wantErr:    true,
@@ -97,7 +143,7 @@ func TestChatService_GetOne(t *testing.T) {
 		{
 			name: "chat succefully fetched and converted to DTO",
 			setup: func(m *chatServiceMocks) {
-				m.chatRepo.On("ExistsID", chatID).Return(true, nil)
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
 				m.chatRepo.On("GetOne", chatID).Return(&chatExample, nil)
 			},
 			wantErr:  false,
@@ -110,7 +156,7 @@ func TestChatService_GetOne(t *testing.T) {
 			mocks := setupChatService()
 			tt.setup(&mocks)
 
-			chatDTO, err := mocks.chatSrv.GetOne(chatID)
+			chatDTO, err := mocks.chatSrv.GetOne(userID, chatID)
 			if tt.wantChat {
 				assert.NotNil(t, chatDTO)
 				assert.NotZero(t, chatDTO.ID)
@@ -132,6 +178,7 @@ func TestChatService_GetOne(t *testing.T) {
 func TestChatService_GetAll(t *testing.T) {
 	tests := []struct {
 		name       string
+		archived   bool
 		setup      func(m *chatServiceMocks)
 		wantErr    bool
 		wantChats  bool
@@ -140,7 +187,7 @@ func TestChatService_GetAll(t *testing.T) {
 		{
 			name: "Failed to fetch chats",
 			setup: func(m *chatServiceMocks) {
-				m.chatRepo.On("GetAll").Return(nil, errExample)
+				m.chatRepo.On("GetSummaries", userID, false).Return(nil, errExample)
 			},
 			wantErr:    true,
 			errMessage: shared.InternalError.Error(),
@@ -149,7 +196,16 @@ func TestChatService_GetAll(t *testing.T) {
 		{
 			name: "chats succefully fetched and converted to DTO",
 			setup: func(m *chatServiceMocks) {
-				m.chatRepo.On("GetAll").Return(chatsExample, nil)
+				m.chatRepo.On("GetSummaries", userID, false).Return(summaryExample, nil)
+			},
+			wantErr:   false,
+			wantChats: true,
+		},
+		{
+			name:     "archived chats fetched",
+			archived: true,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetSummaries", userID, true).Return(summaryExample, nil)
 			},
 			wantErr:   false,
 			wantChats: true,
@@ -161,10 +217,13 @@ func TestChatService_GetAll(t *testing.T) {
 			mocks := setupChatService()
 			tt.setup(&mocks)
 
-			chatsDTO, err := mocks.chatSrv.GetAll()
+			chatsDTO, err := mocks.chatSrv.GetAll(userID, tt.archived)
 			if tt.wantChats {
 				assert.NotNil(t, chatsDTO)
-				assert.NotEmpty(t, chatsDTO)
+				assert.Len(t, *chatsDTO, 2)
+				assert.Equal(t, int64(3), (*chatsDTO)[0].UnreadCount)
+				assert.Nil(t, (*chatsDTO)[0].LastMessage)
+				assert.Equal(t, lastMessageID, (*chatsDTO)[1].LastMessage.ID)
 			} else {
 				assert.Nil(t, chatsDTO)
 			}
@@ -182,6 +241,7 @@ func TestChatService_GetAll(t *testing.T) {
 func TestChatService_Create(t *testing.T) {
 	tests := []struct {
 		name       string
+		req        chat.CreateRequest
 		setup      func(m *chatServiceMocks)
 		wantErr    bool
 		errMessage string
@@ -203,41 +263,139 @@ func TestChatService_Create(t *testing.T) {
 			errMessage: "some user IDs do not exist",
 		},
 		{
-			name: "error checking chat with this userIDs",
+			name: "error creating chat",
 			setup: func(m *chatServiceMocks) {
 				m.userRepo.On("IDsExists", createRequestExample.UserIDs).Return(true, nil)
-				m.chatRepo.On("ExistsSetUserIDs", createRequestExample.UserIDs).Return(false, errExample)
+				m.chatRepo.On("Create", userID, createRequestExample.Name, createRequestExample.UserIDs).Return(nil, errExample)
 			},
 			wantErr:    true,
 			errMessage: shared.InternalError.Error(),
 		},
 		{
-			name: "chat with the same users already exists",
+			name: "chat created successfully",
 			setup: func(m *chatServiceMocks) {
 				m.userRepo.On("IDsExists", createRequestExample.UserIDs).Return(true, nil)
-				m.chatRepo.On("ExistsSetUserIDs", createRequestExample.UserIDs).Return(true, nil)
+				m.chatRepo.On("Create", userID, createRequestExample.Name, createRequestExample.UserIDs).Return(&systemMessageExample, nil)
+				m.hub.On("UpdateMembership", chatID, systemMessageExample.ID-1, createRequestExample.UserIDs, []uint(nil)).Return()
+				m.hub.On("PublishMessage", systemMessageExample).Return()
+			},
+			wantErr: false,
+		},
+		{
+			name: "creator is added to the members",
+			req:  chat.CreateRequest{UserIDs: []uint{2, 3}},
+			setup: func(m *chatServiceMocks) {
+				m.userRepo.On("IDsExists", []uint{2, 3, 1}).Return(true, nil)
+				m.chatRepo.On("Create", userID, (*string)(nil), []uint{2, 3, 1}).Return(&systemMessageExample, nil)
+				m.hub.On("UpdateMembership", chatID, systemMessageExample.ID-1, []uint{2, 3, 1}, []uint(nil)).Return()
+				m.hub.On("PublishMessage", systemMessageExample).Return()
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			req := tt.req
+			if req.UserIDs == nil {
+				req = createRequestExample
+			}
+
+			err := mocks.chatSrv.Create(userID, req)
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+			}
+			mocks.userRepo.AssertExpectations(t)
+			mocks.chatRepo.AssertExpectations(t)
+			mocks.hub.AssertExpectations(t)
+		})
+	}
+}
+func TestChatService_GetOrCreateDirect(t *testing.T) {
+	peerID := uint(2)
+
+	tests := []struct {
+		name        string
+		peerID      uint
+		setup       func(m *chatServiceMocks)
+		wantErr     bool
+		errMessage  string
+		wantCreated bool
+	}{
+		{
+			name:       "direct chat with oneself",
+			peerID:     userID,
+			setup:      func(m *chatServiceMocks) {},
+			wantErr:    true,
+			errMessage: "cannot open a direct chat with yourself",
+		},
+		{
+			name:   "peer not found",
+			peerID: peerID,
+			setup: func(m *chatServiceMocks) {
+				m.userRepo.On("IDsExists", []uint{peerID}).Return(false, nil)
 			},
 			wantErr:    true,
-			errMessage: "chat with the same users already exists",
+			errMessage: "user not found",
 		},
 		{
-			name: "error creating chat",
+			name:   "failed to check friendship",
+			peerID: peerID,
 			setup: func(m *chatServiceMocks) {
-				m.userRepo.On("IDsExists", createRequestExample.UserIDs).Return(true, nil)
-				m.chatRepo.On("ExistsSetUserIDs", createRequestExample.UserIDs).Return(false, nil)
-				m.chatRepo.On("Create", createRequestExample.Name, createRequestExample.UserIDs).Return(errExample)
+				m.userRepo.On("IDsExists", []uint{peerID}).Return(true, nil)
+				m.friendshipRepo.On("AreFriends", userID, peerID).Return(false, errExample)
 			},
 			wantErr:    true,
 			errMessage: shared.InternalError.Error(),
 		},
 		{
-			name: "chat created successfully",
+			name:   "peer is not a friend",
+			peerID: peerID,
 			setup: func(m *chatServiceMocks) {
-				m.userRepo.On("IDsExists", createRequestExample.UserIDs).Return(true, nil)
-				m.chatRepo.On("ExistsSetUserIDs", createRequestExample.UserIDs).Return(false, nil)
-				m.chatRepo.On("Create", createRequestExample.Name, createRequestExample.UserIDs).Return(nil)
+				m.userRepo.On("IDsExists", []uint{peerID}).Return(true, nil)
+				m.friendshipRepo.On("AreFriends", userID, peerID).Return(false, nil)
 			},
-			wantErr: false,
+			wantErr:    true,
+			errMessage: "direct chats are only available between friends",
+		},
+		{
+			name:   "failed to open direct chat",
+			peerID: peerID,
+			setup: func(m *chatServiceMocks) {
+				m.userRepo.On("IDsExists", []uint{peerID}).Return(true, nil)
+				m.friendshipRepo.On("AreFriends", userID, peerID).Return(true, nil)
+				m.chatRepo.On("GetOrCreateDirect", userID, peerID).Return(uint(0), false, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name:   "existing direct chat is returned",
+			peerID: peerID,
+			setup: func(m *chatServiceMocks) {
+				m.userRepo.On("IDsExists", []uint{peerID}).Return(true, nil)
+				m.friendshipRepo.On("AreFriends", userID, peerID).Return(true, nil)
+				m.chatRepo.On("GetOrCreateDirect", userID, peerID).Return(chatID, false, nil)
+			},
+			wantCreated: false,
+		},
+		{
+			name:   "direct chat is created",
+			peerID: peerID,
+			setup: func(m *chatServiceMocks) {
+				m.userRepo.On("IDsExists", []uint{peerID}).Return(true, nil)
+				m.friendshipRepo.On("AreFriends", userID, peerID).Return(true, nil)
+				m.chatRepo.On("GetOrCreateDirect", userID, peerID).Return(chatID, true, nil)
+				m.hub.On("UpdateMembership", chatID, uint(0), []uint{userID, peerID}, []uint(nil)).Return()
+			},
+			wantCreated: true,
 		},
 	}
 
@@ -246,19 +404,30 @@ func TestChatService_Create(t *testing.T) {
 			mocks := setupChatService()
 			tt.setup(&mocks)
 
-			err := mocks.chatSrv.Create(createRequestExample)
+			direct, err := mocks.chatSrv.GetOrCreateDirect(userID, tt.peerID)
 
 			if tt.wantErr {
+				assert.Nil(t, direct)
 				assert.NotNil(t, err)
 				assert.Equal(t, tt.errMessage, err.Error())
 			} else {
 				assert.Nil(t, err)
+				assert.Equal(t, chatID, direct.ChatID)
+				assert.Equal(t, tt.wantCreated, direct.Created)
 			}
 			mocks.userRepo.AssertExpectations(t)
+			mocks.friendshipRepo.AssertExpectations(t)
+			mocks.chatRepo.AssertExpectations(t)
+			mocks.hub.AssertExpectations(t)
 		})
 	}
 }
+
 func TestChatService_Update(t *testing.T) {
+	req := chat.UpdateRequest{Name: "renamed"}
+	renamed := repository.Message{ID: 8, ChatID: chatID, SenderID: userID, Kind: repository.MessageKindSystem,
+		System: &repository.SystemEvent{Action: repository.SystemChatRenamed, Name: req.Name}}
+
 	tests := []struct {
 		name       string
 		setup      func(m *chatServiceMocks)
@@ -266,77 +435,160 @@ func TestChatService_Update(t *testing.T) {
 		errMessage string
 	}{
 		{
-			name: "error checking user IDs existence",
+			name: "failed to fetch actor role",
 			setup: func(m *chatServiceMocks) {
-				m.userRepo.On("IDsExists", createRequestExample.UserIDs).Return(false, errExample)
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRole(""), errExample)
 			},
 			wantErr:    true,
 			errMessage: shared.InternalError.Error(),
 		},
 		{
-			name: "some user IDs do not exist",
+			name: "actor is not a member of the chat",
 			setup: func(m *chatServiceMocks) {
-				m.userRepo.On("IDsExists", createRequestExample.UserIDs).Return(false, nil)
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRole(""), nil)
 			},
 			wantErr:    true,
-			errMessage: "some user IDs do not exist",
+			errMessage: "chat not found",
 		},
 		{
-			name: "error checking chat with this userIDs",
+			name: "members cannot manage the chat",
 			setup: func(m *chatServiceMocks) {
-				m.userRepo.On("IDsExists", createRequestExample.UserIDs).Return(true, nil)
-				m.chatRepo.On("ExistsSetUserIDs", createRequestExample.UserIDs).Return(false, errExample)
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleMember, nil)
+			},
+			wantErr:    true,
+			errMessage: "only the owner or an admin can manage the chat",
+		},
+		{
+			name: "error while renaming chat",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("Rename", chatID, userID, req.Name).Return(nil, errExample)
 			},
 			wantErr:    true,
 			errMessage: shared.InternalError.Error(),
 		},
 		{
-			name: "chat with the same users already exists",
+			name: "actor demoted before the rename",
 			setup: func(m *chatServiceMocks) {
-				m.userRepo.On("IDsExists", createRequestExample.UserIDs).Return(true, nil)
-				m.chatRepo.On("ExistsSetUserIDs", createRequestExample.UserIDs).Return(true, nil)
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("Rename", chatID, userID, req.Name).Return(nil, repository.ErrNotAllowed)
 			},
 			wantErr:    true,
-			errMessage: "chat with the same users already exists",
+			errMessage: "not allowed to manage this member",
 		},
 		{
-			name: "failed to check chat existence",
+			name: "name is unchanged",
 			setup: func(m *chatServiceMocks) {
-				m.userRepo.On("IDsExists", createRequestExample.UserIDs).Return(true, nil)
-				m.chatRepo.On("ExistsSetUserIDs", createRequestExample.UserIDs).Return(false, nil)
-				m.chatRepo.On("ExistsID", chatID).Return(true, errExample)
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("Rename", chatID, userID, req.Name).Return(nil, nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "chat renamed successfully",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleOwner, nil)
+				m.chatRepo.On("Rename", chatID, userID, req.Name).Return(&renamed, nil)
+				m.hub.On("PublishMessage", renamed).Return()
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			err := mocks.chatSrv.Update(userID, chatID, req)
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+			}
+			mocks.chatRepo.AssertExpectations(t)
+			mocks.hub.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_AddMembers(t *testing.T) {
+	req := chat.AddMembersRequest{UserIDs: []uint{3, 2, 3}}
+	userIDs := []uint{2, 3}
+	added := repository.Message{ID: 8, ChatID: chatID, SenderID: userID, Kind: repository.MessageKindSystem,
+		System: &repository.SystemEvent{Action: repository.SystemMembersAdded, UserIDs: []uint{3}}}
+
+	tests := []struct {
+		name       string
+		setup      func(m *chatServiceMocks)
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name: "members cannot add members",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleMember, nil)
+			},
+			wantErr:    true,
+			errMessage: "only the owner or an admin can manage the chat",
+		},
+		{
+			name: "error checking user IDs existence",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.userRepo.On("IDsExists", userIDs).Return(false, errExample)
 			},
 			wantErr:    true,
 			errMessage: shared.InternalError.Error(),
 		},
 		{
-			name: "chat not found",
+			name: "some user IDs do not exist",
 			setup: func(m *chatServiceMocks) {
-				m.userRepo.On("IDsExists", createRequestExample.UserIDs).Return(true, nil)
-				m.chatRepo.On("ExistsSetUserIDs", createRequestExample.UserIDs).Return(false, nil)
-				m.chatRepo.On("ExistsID", chatID).Return(false, nil)
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.userRepo.On("IDsExists", userIDs).Return(false, nil)
+			},
+			wantErr:    true,
+			errMessage: "some user IDs do not exist",
+		},
+		{
+			name: "chat archived concurrently",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.userRepo.On("IDsExists", userIDs).Return(true, nil)
+				m.chatRepo.On("AddMembers", chatID, userID, userIDs).Return(nil, nil, gorm.ErrRecordNotFound)
 			},
 			wantErr:    true,
 			errMessage: "chat not found",
 		},
 		{
-			name: "error while updating chat",
+			name: "failed to add members",
 			setup: func(m *chatServiceMocks) {
-				m.userRepo.On("IDsExists", createRequestExample.UserIDs).Return(true, nil)
-				m.chatRepo.On("ExistsSetUserIDs", createRequestExample.UserIDs).Return(false, nil)
-				m.chatRepo.On("ExistsID", chatID).Return(true, nil)
-				m.chatRepo.On("Update", chatID, createRequestExample.Name, createRequestExample.UserIDs).Return(errExample)
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.userRepo.On("IDsExists", userIDs).Return(true, nil)
+				m.chatRepo.On("AddMembers", chatID, userID, userIDs).Return(nil, nil, errExample)
 			},
 			wantErr:    true,
 			errMessage: shared.InternalError.Error(),
 		},
 		{
-			name: "chat updated successfully",
+			name: "everyone is already a member",
 			setup: func(m *chatServiceMocks) {
-				m.userRepo.On("IDsExists", createRequestExample.UserIDs).Return(true, nil)
-				m.chatRepo.On("ExistsSetUserIDs", createRequestExample.UserIDs).Return(false, nil)
-				m.chatRepo.On("ExistsID", chatID).Return(true, nil)
-				m.chatRepo.On("Update", chatID, createRequestExample.Name, createRequestExample.UserIDs).Return(nil)
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.userRepo.On("IDsExists", userIDs).Return(true, nil)
+				m.chatRepo.On("AddMembers", chatID, userID, userIDs).Return(nil, nil, nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "only new members are announced",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.userRepo.On("IDsExists", userIDs).Return(true, nil)
+				m.chatRepo.On("AddMembers", chatID, userID, userIDs).Return([]uint{3}, &added, nil)
+				m.hub.On("UpdateMembership", chatID, added.ID-1, []uint{3}, []uint(nil)).Return()
+				m.hub.On("PublishMessage", added).Return()
 			},
 			wantErr: false,
 		},
@@ -347,7 +599,7 @@ func TestChatService_Update(t *testing.T) {
 			mocks := setupChatService()
 			tt.setup(&mocks)
 
-			err := mocks.chatSrv.Update(chatID, createRequestExample)
+			err := mocks.chatSrv.AddMembers(userID, chatID, req)
 
 			if tt.wantErr {
 				assert.NotNil(t, err)
@@ -356,10 +608,17 @@ func TestChatService_Update(t *testing.T) {
 				assert.Nil(t, err)
 			}
 			mocks.userRepo.AssertExpectations(t)
+			mocks.chatRepo.AssertExpectations(t)
+			mocks.hub.AssertExpectations(t)
 		})
 	}
 }
-func TestChatService_HandleWebSocket(t *testing.T) {
+
+func TestChatService_RemoveMember(t *testing.T) {
+	memberID := uint(3)
+	removed := []repository.Message{{ID: 8, ChatID: chatID, SenderID: userID, Kind: repository.MessageKindSystem,
+		System: &repository.SystemEvent{Action: repository.SystemMembersRemoved, UserIDs: []uint{memberID}}}}
+
 	tests := []struct {
 		name       string
 		setup      func(m *chatServiceMocks)
@@ -367,28 +626,77 @@ func TestChatService_HandleWebSocket(t *testing.T) {
 		errMessage string
 	}{
 		{
-			name: "error getting chat IDs",
+			name: "members cannot remove members",
 			setup: func(m *chatServiceMocks) {
-				m.chatRepo.On("GetChatIDsByUserID", userID).Return(nil, errExample)
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleMember, nil)
+			},
+			wantErr:    true,
+			errMessage: "only the owner or an admin can manage the chat",
+		},
+		{
+			name: "member not found",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("GetRole", chatID, memberID).Return(repository.ChatRole(""), nil)
+			},
+			wantErr:    true,
+			errMessage: "member not found",
+		},
+		{
+			name: "owner cannot be removed",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("GetRole", chatID, memberID).Return(repository.ChatRoleOwner, nil)
+			},
+			wantErr:    true,
+			errMessage: "chat owner cannot be removed",
+		},
+		{
+			name: "admin cannot remove another admin",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("GetRole", chatID, memberID).Return(repository.ChatRoleAdmin, nil)
+			},
+			wantErr:    true,
+			errMessage: "only the owner can remove admins",
+		},
+		{
+			name: "failed to remove member",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("GetRole", chatID, memberID).Return(repository.ChatRoleMember, nil)
+				m.chatRepo.On("RemoveMember", chatID, userID, memberID).Return(nil, errExample)
 			},
 			wantErr:    true,
 			errMessage: shared.InternalError.Error(),
 		},
 		{
-			name: "error upgrading to WebSocket",
+			name: "actor demoted before the removal",
 			setup: func(m *chatServiceMocks) {
-				m.chatRepo.On("GetChatIDsByUserID", userID).Return([]uint{chatID}, nil)
-				m.wsUpgrader.On("Upgrade", mock.Anything, mock.Anything, mock.Anything).Return(nil, errExample)
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("GetRole", chatID, memberID).Return(repository.ChatRoleMember, nil)
+				m.chatRepo.On("RemoveMember", chatID, userID, memberID).Return(nil, repository.ErrNotAllowed)
 			},
 			wantErr:    true,
-			errMessage: "WebSocket upgrade failed",
+			errMessage: "not allowed to manage this member",
 		},
 		{
-			name: "successful websocket connection",
+			name: "member removed concurrently",
 			setup: func(m *chatServiceMocks) {
-				m.chatRepo.On("GetChatIDsByUserID", userID).Return([]uint{chatID}, nil)
-				m.wsUpgrader.On("Upgrade", mock.Anything, mock.Anything, mock.Anything).Return(&websocket.Conn{}, nil)
-				m.hub.On("RegisterClient", mock.Anything).Return()
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("GetRole", chatID, memberID).Return(repository.ChatRoleMember, nil)
+				m.chatRepo.On("RemoveMember", chatID, userID, memberID).Return(nil, nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "owner removes an admin",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleOwner, nil)
+				m.chatRepo.On("GetRole", chatID, memberID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("RemoveMember", chatID, userID, memberID).Return(removed, nil)
+				m.hub.On("UpdateMembership", chatID, uint(0), []uint(nil), []uint{memberID}).Return()
+				m.hub.On("PublishMessage", removed[0]).Return()
 			},
 			wantErr: false,
 		},
@@ -399,11 +707,2189 @@ func TestChatService_HandleWebSocket(t *testing.T) {
 			mocks := setupChatService()
 			tt.setup(&mocks)
 
-			// Используем httptest.ResponseRecorder и httptest.NewRequest для моков
+			err := mocks.chatSrv.RemoveMember(userID, chatID, memberID)
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+			}
+			mocks.chatRepo.AssertExpectations(t)
+			mocks.hub.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_Leave(t *testing.T) {
+	// Владелец уходит, владельцем становится пользователь 2
+	left := []repository.Message{
+		{ID: 8, ChatID: chatID, SenderID: userID, Kind: repository.MessageKindSystem,
+			System: &repository.SystemEvent{Action: repository.SystemMemberLeft}},
+		{ID: 9, ChatID: chatID, SenderID: userID, Kind: repository.MessageKindSystem,
+			System: &repository.SystemEvent{Action: repository.SystemRoleChanged, UserIDs: []uint{2}, Role: repository.ChatRoleOwner}},
+	}
+
+	tests := []struct {
+		name       string
+		setup      func(m *chatServiceMocks)
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name: "failed to check chat membership",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(false, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "user is not a member of the chat",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(false, nil)
+			},
+			wantErr:    true,
+			errMessage: "chat not found",
+		},
+		{
+			name: "direct chats cannot be left",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.chatRepo.On("GetType", chatID).Return(repository.ChatTypeDirect, nil)
+			},
+			wantErr:    true,
+			errMessage: "direct chats cannot be left",
+		},
+		{
+			name: "chat already archived",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.chatRepo.On("GetType", chatID).Return(repository.ChatTypeGroup, nil)
+				m.chatRepo.On("RemoveMember", chatID, userID, userID).Return(nil, gorm.ErrRecordNotFound)
+			},
+			wantErr:    true,
+			errMessage: "chat not found",
+		},
+		{
+			name: "owner leaves and hands over the chat",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.chatRepo.On("GetType", chatID).Return(repository.ChatTypeGroup, nil)
+				m.chatRepo.On("RemoveMember", chatID, userID, userID).Return(left, nil)
+				m.hub.On("UpdateMembership", chatID, uint(0), []uint(nil), []uint{userID}).Return()
+				m.hub.On("PublishMessage", left[0]).Return().Once()
+				m.hub.On("PublishMessage", left[1]).Return().Once()
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			err := mocks.chatSrv.Leave(userID, chatID)
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+			}
+			mocks.chatRepo.AssertExpectations(t)
+			mocks.hub.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_SetRole(t *testing.T) {
+	memberID := uint(2)
+	roleMessage := repository.Message{ID: 10, ChatID: chatID, SenderID: userID, Kind: repository.MessageKindSystem,
+		System: &repository.SystemEvent{Action: repository.SystemRoleChanged, UserIDs: []uint{memberID}, Role: repository.ChatRoleAdmin}}
+
+	tests := []struct {
+		name       string
+		role       repository.ChatRole
+		setup      func(m *chatServiceMocks)
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name: "actor is not a member of the chat",
+			role: repository.ChatRoleAdmin,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRole(""), nil)
+			},
+			wantErr:    true,
+			errMessage: "chat not found",
+		},
+		{
+			name: "members cannot change roles",
+			role: repository.ChatRoleAdmin,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleMember, nil)
+			},
+			wantErr:    true,
+			errMessage: "only the owner or an admin can manage the chat",
+		},
+		{
+			name: "failed to fetch member role",
+			role: repository.ChatRoleAdmin,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("GetRole", chatID, memberID).Return(repository.ChatRole(""), errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "member not found",
+			role: repository.ChatRoleAdmin,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("GetRole", chatID, memberID).Return(repository.ChatRole(""), nil)
+			},
+			wantErr:    true,
+			errMessage: "member not found",
+		},
+		{
+			name: "owner role cannot be changed",
+			role: repository.ChatRoleMember,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("GetRole", chatID, memberID).Return(repository.ChatRoleOwner, nil)
+			},
+			wantErr:    true,
+			errMessage: "owner role cannot be changed",
+		},
+		{
+			name: "admin cannot demote another admin",
+			role: repository.ChatRoleMember,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("GetRole", chatID, memberID).Return(repository.ChatRoleAdmin, nil)
+			},
+			wantErr:    true,
+			errMessage: "only the owner can demote admins",
+		},
+		{
+			name: "role is unchanged",
+			role: repository.ChatRoleAdmin,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("GetRole", chatID, memberID).Return(repository.ChatRoleAdmin, nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "failed to change role",
+			role: repository.ChatRoleAdmin,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("GetRole", chatID, memberID).Return(repository.ChatRoleMember, nil)
+				m.chatRepo.On("SetRole", chatID, userID, memberID, repository.ChatRoleAdmin).Return(nil, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "actor demoted before the change",
+			role: repository.ChatRoleAdmin,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("GetRole", chatID, memberID).Return(repository.ChatRoleMember, nil)
+				m.chatRepo.On("SetRole", chatID, userID, memberID, repository.ChatRoleAdmin).Return(nil, repository.ErrNotAllowed)
+			},
+			wantErr:    true,
+			errMessage: "not allowed to manage this member",
+		},
+		{
+			name: "admin promotes a member",
+			role: repository.ChatRoleAdmin,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("GetRole", chatID, memberID).Return(repository.ChatRoleMember, nil)
+				m.chatRepo.On("SetRole", chatID, userID, memberID, repository.ChatRoleAdmin).Return(&roleMessage, nil)
+				m.hub.On("PublishMessage", roleMessage).Return()
+			},
+			wantErr: false,
+		},
+		{
+			name: "owner demotes an admin changed concurrently",
+			role: repository.ChatRoleMember,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleOwner, nil)
+				m.chatRepo.On("GetRole", chatID, memberID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("SetRole", chatID, userID, memberID, repository.ChatRoleMember).Return(nil, nil)
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			err := mocks.chatSrv.SetRole(userID, chatID, memberID, chat.RoleRequest{Role: tt.role})
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+			}
+			mocks.chatRepo.AssertExpectations(t)
+			mocks.hub.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_SetRetention(t *testing.T) {
+	day, tooLong := int64(86400), int64(retentionMaxAge/time.Second)+1
+	changed := repository.Message{ID: 9, ChatID: chatID, SenderID: userID, Kind: repository.MessageKindSystem,
+		System: &repository.SystemEvent{Action: repository.SystemRetentionChanged, MessageTTLSeconds: &day}}
+
+	tests := []struct {
+		name       string
+		req        chat.RetentionRequest
+		setup      func(m *chatServiceMocks)
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name: "not a member",
+			req:  chat.RetentionRequest{MessageTTLSeconds: &day},
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRole(""), nil)
+			},
+			wantErr:    true,
+			errMessage: "chat not found",
+		},
+		{
+			name: "group members cannot change retention",
+			req:  chat.RetentionRequest{MessageTTLSeconds: &day},
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleMember, nil)
+				m.chatRepo.On("GetType", chatID).Return(repository.ChatTypeGroup, nil)
+			},
+			wantErr:    true,
+			errMessage: "only the owner or an admin can manage the chat",
+		},
+		{
+			name: "nothing to update",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleOwner, nil)
+			},
+			wantErr:    true,
+			errMessage: "nothing to update",
+		},
+		{
+			name: "retention above the global limit",
+			req:  chat.RetentionRequest{RetentionSeconds: &tooLong},
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+			},
+			wantErr:    true,
+			errMessage: "retention exceeds the global limit",
+		},
+		{
+			name: "failed to change retention",
+			req:  chat.RetentionRequest{MessageTTLSeconds: &day},
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("SetRetention", chatID, userID, (*int64)(nil), &day).Return(nil, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "retention unchanged",
+			req:  chat.RetentionRequest{MessageTTLSeconds: &day},
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("SetRetention", chatID, userID, (*int64)(nil), &day).Return(nil, nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "disappearing messages enabled in a direct chat",
+			req:  chat.RetentionRequest{MessageTTLSeconds: &day},
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleMember, nil)
+				m.chatRepo.On("GetType", chatID).Return(repository.ChatTypeDirect, nil)
+				m.chatRepo.On("SetRetention", chatID, userID, (*int64)(nil), &day).Return(&changed, nil)
+				m.hub.On("PublishMessage", changed).Return()
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			err := mocks.chatSrv.SetRetention(userID, chatID, tt.req)
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+			}
+			mocks.chatRepo.AssertExpectations(t)
+			mocks.hub.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_CreateInvite(t *testing.T) {
+	maxUses := 5
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name       string
+		req        chat.CreateInviteRequest
+		setup      func(m *chatServiceMocks)
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name: "members cannot create invites",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleMember, nil)
+			},
+			wantErr:    true,
+			errMessage: "only the owner or an admin can manage the chat",
+		},
+		{
+			name: "direct chats have no invites",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("GetType", chatID).Return(repository.ChatTypeDirect, nil)
+			},
+			wantErr:    true,
+			errMessage: "invites are only available for group chats",
+		},
+		{
+			name: "expiry in the past",
+			req:  chat.CreateInviteRequest{ExpiresAt: &past},
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("GetType", chatID).Return(repository.ChatTypeGroup, nil)
+			},
+			wantErr:    true,
+			errMessage: "expires_at must be in the future",
+		},
+		{
+			name: "failed to create invite",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.chatRepo.On("GetType", chatID).Return(repository.ChatTypeGroup, nil)
+				m.chatRepo.On("CreateInvite", mock.Anything).Return(errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "invite created",
+			req:  chat.CreateInviteRequest{MaxUses: &maxUses, ExpiresAt: &future},
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleOwner, nil)
+				m.chatRepo.On("GetType", chatID).Return(repository.ChatTypeGroup, nil)
+				m.chatRepo.On("CreateInvite", mock.MatchedBy(func(invite *repository.ChatInvite) bool {
+					return invite.ChatID == chatID && invite.CreatorID == userID && *invite.MaxUses == maxUses && invite.ExpiresAt.Equal(future)
+				})).Run(func(args mock.Arguments) {
+					args.Get(0).(*repository.ChatInvite).ID = 3
+				}).Return(nil)
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			invite, err := mocks.chatSrv.CreateInvite(userID, chatID, tt.req)
+
+			if tt.wantErr {
+				assert.Nil(t, invite)
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, uint(3), invite.ID)
+				// 16 случайных байт в hex
+				assert.Len(t, invite.Code, 32)
+			}
+			mocks.chatRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_JoinByInvite(t *testing.T) {
+	const code = "0123456789abcdef0123456789abcdef"
+	joined := repository.Message{ID: 8, ChatID: chatID, SenderID: userID, Kind: repository.MessageKindSystem,
+		System: &repository.SystemEvent{Action: repository.SystemMemberJoined}}
+
+	tests := []struct {
+		name       string
+		setup      func(m *chatServiceMocks)
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name: "invite not found",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("JoinByInvite", code, userID).Return(uint(0), nil, gorm.ErrRecordNotFound)
+			},
+			wantErr:    true,
+			errMessage: "invite not found",
+		},
+		{
+			name: "invite is no longer valid",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("JoinByInvite", code, userID).Return(chatID, nil, repository.ErrInviteUnavailable)
+			},
+			wantErr:    true,
+			errMessage: "invite is no longer valid",
+		},
+		{
+			name: "failed to join",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("JoinByInvite", code, userID).Return(uint(0), nil, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "already a member, even with a used up invite",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("JoinByInvite", code, userID).Return(chatID, nil, nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "joined the chat",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("JoinByInvite", code, userID).Return(chatID, &joined, nil)
+				m.hub.On("UpdateMembership", chatID, joined.ID-1, []uint{userID}, []uint(nil)).Return()
+				m.hub.On("PublishMessage", joined).Return()
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			resp, err := mocks.chatSrv.JoinByInvite(userID, code)
+
+			if tt.wantErr {
+				assert.Nil(t, resp)
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, chatID, resp.ChatID)
+			}
+			mocks.chatRepo.AssertExpectations(t)
+			mocks.hub.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_PinMessage(t *testing.T) {
+	const messageID = uint(10)
+	deletedAt := time.Now()
+	message := repository.Message{ID: messageID, ChatID: chatID, SenderID: 2, Content: "hello", Kind: repository.MessageKindText}
+	foreign := repository.Message{ID: messageID, ChatID: 2, Kind: repository.MessageKindText}
+	deleted := repository.Message{ID: messageID, ChatID: chatID, Kind: repository.MessageKindText, DeletedAt: &deletedAt}
+	system := repository.Message{ID: messageID, ChatID: chatID, Kind: repository.MessageKindSystem}
+	pin := repository.PinnedMessage{ChatID: chatID, MessageID: messageID, PinnedBy: userID, PinnedAt: time.Now()}
+
+	tests := []struct {
+		name       string
+		setup      func(m *chatServiceMocks)
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name: "members cannot pin messages",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleMember, nil)
+			},
+			wantErr:    true,
+			errMessage: "only the owner or an admin can manage the chat",
+		},
+		{
+			name: "message not found",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.messageRepo.On("GetByID", messageID).Return(nil, gorm.ErrRecordNotFound)
+			},
+			wantErr:    true,
+			errMessage: "message not found",
+		},
+		{
+			name: "message belongs to another chat",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.messageRepo.On("GetByID", messageID).Return(&foreign, nil)
+			},
+			wantErr:    true,
+			errMessage: "message not found",
+		},
+		{
+			name: "message is deleted",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.messageRepo.On("GetByID", messageID).Return(&deleted, nil)
+			},
+			wantErr:    true,
+			errMessage: "message is deleted",
+		},
+		{
+			name: "system messages cannot be pinned",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleOwner, nil)
+				m.messageRepo.On("GetByID", messageID).Return(&system, nil)
+			},
+			wantErr:    true,
+			errMessage: "system messages cannot be pinned",
+		},
+		{
+			name: "pinned messages limit reached",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.messageRepo.On("GetByID", messageID).Return(&message, nil)
+				m.messageRepo.On("Pin", chatID, messageID, userID, maxPins).Return(nil, repository.ErrPinLimitReached)
+			},
+			wantErr:    true,
+			errMessage: "pinned messages limit reached",
+		},
+		{
+			name: "chat is archived",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.messageRepo.On("GetByID", messageID).Return(&message, nil)
+				m.messageRepo.On("Pin", chatID, messageID, userID, maxPins).Return(nil, gorm.ErrRecordNotFound)
+			},
+			wantErr:    true,
+			errMessage: "chat not found",
+		},
+		{
+			name: "failed to pin message",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.messageRepo.On("GetByID", messageID).Return(&message, nil)
+				m.messageRepo.On("Pin", chatID, messageID, userID, maxPins).Return(nil, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "already pinned",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.messageRepo.On("GetByID", messageID).Return(&message, nil)
+				m.messageRepo.On("Pin", chatID, messageID, userID, maxPins).Return(nil, nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "message pinned",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.messageRepo.On("GetByID", messageID).Return(&message, nil)
+				m.messageRepo.On("Pin", chatID, messageID, userID, maxPins).Return(&pin, nil)
+				m.hub.On("PublishPin", ws.EventPinned, chatID, ws.Pin{
+					MessageID: messageID,
+					UserID:    userID,
+					PinnedAt:  &pin.PinnedAt,
+					Message:   message.ConvertToPreviewDTO(),
+				}).Return()
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			err := mocks.chatSrv.PinMessage(userID, chatID, messageID)
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+			}
+			mocks.chatRepo.AssertExpectations(t)
+			mocks.messageRepo.AssertExpectations(t)
+			mocks.hub.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_UnpinMessage(t *testing.T) {
+	const messageID = uint(10)
+
+	tests := []struct {
+		name       string
+		setup      func(m *chatServiceMocks)
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name: "not a member",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRole(""), nil)
+			},
+			wantErr:    true,
+			errMessage: "chat not found",
+		},
+		{
+			name: "failed to unpin message",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.messageRepo.On("Unpin", chatID, messageID).Return(false, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "message is not pinned",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.messageRepo.On("Unpin", chatID, messageID).Return(false, nil)
+			},
+			wantErr:    true,
+			errMessage: "message is not pinned",
+		},
+		{
+			name: "message unpinned",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleOwner, nil)
+				m.messageRepo.On("Unpin", chatID, messageID).Return(true, nil)
+				m.hub.On("PublishPin", ws.EventUnpinned, chatID, ws.Pin{MessageID: messageID, UserID: userID}).Return()
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			err := mocks.chatSrv.UnpinMessage(userID, chatID, messageID)
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+			}
+			mocks.chatRepo.AssertExpectations(t)
+			mocks.messageRepo.AssertExpectations(t)
+			mocks.hub.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_UpdateSettings(t *testing.T) {
+	muted, unmuted, archived := true, false, true
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name       string
+		req        chat.SettingsRequest
+		setup      func(m *chatServiceMocks)
+		wantErr    bool
+		errMessage string
+		want       repository.ChatSettingsDTO
+	}{
+		{
+			name:       "nothing to update",
+			setup:      func(m *chatServiceMocks) {},
+			wantErr:    true,
+			errMessage: "no settings to update",
+		},
+		{
+			name:       "mute expiry for unmuted chat",
+			req:        chat.SettingsRequest{Muted: &unmuted, MutedUntil: &future},
+			setup:      func(m *chatServiceMocks) {},
+			wantErr:    true,
+			errMessage: "muted_until requires muted to be true",
+		},
+		{
+			name:       "mute expiry in the past",
+			req:        chat.SettingsRequest{MutedUntil: &past},
+			setup:      func(m *chatServiceMocks) {},
+			wantErr:    true,
+			errMessage: "muted_until must be in the future",
+		},
+		{
+			name: "not a member",
+			req:  chat.SettingsRequest{Muted: &muted},
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("UpdateSettings", chatID, userID, map[string]interface{}{"muted": true, "muted_until": nil}).
+					Return(nil, gorm.ErrRecordNotFound)
+			},
+			wantErr:    true,
+			errMessage: "chat not found",
+		},
+		{
+			name: "failed to update settings",
+			req:  chat.SettingsRequest{Muted: &unmuted},
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("UpdateSettings", chatID, userID, map[string]interface{}{"muted": false, "muted_until": nil}).
+					Return(nil, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "muted until a given time",
+			req:  chat.SettingsRequest{MutedUntil: &future},
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("UpdateSettings", chatID, userID, map[string]interface{}{"muted": true, "muted_until": future}).
+					Return(&repository.ChatMember{ChatID: chatID, UserID: userID, Muted: true, MutedUntil: &future}, nil)
+			},
+			wantErr: false,
+			want:    repository.ChatSettingsDTO{Muted: true, MutedUntil: &future},
+		},
+		{
+			name: "archived and unpinned",
+			req:  chat.SettingsRequest{Archived: &archived, Pinned: &unmuted},
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("UpdateSettings", chatID, userID, mock.MatchedBy(func(changes map[string]interface{}) bool {
+					_, isTime := changes["archived_at"].(time.Time)
+					return len(changes) == 2 && isTime && changes["pinned_at"] == nil
+				})).Return(&repository.ChatMember{ChatID: chatID, UserID: userID, ArchivedAt: &past}, nil)
+			},
+			wantErr: false,
+			want:    repository.ChatSettingsDTO{Archived: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			settings, err := mocks.chatSrv.UpdateSettings(userID, chatID, tt.req)
+
+			if tt.wantErr {
+				assert.Nil(t, settings)
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.want, *settings)
+			}
+			mocks.chatRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_ScheduleMessage(t *testing.T) {
+	replyToID := uint(10)
+	future := time.Now().Add(time.Hour)
+	req := chat.ScheduleMessageRequest{Content: "later", SendAt: future}
+	reply := chat.ScheduleMessageRequest{Content: "later", ReplyToID: &replyToID, SendAt: future}
+
+	tests := []struct {
+		name       string
+		req        chat.ScheduleMessageRequest
+		setup      func(m *chatServiceMocks)
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name: "not a member",
+			req:  req,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(false, nil)
+			},
+			wantErr:    true,
+			errMessage: "chat not found",
+		},
+		{
+			name: "send time in the past",
+			req:  chat.ScheduleMessageRequest{Content: "later", SendAt: time.Now().Add(-time.Minute)},
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+			},
+			wantErr:    true,
+			errMessage: "send_at must be in the future",
+		},
+		{
+			name: "replied message in another chat",
+			req:  reply,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.messageRepo.On("GetByID", replyToID).Return(&repository.Message{ID: replyToID, ChatID: 2}, nil)
+			},
+			wantErr:    true,
+			errMessage: "message not found",
+		},
+		{
+			name: "failed to schedule",
+			req:  req,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.scheduledRepo.On("Create", mock.Anything).Return(errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "reply scheduled",
+			req:  reply,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.messageRepo.On("GetByID", replyToID).Return(&repository.Message{ID: replyToID, ChatID: chatID}, nil)
+				m.scheduledRepo.On("Create", &repository.ScheduledMessage{ChatID: chatID, SenderID: userID, Content: "later", ReplyToID: &replyToID, SendAt: future}).
+					Run(func(args mock.Arguments) { args.Get(0).(*repository.ScheduledMessage).ID = 3 }).
+					Return(nil)
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			scheduled, err := mocks.chatSrv.ScheduleMessage(userID, chatID, tt.req)
+
+			if tt.wantErr {
+				assert.Nil(t, scheduled)
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, uint(3), scheduled.ID)
+				assert.Equal(t, future, scheduled.SendAt)
+			}
+			mocks.chatRepo.AssertExpectations(t)
+			mocks.messageRepo.AssertExpectations(t)
+			mocks.scheduledRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_EditScheduled(t *testing.T) {
+	const scheduledID = uint(3)
+	content := "updated"
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name       string
+		req        chat.EditScheduledRequest
+		setup      func(m *chatServiceMocks)
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name:       "nothing to update",
+			setup:      func(m *chatServiceMocks) {},
+			wantErr:    true,
+			errMessage: "nothing to update",
+		},
+		{
+			name:       "send time in the past",
+			req:        chat.EditScheduledRequest{SendAt: &past},
+			setup:      func(m *chatServiceMocks) {},
+			wantErr:    true,
+			errMessage: "send_at must be in the future",
+		},
+		{
+			name: "already sent or cancelled",
+			req:  chat.EditScheduledRequest{Content: &content},
+			setup: func(m *chatServiceMocks) {
+				m.scheduledRepo.On("Update", scheduledID, chatID, userID, map[string]interface{}{"content": content}).Return(nil, nil)
+			},
+			wantErr:    true,
+			errMessage: "scheduled message not found",
+		},
+		{
+			name: "rescheduled",
+			req:  chat.EditScheduledRequest{Content: &content, SendAt: &future},
+			setup: func(m *chatServiceMocks) {
+				m.scheduledRepo.On("Update", scheduledID, chatID, userID, map[string]interface{}{"content": content, "send_at": future}).
+					Return(&repository.ScheduledMessage{ID: scheduledID, ChatID: chatID, SenderID: userID, Content: content, SendAt: future}, nil)
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			scheduled, err := mocks.chatSrv.EditScheduled(userID, chatID, scheduledID, tt.req)
+
+			if tt.wantErr {
+				assert.Nil(t, scheduled)
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, content, scheduled.Content)
+			}
+			mocks.scheduledRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_MarkRead(t *testing.T) {
+	req := chat.ReadRequest{MessageID: 10}
+
+	tests := []struct {
+		name       string
+		setup      func(m *chatServiceMocks)
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name: "error checking membership",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(false, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "user is not a member of the chat",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(false, nil)
+			},
+			wantErr:    true,
+			errMessage: "chat not found",
+		},
+		{
+			name: "error marking chat as read",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.hub.On("MarkRead", userID, chatID, req.MessageID).Return(false, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "chat marked as read",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.hub.On("MarkRead", userID, chatID, req.MessageID).Return(true, nil)
+			},
+		},
+		{
+			name: "already read position is not an error",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.hub.On("MarkRead", userID, chatID, req.MessageID).Return(false, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			err := mocks.chatSrv.MarkRead(userID, chatID, req)
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+			}
+			mocks.chatRepo.AssertExpectations(t)
+			mocks.hub.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_EditMessage(t *testing.T) {
+	const (
+		messageID = uint(10)
+		senderID  = uint(2)
+	)
+	req := chat.EditMessageRequest{Content: "edited"}
+	deletedAt := time.Now()
+	edited := &repository.Message{ID: messageID, ChatID: chatID, SenderID: senderID, Content: req.Content, EditedAt: &deletedAt}
+
+	tests := []struct {
+		name       string
+		editorID   uint
+		setup      func(m *chatServiceMocks)
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name:     "message not found",
+			editorID: senderID,
+			setup: func(m *chatServiceMocks) {
+				m.messageRepo.On("GetByID", messageID).Return(nil, gorm.ErrRecordNotFound)
+			},
+			wantErr:    true,
+			errMessage: "message not found",
+		},
+		{
+			name:     "message belongs to another chat",
+			editorID: senderID,
+			setup: func(m *chatServiceMocks) {
+				m.messageRepo.On("GetByID", messageID).Return(&repository.Message{ID: messageID, ChatID: chatID + 1, SenderID: senderID}, nil)
+			},
+			wantErr:    true,
+			errMessage: "message not found",
+		},
+		{
+			name:     "message already deleted",
+			editorID: senderID,
+			setup: func(m *chatServiceMocks) {
+				m.messageRepo.On("GetByID", messageID).Return(&repository.Message{ID: messageID, ChatID: chatID, SenderID: senderID, DeletedAt: &deletedAt}, nil)
+			},
+			wantErr:    true,
+			errMessage: "message is deleted",
+		},
+		{
+			name:     "system messages cannot be edited",
+			editorID: senderID,
+			setup: func(m *chatServiceMocks) {
+				m.messageRepo.On("GetByID", messageID).Return(&repository.Message{ID: messageID, ChatID: chatID, SenderID: senderID, Kind: repository.MessageKindSystem}, nil)
+			},
+			wantErr:    true,
+			errMessage: "system messages cannot be changed",
+		},
+		{
+			name:     "regular member cannot edit someone else's message",
+			editorID: userID,
+			setup: func(m *chatServiceMocks) {
+				m.messageRepo.On("GetByID", messageID).Return(&repository.Message{ID: messageID, ChatID: chatID, SenderID: senderID}, nil)
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleMember, nil)
+			},
+			wantErr:    true,
+			errMessage: "only the sender or a chat admin can change this message",
+		},
+		{
+			name:     "sender is no longer a member",
+			editorID: senderID,
+			setup: func(m *chatServiceMocks) {
+				m.messageRepo.On("GetByID", messageID).Return(&repository.Message{ID: messageID, ChatID: chatID, SenderID: senderID}, nil)
+				m.chatRepo.On("GetRole", chatID, senderID).Return(repository.ChatRole(""), nil)
+			},
+			wantErr:    true,
+			errMessage: "chat not found",
+		},
+		{
+			name:     "message deleted concurrently",
+			editorID: senderID,
+			setup: func(m *chatServiceMocks) {
+				m.messageRepo.On("GetByID", messageID).Return(&repository.Message{ID: messageID, ChatID: chatID, SenderID: senderID}, nil)
+				m.chatRepo.On("GetRole", chatID, senderID).Return(repository.ChatRoleMember, nil)
+				m.messageRepo.On("Edit", messageID, senderID, req.Content).Return(nil, repository.ErrMessageDeleted)
+			},
+			wantErr:    true,
+			errMessage: "message is deleted",
+		},
+		{
+			name:     "error editing message",
+			editorID: senderID,
+			setup: func(m *chatServiceMocks) {
+				m.messageRepo.On("GetByID", messageID).Return(&repository.Message{ID: messageID, ChatID: chatID, SenderID: senderID}, nil)
+				m.chatRepo.On("GetRole", chatID, senderID).Return(repository.ChatRoleMember, nil)
+				m.messageRepo.On("Edit", messageID, senderID, req.Content).Return(nil, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name:     "sender edits the message",
+			editorID: senderID,
+			setup: func(m *chatServiceMocks) {
+				m.messageRepo.On("GetByID", messageID).Return(&repository.Message{ID: messageID, ChatID: chatID, SenderID: senderID}, nil)
+				m.chatRepo.On("GetRole", chatID, senderID).Return(repository.ChatRoleMember, nil)
+				m.messageRepo.On("Edit", messageID, senderID, req.Content).Return(edited, nil)
+				m.hub.On("PublishMessageChange", ws.EventMessageUpdated, *edited).Return()
+			},
+		},
+		{
+			name:     "admin edits someone else's message",
+			editorID: userID,
+			setup: func(m *chatServiceMocks) {
+				m.messageRepo.On("GetByID", messageID).Return(&repository.Message{ID: messageID, ChatID: chatID, SenderID: senderID}, nil)
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleAdmin, nil)
+				m.messageRepo.On("Edit", messageID, userID, req.Content).Return(edited, nil)
+				m.hub.On("PublishMessageChange", ws.EventMessageUpdated, *edited).Return()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			message, err := mocks.chatSrv.EditMessage(tt.editorID, chatID, messageID, req)
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, req.Content, message.Content)
+				assert.NotNil(t, message.EditedAt)
+			}
+			mocks.messageRepo.AssertExpectations(t)
+			mocks.chatRepo.AssertExpectations(t)
+			mocks.hub.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_DeleteMessage(t *testing.T) {
+	const messageID = uint(10)
+	deletedAt := time.Now()
+	tombstone := &repository.Message{ID: messageID, ChatID: chatID, SenderID: userID, DeletedAt: &deletedAt}
+
+	tests := []struct {
+		name       string
+		setup      func(m *chatServiceMocks)
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name: "message deleted concurrently",
+			setup: func(m *chatServiceMocks) {
+				m.messageRepo.On("GetByID", messageID).Return(&repository.Message{ID: messageID, ChatID: chatID, SenderID: userID}, nil)
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleMember, nil)
+				m.messageRepo.On("SoftDelete", messageID, userID).Return(nil, repository.ErrMessageDeleted)
+			},
+			wantErr:    true,
+			errMessage: "message is deleted",
+		},
+		{
+			name: "error deleting message",
+			setup: func(m *chatServiceMocks) {
+				m.messageRepo.On("GetByID", messageID).Return(&repository.Message{ID: messageID, ChatID: chatID, SenderID: userID}, nil)
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleMember, nil)
+				m.messageRepo.On("SoftDelete", messageID, userID).Return(nil, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "sender deletes the message",
+			setup: func(m *chatServiceMocks) {
+				m.messageRepo.On("GetByID", messageID).Return(&repository.Message{ID: messageID, ChatID: chatID, SenderID: userID}, nil)
+				m.chatRepo.On("GetRole", chatID, userID).Return(repository.ChatRoleMember, nil)
+				m.messageRepo.On("SoftDelete", messageID, userID).Return(tombstone, nil)
+				m.hub.On("PublishMessageChange", ws.EventMessageDeleted, *tombstone).Return()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			err := mocks.chatSrv.DeleteMessage(userID, chatID, messageID)
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+			}
+			mocks.messageRepo.AssertExpectations(t)
+			mocks.chatRepo.AssertExpectations(t)
+			mocks.hub.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_GetThread(t *testing.T) {
+	const (
+		messageID = uint(10)
+		limit     = 2
+	)
+	parent := &repository.Message{ID: messageID, ChatID: chatID, SenderID: userID, Content: "parent"}
+	reply := func(id uint) repository.Message {
+		parentID := messageID
+		return repository.Message{ID: id, ChatID: chatID, SenderID: userID, ReplyToID: &parentID}
+	}
+	nextAfterID := uint(12)
+
+	tests := []struct {
+		name       string
+		setup      func(m *chatServiceMocks)
+		wantIDs    []uint
+		wantNext   *uint
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name: "user is not a member",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(false, nil)
+			},
+			wantErr:    true,
+			errMessage: "chat not found",
+		},
+		{
+			name: "parent message belongs to another chat",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.messageRepo.On("GetByID", messageID).Return(&repository.Message{ID: messageID, ChatID: chatID + 1}, nil)
+			},
+			wantErr:    true,
+			errMessage: "message not found",
+		},
+		{
+			name: "error fetching replies",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.messageRepo.On("GetByID", messageID).Return(parent, nil)
+				m.messageRepo.On("ListReplies", messageID, uint(0), limit+1).Return(nil, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "last page",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.messageRepo.On("GetByID", messageID).Return(parent, nil)
+				m.messageRepo.On("ListReplies", messageID, uint(0), limit+1).Return([]repository.Message{reply(11)}, nil)
+			},
+			wantIDs: []uint{11},
+		},
+		{
+			name: "more replies available",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.messageRepo.On("GetByID", messageID).Return(parent, nil)
+				m.messageRepo.On("ListReplies", messageID, uint(0), limit+1).Return([]repository.Message{reply(11), reply(12), reply(13)}, nil)
+			},
+			wantIDs:  []uint{11, 12},
+			wantNext: &nextAfterID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			thread, err := mocks.chatSrv.GetThread(userID, chatID, messageID, 0, limit)
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+				assert.Nil(t, thread)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, messageID, thread.Parent.ID)
+				var ids []uint
+				for _, reply := range thread.Replies {
+					ids = append(ids, reply.ID)
+				}
+				assert.Equal(t, tt.wantIDs, ids)
+				assert.Equal(t, tt.wantNext, thread.NextAfterID)
+			}
+			mocks.chatRepo.AssertExpectations(t)
+			mocks.messageRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_AddReaction(t *testing.T) {
+	const messageID = uint(10)
+	req := chat.ReactionRequest{Emoji: "👍"}
+	reaction := &ws.Reaction{MessageID: messageID, UserID: userID, Emoji: req.Emoji, Count: 2}
+
+	tests := []struct {
+		name       string
+		setup      func(m *chatServiceMocks)
+		want       *ws.Reaction
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name: "reaction is not allowed",
+			setup: func(m *chatServiceMocks) {
+				m.hub.On("React", userID, chatID, messageID, req.Emoji, true).Return(nil, ws.ErrReactionNotAllowed)
+			},
+			wantErr:    true,
+			errMessage: "reaction is not allowed",
+		},
+		{
+			name: "user is not a member",
+			setup: func(m *chatServiceMocks) {
+				m.hub.On("React", userID, chatID, messageID, req.Emoji, true).Return(nil, ws.ErrNotChatMember)
+			},
+			wantErr:    true,
+			errMessage: "chat not found",
+		},
+		{
+			name: "message not found",
+			setup: func(m *chatServiceMocks) {
+				m.hub.On("React", userID, chatID, messageID, req.Emoji, true).Return(nil, ws.ErrMessageNotFound)
+			},
+			wantErr:    true,
+			errMessage: "message not found",
+		},
+		{
+			name: "error saving reaction",
+			setup: func(m *chatServiceMocks) {
+				m.hub.On("React", userID, chatID, messageID, req.Emoji, true).Return(nil, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "reaction added",
+			setup: func(m *chatServiceMocks) {
+				m.hub.On("React", userID, chatID, messageID, req.Emoji, true).Return(reaction, nil)
+			},
+			want: reaction,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			got, err := mocks.chatSrv.AddReaction(userID, chatID, messageID, req)
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+			mocks.hub.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_CreatePoll(t *testing.T) {
+	const messageID = uint(10)
+	past := time.Now().Add(-time.Minute)
+	req := chat.CreatePollRequest{Question: "Where?", Options: []string{"Here", "There"}, Multiple: true}
+	created := repository.Message{
+		ID:       messageID,
+		ChatID:   chatID,
+		SenderID: userID,
+		Content:  req.Question,
+		Kind:     repository.MessageKindPoll,
+		Poll: &repository.Poll{
+			MessageID: messageID,
+			Multiple:  true,
+			Options:   []repository.PollOption{{ID: 1, MessageID: messageID, Text: "Here"}, {ID: 2, MessageID: messageID, Position: 1, Text: "There"}},
+		},
+	}
+	isPoll := mock.MatchedBy(func(messages []*repository.Message) bool {
+		message := messages[0]
+		return len(messages) == 1 && message.Kind == repository.MessageKindPoll && message.Content == req.Question &&
+			message.Poll.Multiple && len(message.Poll.Options) == 2 && message.Poll.Options[1].Position == 1
+	})
+
+	tests := []struct {
+		name       string
+		req        chat.CreatePollRequest
+		setup      func(m *chatServiceMocks)
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name: "user is not a member",
+			req:  req,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(false, nil)
+			},
+			wantErr:    true,
+			errMessage: "chat not found",
+		},
+		{
+			name: "closing time in the past",
+			req:  chat.CreatePollRequest{Question: req.Question, Options: req.Options, ClosesAt: &past},
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+			},
+			wantErr:    true,
+			errMessage: "closes_at must be in the future",
+		},
+		{
+			name: "duplicate options",
+			req:  chat.CreatePollRequest{Question: req.Question, Options: []string{"Here", "Here"}},
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+			},
+			wantErr:    true,
+			errMessage: "poll options must be unique",
+		},
+		{
+			name: "failed to create poll",
+			req:  req,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.messageRepo.On("CreateBatch", isPoll).Return(errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "poll created",
+			req:  req,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.messageRepo.On("CreateBatch", isPoll).Run(func(args mock.Arguments) {
+					args.Get(0).([]*repository.Message)[0].ID = messageID
+				}).Return(nil)
+				m.hub.On("PublishSaved", mock.MatchedBy(func(records []repository.Message) bool {
+					return len(records) == 1 && records[0].ID == messageID
+				})).Return()
+				m.messageRepo.On("GetByID", messageID).Return(&created, nil)
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			got, err := mocks.chatSrv.CreatePoll(userID, chatID, tt.req)
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+				assert.Nil(t, got)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, repository.MessageKindPoll, got.Kind)
+				assert.Len(t, got.Poll.Options, 2)
+				assert.False(t, got.Poll.Closed)
+			}
+			mocks.chatRepo.AssertExpectations(t)
+			mocks.messageRepo.AssertExpectations(t)
+			mocks.hub.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_Vote(t *testing.T) {
+	const messageID = uint(10)
+	req := chat.VoteRequest{OptionIDs: []uint{1}}
+	poll := &repository.PollDTO{TotalVoters: 1, Options: []repository.PollOptionDTO{{ID: 1, Text: "Here", Votes: 1, Voted: true}}}
+
+	tests := []struct {
+		name       string
+		setup      func(m *chatServiceMocks)
+		want       *repository.PollDTO
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name: "user is not a member",
+			setup: func(m *chatServiceMocks) {
+				m.hub.On("Vote", userID, chatID, messageID, req.OptionIDs).Return(nil, ws.ErrNotChatMember)
+			},
+			wantErr:    true,
+			errMessage: "chat not found",
+		},
+		{
+			name: "message is not a poll",
+			setup: func(m *chatServiceMocks) {
+				m.hub.On("Vote", userID, chatID, messageID, req.OptionIDs).Return(nil, ws.ErrNotAPoll)
+			},
+			wantErr:    true,
+			errMessage: "message is not a poll",
+		},
+		{
+			name: "poll is closed",
+			setup: func(m *chatServiceMocks) {
+				m.hub.On("Vote", userID, chatID, messageID, req.OptionIDs).Return(nil, repository.ErrPollClosed)
+			},
+			wantErr:    true,
+			errMessage: "poll is closed",
+		},
+		{
+			name: "option from another poll",
+			setup: func(m *chatServiceMocks) {
+				m.hub.On("Vote", userID, chatID, messageID, req.OptionIDs).Return(nil, repository.ErrInvalidPollOption)
+			},
+			wantErr:    true,
+			errMessage: "option does not belong to the poll",
+		},
+		{
+			name: "failed to vote",
+			setup: func(m *chatServiceMocks) {
+				m.hub.On("Vote", userID, chatID, messageID, req.OptionIDs).Return(nil, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "vote accepted",
+			setup: func(m *chatServiceMocks) {
+				m.hub.On("Vote", userID, chatID, messageID, req.OptionIDs).Return(poll, nil)
+			},
+			want: poll,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			got, err := mocks.chatSrv.Vote(userID, chatID, messageID, req)
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+			mocks.hub.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_GetMentions(t *testing.T) {
+	messages := []repository.Message{
+		{ID: 30, ChatID: 2, SenderID: 2, Content: "@all standup", MentionsAll: true},
+		{ID: 20, ChatID: 1, SenderID: 3, Content: "ping", Mentions: repository.MentionList{userID}},
+		{ID: 10, ChatID: 1, SenderID: 3, Content: "older"},
+	}
+
+	tests := []struct {
+		name       string
+		beforeID   uint
+		setup      func(m *chatServiceMocks)
+		wantIDs    []uint
+		wantNext   *uint
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name:     "failed to fetch mentions",
+			beforeID: 0,
+			setup: func(m *chatServiceMocks) {
+				m.messageRepo.On("ListMentions", userID, uint(0), 3).Return(nil, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name:     "first page has more mentions",
+			beforeID: 0,
+			setup: func(m *chatServiceMocks) {
+				m.messageRepo.On("ListMentions", userID, uint(0), 3).Return(messages, nil)
+			},
+			wantIDs:  []uint{30, 20},
+			wantNext: &messages[1].ID,
+		},
+		{
+			name:     "last page",
+			beforeID: 20,
+			setup: func(m *chatServiceMocks) {
+				m.messageRepo.On("ListMentions", userID, uint(20), 3).Return(messages[2:], nil)
+			},
+			wantIDs: []uint{10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			got, err := mocks.chatSrv.GetMentions(userID, tt.beforeID, 2)
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+				assert.Nil(t, got)
+			} else {
+				assert.Nil(t, err)
+				var ids []uint
+				for _, mention := range got.Mentions {
+					ids = append(ids, mention.Message.ID)
+				}
+				assert.Equal(t, tt.wantIDs, ids)
+				assert.Equal(t, tt.wantNext, got.NextBeforeID)
+			}
+			mocks.messageRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_ForwardMessage(t *testing.T) {
+	const messageID = uint(10)
+	deletedAt := time.Now()
+	message := repository.Message{ID: messageID, ChatID: chatID, SenderID: 2, Content: "hello", Kind: repository.MessageKindText}
+	deleted := repository.Message{ID: messageID, ChatID: chatID, Kind: repository.MessageKindText, DeletedAt: &deletedAt}
+	poll := repository.Message{ID: messageID, ChatID: chatID, Kind: repository.MessageKindPoll}
+	req := chat.ForwardRequest{ChatIDs: []uint{3, 2, 3}}
+	forwarded := []repository.Message{
+		{ID: 20, ChatID: 2, SenderID: userID, Content: "hello", ForwardedFrom: message.Origin()},
+		{ID: 21, ChatID: 3, SenderID: userID, Content: "hello", ForwardedFrom: message.Origin()},
+	}
+
+	tests := []struct {
+		name       string
+		setup      func(m *chatServiceMocks)
+		want       []chat.ForwardedMessageDTO
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name: "user is not a member of the source chat",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(false, nil)
+			},
+			wantErr:    true,
+			errMessage: "chat not found",
+		},
+		{
+			name: "message not found",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.messageRepo.On("GetByID", messageID).Return(nil, gorm.ErrRecordNotFound)
+			},
+			wantErr:    true,
+			errMessage: "message not found",
+		},
+		{
+			name: "message is deleted",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.messageRepo.On("GetByID", messageID).Return(&deleted, nil)
+			},
+			wantErr:    true,
+			errMessage: "message is deleted",
+		},
+		{
+			name: "polls cannot be forwarded",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.messageRepo.On("GetByID", messageID).Return(&poll, nil)
+			},
+			wantErr:    true,
+			errMessage: "only text messages can be forwarded",
+		},
+		{
+			name: "user is not a member of a target chat",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.messageRepo.On("GetByID", messageID).Return(&message, nil)
+				m.chatRepo.On("IsMember", uint(2), userID).Return(true, nil)
+				m.chatRepo.On("IsMember", uint(3), userID).Return(false, nil)
+			},
+			wantErr:    true,
+			errMessage: "target chat not found",
+		},
+		{
+			name: "failed to forward message",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", mock.Anything, userID).Return(true, nil)
+				m.messageRepo.On("GetByID", messageID).Return(&message, nil)
+				m.messageRepo.On("Forward", &message, userID, []uint{2, 3}).Return(nil, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "message forwarded once to each chat",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", mock.Anything, userID).Return(true, nil)
+				m.messageRepo.On("GetByID", messageID).Return(&message, nil)
+				m.messageRepo.On("Forward", &message, userID, []uint{2, 3}).Return(forwarded, nil)
+				m.hub.On("PublishSaved", forwarded).Return()
+			},
+			want: []chat.ForwardedMessageDTO{{ChatID: 2, MessageID: 20}, {ChatID: 3, MessageID: 21}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			got, err := mocks.chatSrv.ForwardMessage(userID, chatID, messageID, req)
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+			mocks.chatRepo.AssertExpectations(t)
+			mocks.messageRepo.AssertExpectations(t)
+			mocks.hub.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_UploadAttachment(t *testing.T) {
+	pngHeader := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 24)
+
+	tests := []struct {
+		name            string
+		size            int64
+		content         string
+		setup           func(m *chatServiceMocks)
+		wantContentType string
+		wantErr         bool
+		errMessage      string
+	}{
+		{
+			name:    "user is not a member",
+			size:    5,
+			content: "hello",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(false, nil)
+			},
+			wantErr:    true,
+			errMessage: "chat not found",
+		},
+		{
+			name:    "file is too large",
+			size:    maxUploadSize + 1,
+			content: "hello",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+			},
+			wantErr:    true,
+			errMessage: "file is larger than 1024 bytes",
+		},
+		{
+			name:    "error storing blob",
+			size:    5,
+			content: "hello",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.blobs.On("Put", mock.Anything, mock.Anything, mock.Anything, int64(5), "text/plain; charset=utf-8").Return(errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name:    "error saving attachment removes blob",
+			size:    5,
+			content: "hello",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.blobs.On("Put", mock.Anything, mock.Anything, mock.Anything, int64(5), "text/plain; charset=utf-8").Return(nil)
+				m.attachmentRepo.On("Create", mock.Anything).Return(errExample)
+				m.blobs.On("Delete", mock.Anything, mock.Anything).Return(nil)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name:    "text file uploaded without thumbnail",
+			size:    5,
+			content: "hello",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.blobs.On("Put", mock.Anything, mock.Anything, mock.Anything, int64(5), "text/plain; charset=utf-8").Return(nil)
+				m.attachmentRepo.On("Create", mock.Anything).Return(nil)
+			},
+			wantContentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:    "image type is sniffed and thumbnail queued",
+			size:    int64(len(pngHeader)),
+			content: pngHeader,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.blobs.On("Put", mock.Anything, mock.Anything, mock.Anything, int64(len(pngHeader)), "image/png").Return(nil)
+				m.attachmentRepo.On("Create", mock.Anything).Return(nil)
+				m.thumbnailer.On("Enqueue", mock.MatchedBy(func(a repository.Attachment) bool {
+					return a.ContentType == "image/png" && a.FileName == "photo.txt"
+				})).Return()
+			},
+			wantContentType: "image/png",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			attachment, err := mocks.chatSrv.UploadAttachment(userID, chatID, "../../photo.txt", tt.size, strings.NewReader(tt.content))
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+				assert.Nil(t, attachment)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.wantContentType, attachment.ContentType)
+				assert.Equal(t, "photo.txt", attachment.FileName)
+			}
+			mocks.chatRepo.AssertExpectations(t)
+			mocks.blobs.AssertExpectations(t)
+			mocks.attachmentRepo.AssertExpectations(t)
+			mocks.thumbnailer.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_OpenAttachment(t *testing.T) {
+	const (
+		attachmentID = uint(5)
+		messageID    = uint(10)
+		otherUserID  = uint(2)
+	)
+	thumbnailKey := "chats/1/key.thumb.jpg"
+	deletedAt := time.Now()
+	attached := func(thumbnail *string) *repository.Attachment {
+		id := messageID
+		return &repository.Attachment{ID: attachmentID, ChatID: chatID, UploaderID: otherUserID, MessageID: &id,
+			FileName: "photo.png", ContentType: "image/png", Size: 5, StorageKey: "chats/1/key", ThumbnailKey: thumbnail}
+	}
+
+	tests := []struct {
+		name            string
+		thumbnail       bool
+		setup           func(m *chatServiceMocks)
+		wantContentType string
+		wantErr         bool
+		errMessage      string
+	}{
+		{
+			name: "attachment in another chat",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.attachmentRepo.On("GetByID", attachmentID).Return(&repository.Attachment{ID: attachmentID, ChatID: chatID + 1, UploaderID: userID}, nil)
+			},
+			wantErr:    true,
+			errMessage: "attachment not found",
+		},
+		{
+			name: "unattached upload of another user",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.attachmentRepo.On("GetByID", attachmentID).Return(&repository.Attachment{ID: attachmentID, ChatID: chatID, UploaderID: otherUserID}, nil)
+			},
+			wantErr:    true,
+			errMessage: "attachment not found",
+		},
+		{
+			name: "message is deleted",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.attachmentRepo.On("GetByID", attachmentID).Return(attached(nil), nil)
+				m.messageRepo.On("GetByID", messageID).Return(&repository.Message{ID: messageID, ChatID: chatID, DeletedAt: &deletedAt}, nil)
+			},
+			wantErr:    true,
+			errMessage: "attachment not found",
+		},
+		{
+			name:      "thumbnail is not ready",
+			thumbnail: true,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.attachmentRepo.On("GetByID", attachmentID).Return(attached(nil), nil)
+				m.messageRepo.On("GetByID", messageID).Return(&repository.Message{ID: messageID, ChatID: chatID}, nil)
+			},
+			wantErr:    true,
+			errMessage: "thumbnail not found",
+		},
+		{
+			name: "original opened",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.attachmentRepo.On("GetByID", attachmentID).Return(attached(nil), nil)
+				m.messageRepo.On("GetByID", messageID).Return(&repository.Message{ID: messageID, ChatID: chatID}, nil)
+				m.blobs.On("Get", mock.Anything, "chats/1/key").Return(io.NopCloser(strings.NewReader("hello")), nil)
+			},
+			wantContentType: "image/png",
+		},
+		{
+			name:      "thumbnail opened",
+			thumbnail: true,
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("IsMember", chatID, userID).Return(true, nil)
+				m.attachmentRepo.On("GetByID", attachmentID).Return(attached(&thumbnailKey), nil)
+				m.messageRepo.On("GetByID", messageID).Return(&repository.Message{ID: messageID, ChatID: chatID}, nil)
+				m.blobs.On("Get", mock.Anything, thumbnailKey).Return(io.NopCloser(strings.NewReader("thumb")), nil)
+			},
+			wantContentType: "image/jpeg",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			file, err := mocks.chatSrv.OpenAttachment(userID, chatID, attachmentID, tt.thumbnail)
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+				assert.Nil(t, file)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.wantContentType, file.ContentType)
+				assert.Equal(t, "photo.png", file.FileName)
+				file.Body.Close()
+			}
+			mocks.attachmentRepo.AssertExpectations(t)
+			mocks.messageRepo.AssertExpectations(t)
+			mocks.blobs.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_Search(t *testing.T) {
+	from := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	to := from.Add(-time.Hour)
+	cursor := uint(100)
+	nextCursor := uint(98)
+
+	tests := []struct {
+		name       string
+		req        chat.SearchRequest
+		setup      func(m *chatServiceMocks)
+		wantIDs    []uint
+		wantNext   *uint
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name:       "empty query",
+			req:        chat.SearchRequest{Query: "  ", Limit: 2},
+			setup:      func(m *chatServiceMocks) {},
+			wantErr:    true,
+			errMessage: "q is required",
+		},
+		{
+			name:       "query is too long",
+			req:        chat.SearchRequest{Query: strings.Repeat("я", 257), Limit: 2},
+			setup:      func(m *chatServiceMocks) {},
+			wantErr:    true,
+			errMessage: "q is too long",
+		},
+		{
+			name:       "invalid date range",
+			req:        chat.SearchRequest{Query: "hello", From: &from, To: &to, Limit: 2},
+			setup:      func(m *chatServiceMocks) {},
+			wantErr:    true,
+			errMessage: "from must be before to",
+		},
+		{
+			name: "search error",
+			req:  chat.SearchRequest{Query: "hello", Limit: 2},
+			setup: func(m *chatServiceMocks) {
+				m.searcher.On("Search", mock.Anything, mock.Anything).Return(nil, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "filters are passed and next page is detected",
+			req:  chat.SearchRequest{Query: " hello ", ChatID: &chatID, SenderID: &userID, Cursor: &cursor, Limit: 2},
+			setup: func(m *chatServiceMocks) {
+				m.searcher.On("Search", mock.Anything, search.Query{
+					Text: "hello", UserID: userID, ChatID: &chatID, SenderID: &userID, BeforeID: &cursor, Limit: 3,
+				}).Return([]search.Hit{{MessageID: 99}, {MessageID: 98}, {MessageID: 97}}, nil)
+			},
+			wantIDs:  []uint{99, 98},
+			wantNext: &nextCursor,
+		},
+		{
+			name: "last page",
+			req:  chat.SearchRequest{Query: "hello", Limit: 2},
+			setup: func(m *chatServiceMocks) {
+				m.searcher.On("Search", mock.Anything, mock.Anything).Return([]search.Hit{{MessageID: 5}}, nil)
+			},
+			wantIDs: []uint{5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			response, err := mocks.chatSrv.Search(userID, tt.req)
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+				assert.Nil(t, response)
+			} else {
+				assert.Nil(t, err)
+				var ids []uint
+				for _, result := range response.Results {
+					ids = append(ids, result.MessageID)
+				}
+				assert.Equal(t, tt.wantIDs, ids)
+				assert.Equal(t, tt.wantNext, response.NextCursor)
+			}
+			mocks.searcher.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_IssueTicket(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		setup      func(m *chatServiceMocks)
+		want       *chat.TicketResponse
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name: "error issuing ticket",
+			setup: func(m *chatServiceMocks) {
+				m.wsAuth.On("IssueTicket", userID, expiresAt).Return(nil, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "successful ticket issue",
+			setup: func(m *chatServiceMocks) {
+				m.wsAuth.On("IssueTicket", userID, expiresAt).Return(&shared.WSTicket{Ticket: "abc", UserID: userID, ExpiresAt: expiresAt}, nil)
+			},
+			want:    &chat.TicketResponse{Ticket: "abc"},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			got, err := mocks.chatSrv.IssueTicket(userID, expiresAt)
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			mocks.wsAuth.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChatService_HandleWebSocket(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(m *chatServiceMocks)
+		target     string
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name: "error getting chat IDs",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetChatIDsByUserID", userID).Return(nil, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "invalid resume cursor",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetChatIDsByUserID", userID).Return([]uint{chatID}, nil)
+			},
+			target:     "/?last_seen=1-10",
+			wantErr:    true,
+			errMessage: "invalid last_seen cursor: 1-10",
+		},
+		{
+			name: "invalid global resume cursor",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetChatIDsByUserID", userID).Return([]uint{chatID}, nil)
+			},
+			target:     "/?last_seen_message_id=abc",
+			wantErr:    true,
+			errMessage: "invalid last_seen_message_id: abc",
+		},
+		{
+			name: "error upgrading to WebSocket",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetChatIDsByUserID", userID).Return([]uint{chatID}, nil)
+				m.wsUpgrader.On("Upgrade", mock.Anything, mock.Anything, mock.Anything).Return(nil, errExample)
+			},
+			wantErr:    true,
+			errMessage: "WebSocket upgrade failed",
+		},
+		{
+			name: "successful websocket connection",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetChatIDsByUserID", userID).Return([]uint{chatID}, nil)
+				m.wsUpgrader.On("Upgrade", mock.Anything, mock.Anything, mock.Anything).Return(&websocket.Conn{}, nil)
+				m.hub.On("RegisterClient", mock.Anything).Return()
+			},
+			wantErr: false,
+		},
+		{
+			name: "successful websocket connection with resume cursors",
+			setup: func(m *chatServiceMocks) {
+				m.chatRepo.On("GetChatIDsByUserID", userID).Return([]uint{chatID}, nil)
+				m.wsUpgrader.On("Upgrade", mock.Anything, mock.Anything, mock.Anything).Return(&websocket.Conn{}, nil)
+				m.hub.On("RegisterClient", mock.Anything).Return()
+			},
+			target:  "/?last_seen_message_id=10&last_seen=1:15,99:3",
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := setupChatService()
+			tt.setup(&mocks)
+
+			// Используем httptest.ResponseRecorder и httptest.NewRequest для моков
+			if tt.target == "" {
+				tt.target = "/"
+			}
+
 			recorder := httptest.NewRecorder()
-			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request := httptest.NewRequest(http.MethodGet, tt.target, nil)
 
-			err := mocks.chatSrv.HandleWebSocket(userID, recorder, request)
+			err := mocks.chatSrv.HandleWebSocket(userID, time.Now().Add(time.Hour), recorder, request)
 
 			if tt.wantErr {
 				assert.NotNil(t, err)
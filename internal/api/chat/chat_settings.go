@@ -0,0 +1,71 @@
+package chat
+
+import (
+	"errors"
+	"net/http"
+	"socialAPI/internal/shared"
+	r "socialAPI/internal/storage/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UpdateSettings меняет личные настройки чата: заглушение, архив и закрепление в списке.
+// Настройки хранятся в записи участника и не затрагивают остальных
+func (c chatService) UpdateSettings(userID, chatID uint, req SettingsRequest) (*r.ChatSettingsDTO, *shared.HttpError) {
+	c.logger.Infow("Attempting to update chat settings", "userID", userID, "chatID", chatID)
+
+	now := time.Now()
+	changes := make(map[string]interface{})
+
+	if req.MutedUntil != nil {
+		if req.Muted != nil && !*req.Muted {
+			c.logger.Warnw("Mute expiry given for unmuted chat", "userID", userID, "chatID", chatID)
+			return nil, shared.NewHttpError("muted_until requires muted to be true", http.StatusBadRequest)
+		}
+		if !req.MutedUntil.After(now) {
+			c.logger.Warnw("Mute expiry is in the past", "userID", userID, "chatID", chatID, "mutedUntil", req.MutedUntil)
+			return nil, shared.NewHttpError("muted_until must be in the future", http.StatusBadRequest)
+		}
+		changes["muted"] = true
+		changes["muted_until"] = *req.MutedUntil
+	} else if req.Muted != nil {
+		changes["muted"] = *req.Muted
+		changes["muted_until"] = nil
+	}
+
+	if req.Archived != nil {
+		changes["archived_at"] = timestampIf(*req.Archived, now)
+	}
+	if req.Pinned != nil {
+		changes["pinned_at"] = timestampIf(*req.Pinned, now)
+	}
+
+	if len(changes) == 0 {
+		c.logger.Warnw("No chat settings to update", "userID", userID, "chatID", chatID)
+		return nil, shared.NewHttpError("no settings to update", http.StatusBadRequest)
+	}
+
+	member, err := c.chatRepo.UpdateSettings(chatID, userID, changes)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.logger.Warnw("User is not a member of the chat", "userID", userID, "chatID", chatID)
+			return nil, shared.NewHttpError("chat not found", http.StatusNotFound)
+		}
+		c.logger.Errorw("Failed to update chat settings", "userID", userID, "chatID", chatID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	c.logger.Infow("Chat settings updated successfully", "userID", userID, "chatID", chatID)
+
+	settings := member.ConvertToSettingsDTO(now)
+	return &settings, nil
+}
+
+// timestampIf возвращает now для включённого флага и nil для выключенного
+func timestampIf(enabled bool, now time.Time) interface{} {
+	if enabled {
+		return now
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package chat
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// parseResumeCursors собирает курсоры переподключения из query-параметров:
+// last_seen_message_id=N задаёт общий курсор для всех чатов пользователя,
+// last_seen=chatID:messageID,... переопределяет его для отдельных чатов.
+// Чаты, в которых пользователь не состоит, игнорируются
+func parseResumeCursors(query url.Values, chatIDs []uint) (map[uint]uint, error) {
+	cursors := make(map[uint]uint)
+
+	member := make(map[uint]bool, len(chatIDs))
+	for _, id := range chatIDs {
+		member[id] = true
+	}
+
+	if global := query.Get("last_seen_message_id"); global != "" {
+		messageID, err := strconv.ParseUint(global, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid last_seen_message_id: %s", global)
+		}
+
+		for _, id := range chatIDs {
+			cursors[id] = uint(messageID)
+		}
+	}
+
+	if perChat := query.Get("last_seen"); perChat != "" {
+		for _, pair := range strings.Split(perChat, ",") {
+			chatPart, messagePart, ok := strings.Cut(pair, ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid last_seen cursor: %s", pair)
+			}
+
+			chatID, err := strconv.ParseUint(chatPart, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid last_seen cursor: %s", pair)
+			}
+
+			messageID, err := strconv.ParseUint(messagePart, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid last_seen cursor: %s", pair)
+			}
+
+			if member[uint(chatID)] {
+				cursors[uint(chatID)] = uint(messageID)
+			}
+		}
+	}
+
+	return cursors, nil
+}
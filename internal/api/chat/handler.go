@@ -1,10 +1,15 @@
 package chat
 
 import (
+	"errors"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"socialAPI/internal/api/middleware"
 	"socialAPI/internal/lib"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -20,10 +25,11 @@ func (c ChatController) GetOneHandler() http.HandlerFunc {
 			return
 		}
 		chatID := uint(chatIDUint64)
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
 
 		c.logger.Infow("Handling GetOne request", "chatID", chatID)
 
-		chat, hErr := c.chatService.GetOne(chatID)
+		chat, hErr := c.chatService.GetOne(userID, chatID)
 		if hErr != nil {
 			c.logger.Errorw("Failed to get chat", "chatID", chatID, "error", err)
 			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
@@ -39,11 +45,14 @@ func (c ChatController) GetOneHandler() http.HandlerFunc {
 
 func (c ChatController) GetAllHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		c.logger.Infow("Handling GetAll request")
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+		archived := r.URL.Query().Get("archived") == "true"
 
-		chats, err := c.chatService.GetAll()
+		c.logger.Infow("Handling GetAll request", "userID", userID, "archived", archived)
+
+		chats, err := c.chatService.GetAll(userID, archived)
 		if err != nil {
-			c.logger.Errorw("Failed to get chats", "error", err)
+			c.logger.Errorw("Failed to get chats", "userID", userID, "error", err)
 			lib.SendMessage(w, r, err.StatusCode, err.Error())
 			return
 		}
@@ -58,9 +67,10 @@ func (c ChatController) GetAllHandler() http.HandlerFunc {
 func (c ChatController) CreateHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		req := r.Context().Value(middleware.DataKey).(CreateRequest)
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
 
-		c.logger.Infow("Create chat", "userIDs", req.UserIDs, "name", req.Name)
-		err := c.chatService.Create(req)
+		c.logger.Infow("Create chat", "creatorID", userID, "userIDs", req.UserIDs, "name", req.Name)
+		err := c.chatService.Create(userID, req)
 		if err != nil {
 			c.logger.Errorw("Error while creating chat", "userIDs", req.UserIDs, "name", req.Name, "error", err)
 			lib.SendMessage(w, r, err.StatusCode, err.Error())
@@ -72,28 +82,1073 @@ func (c ChatController) CreateHandler() http.HandlerFunc {
 	}
 }
 
+func (c ChatController) DirectHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		peerIDParam := chi.URLParam(r, "userID")
+		peerIDUint64, err := strconv.ParseUint(peerIDParam, 10, 32)
+		if err != nil {
+			c.logger.Warnw("Invalid user ID parameter", "userID", peerIDParam, "error", err.Error())
+			lib.SendMessage(w, r, http.StatusBadRequest, "Invalid userID parameter")
+			return
+		}
+		peerID := uint(peerIDUint64)
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+
+		c.logger.Infow("Handling Direct request", "userID", userID, "peerID", peerID)
+
+		direct, hErr := c.chatService.GetOrCreateDirect(userID, peerID)
+		if hErr != nil {
+			c.logger.Errorw("Failed to open direct chat", "userID", userID, "peerID", peerID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		if direct.Created {
+			render.Status(r, http.StatusCreated)
+		} else {
+			render.Status(r, http.StatusOK)
+		}
+		render.JSON(w, r, direct)
+	}
+}
+
 func (c ChatController) UpdateHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		req := r.Context().Value(middleware.DataKey).(CreateRequest)
-		chatID := r.Context().Value(middleware.UserIDKey).(uint)
+		chatID, ok := c.parseChatPath(w, r)
+		if !ok {
+			return
+		}
+
+		req := r.Context().Value(middleware.DataKey).(UpdateRequest)
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
 
-		c.logger.Infow("Update chat", "chatID", chatID, "userIDs", req.UserIDs, "name", req.Name)
-		err := c.chatService.Update(chatID, req)
+		c.logger.Infow("Update chat", "actorID", userID, "chatID", chatID, "name", req.Name)
+		err := c.chatService.Update(userID, chatID, req)
 		if err != nil {
-			c.logger.Errorw("Error while updating chat", "chatID", chatID, "userIDs", req.UserIDs, "name", req.Name, "error", err)
+			c.logger.Errorw("Error while updating chat", "chatID", chatID, "name", req.Name, "error", err)
 			lib.SendMessage(w, r, err.StatusCode, err.Error())
 			return
 		}
 
-		c.logger.Infow("response chat changed successfully sent", "chatID", chatID, "userIDs", req.UserIDs, "name", req.Name)
+		c.logger.Infow("response chat changed successfully sent", "chatID", chatID, "name", req.Name)
 		lib.SendMessage(w, r, http.StatusOK, "Chat changed successfully")
 	}
 }
 
+func (c ChatController) AddMembersHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, ok := c.parseChatPath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+		req := r.Context().Value(middleware.DataKey).(AddMembersRequest)
+
+		c.logger.Infow("Handling AddMembers request", "actorID", userID, "chatID", chatID, "userIDs", req.UserIDs)
+
+		hErr := c.chatService.AddMembers(userID, chatID, req)
+		if hErr != nil {
+			c.logger.Errorw("Failed to add chat members", "actorID", userID, "chatID", chatID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		lib.SendMessage(w, r, http.StatusOK, "Members added")
+	}
+}
+
+func (c ChatController) RemoveMemberHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, memberID, ok := c.parseMemberPath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+
+		c.logger.Infow("Handling RemoveMember request", "actorID", userID, "chatID", chatID, "userID", memberID)
+
+		hErr := c.chatService.RemoveMember(userID, chatID, memberID)
+		if hErr != nil {
+			c.logger.Errorw("Failed to remove chat member", "actorID", userID, "chatID", chatID, "userID", memberID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		lib.SendMessage(w, r, http.StatusOK, "Member removed")
+	}
+}
+
+func (c ChatController) LeaveHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, ok := c.parseChatPath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+
+		c.logger.Infow("Handling Leave request", "userID", userID, "chatID", chatID)
+
+		hErr := c.chatService.Leave(userID, chatID)
+		if hErr != nil {
+			c.logger.Errorw("Failed to leave chat", "userID", userID, "chatID", chatID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		lib.SendMessage(w, r, http.StatusOK, "Chat left")
+	}
+}
+
+func (c ChatController) MarkReadHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatIDParam := chi.URLParam(r, "id")
+		chatIDUint64, err := strconv.ParseUint(chatIDParam, 10, 32)
+		if err != nil {
+			c.logger.Warnw("Invalid chat ID parameter", "chatID", chatIDParam, "error", err.Error())
+			lib.SendMessage(w, r, http.StatusBadRequest, "Invalid id parameter")
+			return
+		}
+		chatID := uint(chatIDUint64)
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+		req := r.Context().Value(middleware.DataKey).(ReadRequest)
+
+		c.logger.Infow("Handling MarkRead request", "userID", userID, "chatID", chatID, "messageID", req.MessageID)
+
+		hErr := c.chatService.MarkRead(userID, chatID, req)
+		if hErr != nil {
+			c.logger.Errorw("Failed to mark chat as read", "userID", userID, "chatID", chatID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		lib.SendMessage(w, r, http.StatusOK, "Chat marked as read")
+	}
+}
+
+func (c ChatController) UpdateSettingsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, ok := c.parseChatPath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+		req := r.Context().Value(middleware.DataKey).(SettingsRequest)
+
+		c.logger.Infow("Handling UpdateSettings request", "userID", userID, "chatID", chatID)
+
+		settings, hErr := c.chatService.UpdateSettings(userID, chatID, req)
+		if hErr != nil {
+			c.logger.Errorw("Failed to update chat settings", "userID", userID, "chatID", chatID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, settings)
+	}
+}
+
+// parseMessagePath читает {id} и {msgID} из пути. При ошибке ответ уже отправлен
+func (c ChatController) parseMessagePath(w http.ResponseWriter, r *http.Request) (uint, uint, bool) {
+	chatIDUint64, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		c.logger.Warnw("Invalid chat ID parameter", "chatID", chi.URLParam(r, "id"), "error", err.Error())
+		lib.SendMessage(w, r, http.StatusBadRequest, "Invalid id parameter")
+		return 0, 0, false
+	}
+
+	messageIDUint64, err := strconv.ParseUint(chi.URLParam(r, "msgID"), 10, 32)
+	if err != nil {
+		c.logger.Warnw("Invalid message ID parameter", "messageID", chi.URLParam(r, "msgID"), "error", err.Error())
+		lib.SendMessage(w, r, http.StatusBadRequest, "Invalid msgID parameter")
+		return 0, 0, false
+	}
+
+	return uint(chatIDUint64), uint(messageIDUint64), true
+}
+
+// parseChatPath читает {id} из пути. При ошибке ответ уже отправлен
+func (c ChatController) parseChatPath(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	chatIDUint64, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		c.logger.Warnw("Invalid chat ID parameter", "chatID", chi.URLParam(r, "id"), "error", err.Error())
+		lib.SendMessage(w, r, http.StatusBadRequest, "Invalid id parameter")
+		return 0, false
+	}
+
+	return uint(chatIDUint64), true
+}
+
+// parseMemberPath читает {id} и {userID} из пути. При ошибке ответ уже отправлен
+func (c ChatController) parseMemberPath(w http.ResponseWriter, r *http.Request) (uint, uint, bool) {
+	chatIDUint64, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		c.logger.Warnw("Invalid chat ID parameter", "chatID", chi.URLParam(r, "id"), "error", err.Error())
+		lib.SendMessage(w, r, http.StatusBadRequest, "Invalid id parameter")
+		return 0, 0, false
+	}
+
+	userIDUint64, err := strconv.ParseUint(chi.URLParam(r, "userID"), 10, 32)
+	if err != nil {
+		c.logger.Warnw("Invalid user ID parameter", "userID", chi.URLParam(r, "userID"), "error", err.Error())
+		lib.SendMessage(w, r, http.StatusBadRequest, "Invalid userID parameter")
+		return 0, 0, false
+	}
+
+	return uint(chatIDUint64), uint(userIDUint64), true
+}
+
+func (c ChatController) SetRoleHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, memberID, ok := c.parseMemberPath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+		req := r.Context().Value(middleware.DataKey).(RoleRequest)
+
+		c.logger.Infow("Handling SetRole request", "actorID", userID, "chatID", chatID, "userID", memberID, "role", req.Role)
+
+		hErr := c.chatService.SetRole(userID, chatID, memberID, req)
+		if hErr != nil {
+			c.logger.Errorw("Failed to change member role", "actorID", userID, "chatID", chatID, "userID", memberID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		lib.SendMessage(w, r, http.StatusOK, "Member role changed")
+	}
+}
+
+func (c ChatController) SetRetentionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, ok := c.parseChatPath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+		req := r.Context().Value(middleware.DataKey).(RetentionRequest)
+
+		c.logger.Infow("Handling SetRetention request", "actorID", userID, "chatID", chatID)
+
+		hErr := c.chatService.SetRetention(userID, chatID, req)
+		if hErr != nil {
+			c.logger.Errorw("Failed to change chat retention", "actorID", userID, "chatID", chatID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		lib.SendMessage(w, r, http.StatusOK, "Chat retention updated")
+	}
+}
+
+func (c ChatController) CreateInviteHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, ok := c.parseChatPath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+		req := r.Context().Value(middleware.DataKey).(CreateInviteRequest)
+
+		c.logger.Infow("Handling CreateInvite request", "actorID", userID, "chatID", chatID)
+
+		invite, hErr := c.chatService.CreateInvite(userID, chatID, req)
+		if hErr != nil {
+			c.logger.Errorw("Failed to create chat invite", "actorID", userID, "chatID", chatID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, invite)
+	}
+}
+
+func (c ChatController) GetInvitesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, ok := c.parseChatPath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+
+		c.logger.Infow("Handling GetInvites request", "actorID", userID, "chatID", chatID)
+
+		invites, hErr := c.chatService.GetInvites(userID, chatID)
+		if hErr != nil {
+			c.logger.Errorw("Failed to fetch chat invites", "actorID", userID, "chatID", chatID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, invites)
+	}
+}
+
+func (c ChatController) RevokeInviteHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, ok := c.parseChatPath(w, r)
+		if !ok {
+			return
+		}
+
+		inviteIDParam := chi.URLParam(r, "inviteID")
+		inviteIDUint64, err := strconv.ParseUint(inviteIDParam, 10, 32)
+		if err != nil {
+			c.logger.Warnw("Invalid invite ID parameter", "inviteID", inviteIDParam, "error", err.Error())
+			lib.SendMessage(w, r, http.StatusBadRequest, "Invalid inviteID parameter")
+			return
+		}
+		inviteID := uint(inviteIDUint64)
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+
+		c.logger.Infow("Handling RevokeInvite request", "actorID", userID, "chatID", chatID, "inviteID", inviteID)
+
+		hErr := c.chatService.RevokeInvite(userID, chatID, inviteID)
+		if hErr != nil {
+			c.logger.Errorw("Failed to revoke chat invite", "actorID", userID, "chatID", chatID, "inviteID", inviteID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		lib.SendMessage(w, r, http.StatusOK, "Invite revoked")
+	}
+}
+
+func (c ChatController) JoinHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		code := chi.URLParam(r, "code")
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+
+		// Код не пишется в лог: по нему можно войти в чат
+		c.logger.Infow("Handling Join request", "userID", userID)
+
+		joined, hErr := c.chatService.JoinByInvite(userID, code)
+		if hErr != nil {
+			c.logger.Errorw("Failed to join chat by invite", "userID", userID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, joined)
+	}
+}
+
+func (c ChatController) EditMessageHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, messageID, ok := c.parseMessagePath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+		req := r.Context().Value(middleware.DataKey).(EditMessageRequest)
+
+		c.logger.Infow("Handling EditMessage request", "userID", userID, "chatID", chatID, "messageID", messageID)
+
+		message, hErr := c.chatService.EditMessage(userID, chatID, messageID, req)
+		if hErr != nil {
+			c.logger.Errorw("Failed to edit message", "userID", userID, "chatID", chatID, "messageID", messageID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, message)
+	}
+}
+
+func (c ChatController) DeleteMessageHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, messageID, ok := c.parseMessagePath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+
+		c.logger.Infow("Handling DeleteMessage request", "userID", userID, "chatID", chatID, "messageID", messageID)
+
+		hErr := c.chatService.DeleteMessage(userID, chatID, messageID)
+		if hErr != nil {
+			c.logger.Errorw("Failed to delete message", "userID", userID, "chatID", chatID, "messageID", messageID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		lib.SendMessage(w, r, http.StatusOK, "Message successfully deleted")
+	}
+}
+
+// parseScheduledPath читает {id} и {schedID} из пути. При ошибке ответ уже отправлен
+func (c ChatController) parseScheduledPath(w http.ResponseWriter, r *http.Request) (uint, uint, bool) {
+	chatID, ok := c.parseChatPath(w, r)
+	if !ok {
+		return 0, 0, false
+	}
+
+	scheduledIDUint64, err := strconv.ParseUint(chi.URLParam(r, "schedID"), 10, 32)
+	if err != nil {
+		c.logger.Warnw("Invalid scheduled message ID parameter", "scheduledID", chi.URLParam(r, "schedID"), "error", err.Error())
+		lib.SendMessage(w, r, http.StatusBadRequest, "Invalid schedID parameter")
+		return 0, 0, false
+	}
+
+	return chatID, uint(scheduledIDUint64), true
+}
+
+func (c ChatController) ScheduleMessageHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, ok := c.parseChatPath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+		req := r.Context().Value(middleware.DataKey).(ScheduleMessageRequest)
+
+		c.logger.Infow("Handling ScheduleMessage request", "userID", userID, "chatID", chatID, "sendAt", req.SendAt)
+
+		scheduled, hErr := c.chatService.ScheduleMessage(userID, chatID, req)
+		if hErr != nil {
+			c.logger.Errorw("Failed to schedule message", "userID", userID, "chatID", chatID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, scheduled)
+	}
+}
+
+func (c ChatController) GetScheduledHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, ok := c.parseChatPath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+
+		c.logger.Infow("Handling GetScheduled request", "userID", userID, "chatID", chatID)
+
+		scheduled, hErr := c.chatService.GetScheduled(userID, chatID)
+		if hErr != nil {
+			c.logger.Errorw("Failed to fetch scheduled messages", "userID", userID, "chatID", chatID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, scheduled)
+	}
+}
+
+func (c ChatController) EditScheduledHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, scheduledID, ok := c.parseScheduledPath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+		req := r.Context().Value(middleware.DataKey).(EditScheduledRequest)
+
+		c.logger.Infow("Handling EditScheduled request", "userID", userID, "chatID", chatID, "scheduledID", scheduledID)
+
+		scheduled, hErr := c.chatService.EditScheduled(userID, chatID, scheduledID, req)
+		if hErr != nil {
+			c.logger.Errorw("Failed to edit scheduled message", "userID", userID, "chatID", chatID, "scheduledID", scheduledID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, scheduled)
+	}
+}
+
+func (c ChatController) CancelScheduledHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, scheduledID, ok := c.parseScheduledPath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+
+		c.logger.Infow("Handling CancelScheduled request", "userID", userID, "chatID", chatID, "scheduledID", scheduledID)
+
+		hErr := c.chatService.CancelScheduled(userID, chatID, scheduledID)
+		if hErr != nil {
+			c.logger.Errorw("Failed to cancel scheduled message", "userID", userID, "chatID", chatID, "scheduledID", scheduledID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		lib.SendMessage(w, r, http.StatusOK, "Scheduled message cancelled")
+	}
+}
+
+func (c ChatController) AddReactionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, messageID, ok := c.parseMessagePath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+		req := r.Context().Value(middleware.DataKey).(ReactionRequest)
+
+		c.logger.Infow("Handling AddReaction request", "userID", userID, "chatID", chatID, "messageID", messageID)
+
+		reaction, hErr := c.chatService.AddReaction(userID, chatID, messageID, req)
+		if hErr != nil {
+			c.logger.Errorw("Failed to add reaction", "userID", userID, "chatID", chatID, "messageID", messageID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, reaction)
+	}
+}
+
+func (c ChatController) RemoveReactionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, messageID, ok := c.parseMessagePath(w, r)
+		if !ok {
+			return
+		}
+
+		// Эмодзи в пути приходит в percent-encoding
+		emoji, err := url.PathUnescape(chi.URLParam(r, "emoji"))
+		if err != nil {
+			c.logger.Warnw("Invalid emoji parameter", "emoji", chi.URLParam(r, "emoji"), "error", err.Error())
+			lib.SendMessage(w, r, http.StatusBadRequest, "Invalid emoji parameter")
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+
+		c.logger.Infow("Handling RemoveReaction request", "userID", userID, "chatID", chatID, "messageID", messageID)
+
+		reaction, hErr := c.chatService.RemoveReaction(userID, chatID, messageID, emoji)
+		if hErr != nil {
+			c.logger.Errorw("Failed to remove reaction", "userID", userID, "chatID", chatID, "messageID", messageID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, reaction)
+	}
+}
+
+func (c ChatController) PinMessageHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, messageID, ok := c.parseMessagePath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+
+		c.logger.Infow("Handling PinMessage request", "actorID", userID, "chatID", chatID, "messageID", messageID)
+
+		hErr := c.chatService.PinMessage(userID, chatID, messageID)
+		if hErr != nil {
+			c.logger.Errorw("Failed to pin message", "actorID", userID, "chatID", chatID, "messageID", messageID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		lib.SendMessage(w, r, http.StatusOK, "Message pinned")
+	}
+}
+
+func (c ChatController) UnpinMessageHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, messageID, ok := c.parseMessagePath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+
+		c.logger.Infow("Handling UnpinMessage request", "actorID", userID, "chatID", chatID, "messageID", messageID)
+
+		hErr := c.chatService.UnpinMessage(userID, chatID, messageID)
+		if hErr != nil {
+			c.logger.Errorw("Failed to unpin message", "actorID", userID, "chatID", chatID, "messageID", messageID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		lib.SendMessage(w, r, http.StatusOK, "Message unpinned")
+	}
+}
+
+func (c ChatController) GetPinsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, ok := c.parseChatPath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+
+		c.logger.Infow("Handling GetPins request", "userID", userID, "chatID", chatID)
+
+		pins, hErr := c.chatService.GetPins(userID, chatID)
+		if hErr != nil {
+			c.logger.Errorw("Failed to fetch pinned messages", "userID", userID, "chatID", chatID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, pins)
+	}
+}
+
+func (c ChatController) CreatePollHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, ok := c.parseChatPath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+		req := r.Context().Value(middleware.DataKey).(CreatePollRequest)
+
+		c.logger.Infow("Handling CreatePoll request", "userID", userID, "chatID", chatID)
+
+		message, hErr := c.chatService.CreatePoll(userID, chatID, req)
+		if hErr != nil {
+			c.logger.Errorw("Failed to create poll", "userID", userID, "chatID", chatID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, message)
+	}
+}
+
+func (c ChatController) VoteHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, messageID, ok := c.parseMessagePath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+		req := r.Context().Value(middleware.DataKey).(VoteRequest)
+
+		c.logger.Infow("Handling Vote request", "userID", userID, "chatID", chatID, "messageID", messageID)
+
+		poll, hErr := c.chatService.Vote(userID, chatID, messageID, req)
+		if hErr != nil {
+			c.logger.Errorw("Failed to vote", "userID", userID, "chatID", chatID, "messageID", messageID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, poll)
+	}
+}
+
+func (c ChatController) RetractVoteHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, messageID, ok := c.parseMessagePath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+
+		c.logger.Infow("Handling RetractVote request", "userID", userID, "chatID", chatID, "messageID", messageID)
+
+		poll, hErr := c.chatService.RetractVote(userID, chatID, messageID)
+		if hErr != nil {
+			c.logger.Errorw("Failed to retract vote", "userID", userID, "chatID", chatID, "messageID", messageID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, poll)
+	}
+}
+
+func (c ChatController) ClosePollHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, messageID, ok := c.parseMessagePath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+
+		c.logger.Infow("Handling ClosePoll request", "actorID", userID, "chatID", chatID, "messageID", messageID)
+
+		hErr := c.chatService.ClosePoll(userID, chatID, messageID)
+		if hErr != nil {
+			c.logger.Errorw("Failed to close poll", "actorID", userID, "chatID", chatID, "messageID", messageID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		lib.SendMessage(w, r, http.StatusOK, "Poll closed")
+	}
+}
+
+func (c ChatController) ForwardMessageHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, messageID, ok := c.parseMessagePath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+		req := r.Context().Value(middleware.DataKey).(ForwardRequest)
+
+		c.logger.Infow("Handling ForwardMessage request", "userID", userID, "chatID", chatID, "messageID", messageID, "targetIDs", req.ChatIDs)
+
+		forwarded, hErr := c.chatService.ForwardMessage(userID, chatID, messageID, req)
+		if hErr != nil {
+			c.logger.Errorw("Failed to forward message", "userID", userID, "chatID", chatID, "messageID", messageID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, forwarded)
+	}
+}
+
+const (
+	// Запас на заголовки multipart сверх максимального размера файла
+	multipartOverhead = 1 << 20
+	// Файлы больше этого размера при разборе формы сбрасываются во временные файлы
+	multipartMemory = 1 << 20
+)
+
+func (c ChatController) UploadAttachmentHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatIDParam := chi.URLParam(r, "id")
+		chatIDUint64, err := strconv.ParseUint(chatIDParam, 10, 32)
+		if err != nil {
+			c.logger.Warnw("Invalid chat ID parameter", "chatID", chatIDParam, "error", err.Error())
+			lib.SendMessage(w, r, http.StatusBadRequest, "Invalid id parameter")
+			return
+		}
+		chatID := uint(chatIDUint64)
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+
+		if err := r.ParseMultipartForm(multipartMemory); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				c.logger.Warnw("Upload body is too large", "userID", userID, "chatID", chatID, "limit", maxBytesErr.Limit)
+				lib.SendMessage(w, r, http.StatusRequestEntityTooLarge, "file is too large")
+				return
+			}
+			c.logger.Warnw("Invalid multipart form", "userID", userID, "chatID", chatID, "error", err.Error())
+			lib.SendMessage(w, r, http.StatusBadRequest, "invalid multipart form")
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			c.logger.Warnw("Missing file field", "userID", userID, "chatID", chatID, "error", err.Error())
+			lib.SendMessage(w, r, http.StatusBadRequest, "file field is required")
+			return
+		}
+		defer file.Close()
+
+		c.logger.Infow("Handling UploadAttachment request", "userID", userID, "chatID", chatID, "size", header.Size)
+
+		attachment, hErr := c.chatService.UploadAttachment(userID, chatID, header.Filename, header.Size, file)
+		if hErr != nil {
+			c.logger.Errorw("Failed to upload attachment", "userID", userID, "chatID", chatID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, attachment)
+	}
+}
+
+func (c ChatController) GetAttachmentHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatIDParam := chi.URLParam(r, "id")
+		chatIDUint64, err := strconv.ParseUint(chatIDParam, 10, 32)
+		if err != nil {
+			c.logger.Warnw("Invalid chat ID parameter", "chatID", chatIDParam, "error", err.Error())
+			lib.SendMessage(w, r, http.StatusBadRequest, "Invalid id parameter")
+			return
+		}
+
+		attachmentIDParam := chi.URLParam(r, "attID")
+		attachmentIDUint64, err := strconv.ParseUint(attachmentIDParam, 10, 32)
+		if err != nil {
+			c.logger.Warnw("Invalid attachment ID parameter", "attachmentID", attachmentIDParam, "error", err.Error())
+			lib.SendMessage(w, r, http.StatusBadRequest, "Invalid attID parameter")
+			return
+		}
+
+		chatID, attachmentID := uint(chatIDUint64), uint(attachmentIDUint64)
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+		thumbnail := r.URL.Query().Get("thumbnail") == "true"
+
+		c.logger.Infow("Handling GetAttachment request", "userID", userID, "chatID", chatID, "attachmentID", attachmentID)
+
+		file, hErr := c.chatService.OpenAttachment(userID, chatID, attachmentID, thumbnail)
+		if hErr != nil {
+			c.logger.Errorw("Failed to open attachment", "userID", userID, "chatID", chatID, "attachmentID", attachmentID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+		defer file.Body.Close()
+
+		// Браузер не должен угадывать тип, а всё, кроме превью изображений, скачивается как файл
+		disposition := "attachment"
+		if thumbnailTypes[file.ContentType] {
+			disposition = "inline"
+		}
+
+		w.Header().Set("Content-Type", file.ContentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": file.FileName}))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "private")
+		if file.Size > 0 {
+			w.Header().Set("Content-Length", strconv.FormatInt(file.Size, 10))
+		}
+		w.WriteHeader(http.StatusOK)
+
+		if _, err := io.Copy(w, file.Body); err != nil {
+			c.logger.Warnw("Failed to send attachment", "attachmentID", attachmentID, "error", err)
+		}
+	}
+}
+
+// Размер страницы результатов поиска
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// parseOptionalID читает необязательный числовой параметр запроса, для пустого значения - nil
+func parseOptionalID(r *http.Request, name string) (*uint, error) {
+	param := r.URL.Query().Get(name)
+	if param == "" {
+		return nil, nil
+	}
+
+	value, err := strconv.ParseUint(param, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	id := uint(value)
+	return &id, nil
+}
+
+// parseOptionalTime читает необязательный параметр запроса в формате RFC 3339
+func parseOptionalTime(r *http.Request, name string) (*time.Time, error) {
+	param := r.URL.Query().Get(name)
+	if param == "" {
+		return nil, nil
+	}
+
+	value, err := time.Parse(time.RFC3339, param)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+func (c ChatController) SearchHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+		req := SearchRequest{Query: r.URL.Query().Get("q"), Limit: defaultSearchLimit}
+
+		var err error
+		for name, target := range map[string]**uint{"chat_id": &req.ChatID, "sender_id": &req.SenderID, "cursor": &req.Cursor} {
+			if *target, err = parseOptionalID(r, name); err != nil {
+				c.logger.Warnw("Invalid search parameter", "name", name, "error", err.Error())
+				lib.SendMessage(w, r, http.StatusBadRequest, "Invalid "+name+" parameter")
+				return
+			}
+		}
+
+		for name, target := range map[string]**time.Time{"from": &req.From, "to": &req.To} {
+			if *target, err = parseOptionalTime(r, name); err != nil {
+				c.logger.Warnw("Invalid search parameter", "name", name, "error", err.Error())
+				lib.SendMessage(w, r, http.StatusBadRequest, name+" must be an RFC 3339 timestamp")
+				return
+			}
+		}
+
+		if param := r.URL.Query().Get("limit"); param != "" {
+			parsed, err := strconv.Atoi(param)
+			if err != nil || parsed < 1 || parsed > maxSearchLimit {
+				c.logger.Warnw("Invalid limit parameter", "limit", param)
+				lib.SendMessage(w, r, http.StatusBadRequest, "limit must be between 1 and 100")
+				return
+			}
+			req.Limit = parsed
+		}
+
+		c.logger.Infow("Handling Search request", "userID", userID)
+
+		results, hErr := c.chatService.Search(userID, req)
+		if hErr != nil {
+			c.logger.Errorw("Failed to search messages", "userID", userID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, results)
+	}
+}
+
+// Размер страницы ответов в треде
+const (
+	defaultThreadLimit = 50
+	maxThreadLimit     = 100
+)
+
+func (c ChatController) GetThreadHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, messageID, ok := c.parseMessagePath(w, r)
+		if !ok {
+			return
+		}
+
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+
+		afterID := uint64(0)
+		if param := r.URL.Query().Get("after_id"); param != "" {
+			var err error
+			if afterID, err = strconv.ParseUint(param, 10, 32); err != nil {
+				c.logger.Warnw("Invalid after_id parameter", "afterID", param, "error", err.Error())
+				lib.SendMessage(w, r, http.StatusBadRequest, "Invalid after_id parameter")
+				return
+			}
+		}
+
+		limit := defaultThreadLimit
+		if param := r.URL.Query().Get("limit"); param != "" {
+			parsed, err := strconv.Atoi(param)
+			if err != nil || parsed < 1 || parsed > maxThreadLimit {
+				c.logger.Warnw("Invalid limit parameter", "limit", param)
+				lib.SendMessage(w, r, http.StatusBadRequest, "limit must be between 1 and 100")
+				return
+			}
+			limit = parsed
+		}
+
+		c.logger.Infow("Handling GetThread request", "userID", userID, "chatID", chatID, "messageID", messageID)
+
+		thread, hErr := c.chatService.GetThread(userID, chatID, messageID, uint(afterID), limit)
+		if hErr != nil {
+			c.logger.Errorw("Failed to get thread", "userID", userID, "chatID", chatID, "messageID", messageID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, thread)
+	}
+}
+
+// Размер страницы упоминаний
+const (
+	defaultMentionsLimit = 50
+	maxMentionsLimit     = 100
+)
+
+func (c ChatController) GetMentionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+
+		beforeID := uint64(0)
+		if param := r.URL.Query().Get("before_id"); param != "" {
+			var err error
+			if beforeID, err = strconv.ParseUint(param, 10, 32); err != nil {
+				c.logger.Warnw("Invalid before_id parameter", "beforeID", param, "error", err.Error())
+				lib.SendMessage(w, r, http.StatusBadRequest, "Invalid before_id parameter")
+				return
+			}
+		}
+
+		limit := defaultMentionsLimit
+		if param := r.URL.Query().Get("limit"); param != "" {
+			parsed, err := strconv.Atoi(param)
+			if err != nil || parsed < 1 || parsed > maxMentionsLimit {
+				c.logger.Warnw("Invalid limit parameter", "limit", param)
+				lib.SendMessage(w, r, http.StatusBadRequest, "limit must be between 1 and 100")
+				return
+			}
+			limit = parsed
+		}
+
+		c.logger.Infow("Handling GetMentions request", "userID", userID, "beforeID", beforeID, "limit", limit)
+
+		mentions, hErr := c.chatService.GetMentions(userID, uint(beforeID), limit)
+		if hErr != nil {
+			c.logger.Errorw("Failed to get mentions", "userID", userID, "error", hErr)
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, mentions)
+	}
+}
+
+func (c ChatController) TicketHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+		expiresAt := r.Context().Value(middleware.TokenExpiresAtKey).(time.Time)
+
+		c.logger.Infow("Handling WebSocket ticket request", "userID", userID)
+
+		ticket, err := c.chatService.IssueTicket(userID, expiresAt)
+		if err != nil {
+			c.logger.Errorw("Failed to issue WebSocket ticket", "userID", userID, "error", err)
+			lib.SendMessage(w, r, err.StatusCode, err.Error())
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, ticket)
+	}
+}
+
 func (c ChatController) BroadcastHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		senderID := r.Context().Value(middleware.UserIDKey).(uint)
-		err := c.chatService.HandleWebSocket(senderID, w, r)
+		expiresAt := r.Context().Value(middleware.TokenExpiresAtKey).(time.Time)
+		err := c.chatService.HandleWebSocket(senderID, expiresAt, w, r)
 
 		if err != nil {
 			lib.SendMessage(w, r, err.StatusCode, err.Error())
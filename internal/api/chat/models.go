@@ -1,6 +1,157 @@
 package chat
 
+import (
+	"io"
+	r "socialAPI/internal/storage/repository"
+	"time"
+)
+
 type CreateRequest struct {
 	UserIDs []uint  `json:"userIDs" validate:"required,min=2"`
 	Name    *string `json:"name"`
 }
+
+// DirectChatResponse - личный чат с пользователем. Created - был ли он создан этим запросом
+type DirectChatResponse struct {
+	ChatID  uint `json:"chat_id"`
+	Created bool `json:"created"`
+}
+
+// CreateInviteRequest - ограничения приглашения. Без полей приглашение бессрочное и многоразовое
+type CreateInviteRequest struct {
+	MaxUses   *int       `json:"max_uses" validate:"omitempty,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type JoinResponse struct {
+	ChatID uint `json:"chat_id"`
+}
+
+type TicketResponse struct {
+	Ticket string `json:"ticket"`
+}
+
+type ReadRequest struct {
+	MessageID uint `json:"message_id" validate:"required"`
+}
+
+type EditMessageRequest struct {
+	Content string `json:"content" validate:"required"`
+}
+
+// UpdateRequest - новое название чата
+type UpdateRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type AddMembersRequest struct {
+	UserIDs []uint `json:"user_ids" validate:"required,min=1"`
+}
+
+// RoleRequest - новая роль участника. Владельца назначить нельзя
+type RoleRequest struct {
+	Role r.ChatRole `json:"role" validate:"required,oneof=admin member"`
+}
+
+// SettingsRequest - изменение личных настроек чата, незаданные поля не меняются.
+// MutedUntil заглушает чат до указанного момента, Muted без MutedUntil - бессрочно
+type SettingsRequest struct {
+	Muted      *bool      `json:"muted"`
+	MutedUntil *time.Time `json:"muted_until"`
+	Archived   *bool      `json:"archived"`
+	Pinned     *bool      `json:"pinned"`
+}
+
+// ScheduleMessageRequest - сообщение, которое будет отправлено в SendAt
+type ScheduleMessageRequest struct {
+	Content   string    `json:"content" validate:"required"`
+	ReplyToID *uint     `json:"reply_to_id"`
+	SendAt    time.Time `json:"send_at" validate:"required"`
+}
+
+// EditScheduledRequest - изменение отложенного сообщения, незаданные поля не меняются
+type EditScheduledRequest struct {
+	Content *string    `json:"content" validate:"omitempty,min=1"`
+	SendAt  *time.Time `json:"send_at"`
+}
+
+// RetentionRequest - сроки хранения сообщений чата в секундах, незаданные поля не меняются.
+// retention_seconds: 0 - хранить без ограничения чата, message_ttl_seconds: 0 - выключить исчезающие сообщения
+type RetentionRequest struct {
+	RetentionSeconds  *int64 `json:"retention_seconds" validate:"omitempty,min=0"`
+	MessageTTLSeconds *int64 `json:"message_ttl_seconds" validate:"omitempty,min=0"`
+}
+
+// CreatePollRequest - опрос с вариантами ответа. Без closes_at опрос открыт, пока его не закроют вручную
+type CreatePollRequest struct {
+	Question  string     `json:"question" validate:"required"`
+	Options   []string   `json:"options" validate:"required,min=2,max=10,dive,required"`
+	Multiple  bool       `json:"multiple"`
+	Anonymous bool       `json:"anonymous"`
+	ClosesAt  *time.Time `json:"closes_at"`
+}
+
+// VoteRequest - выбранные варианты опроса. В опросе с одним ответом - ровно один вариант
+type VoteRequest struct {
+	OptionIDs []uint `json:"option_ids" validate:"required,min=1"`
+}
+
+// ForwardRequest - чаты, в которые пересылается сообщение
+type ForwardRequest struct {
+	ChatIDs []uint `json:"chat_ids" validate:"required,min=1,max=10,dive,required"`
+}
+
+// ForwardedMessageDTO - сообщение, созданное пересылкой в чат ChatID
+type ForwardedMessageDTO struct {
+	ChatID    uint `json:"chat_id"`
+	MessageID uint `json:"message_id"`
+}
+
+type ReactionRequest struct {
+	Emoji string `json:"emoji" validate:"required"`
+}
+
+// ThreadResponse - страница ответов на сообщение. NextAfterID передаётся в after_id для следующей страницы
+type ThreadResponse struct {
+	Parent      r.MessageDTO   `json:"parent"`
+	Replies     []r.MessageDTO `json:"replies"`
+	NextAfterID *uint          `json:"next_after_id,omitempty"`
+}
+
+// MentionsResponse - страница упоминаний. NextBeforeID передаётся в before_id для следующей страницы
+type MentionsResponse struct {
+	Mentions     []r.MentionDTO `json:"mentions"`
+	NextBeforeID *uint          `json:"next_before_id,omitempty"`
+}
+
+// AttachmentFile - открытое на чтение содержимое вложения или его превью. Size равен 0, если размер неизвестен
+type AttachmentFile struct {
+	FileName    string
+	ContentType string
+	Size        int64
+	Body        io.ReadCloser
+}
+
+// SearchRequest - параметры GET /v1/chat/search. Cursor - next_cursor предыдущей страницы
+type SearchRequest struct {
+	Query    string
+	ChatID   *uint
+	SenderID *uint
+	From     *time.Time
+	To       *time.Time
+	Cursor   *uint
+	Limit    int
+}
+
+type SearchResultDTO struct {
+	MessageID uint      `json:"message_id"`
+	ChatID    uint      `json:"chat_id"`
+	SenderID  uint      `json:"sender_id"`
+	Snippet   string    `json:"snippet"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SearchResponse struct {
+	Results    []SearchResultDTO `json:"results"`
+	NextCursor *uint             `json:"next_cursor,omitempty"`
+}
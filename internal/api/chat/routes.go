@@ -9,21 +9,60 @@ import (
 )
 
 type ChatController struct {
-	chatService  ChatService
-	tokenService shared.TokenService
-	logger       *zap.SugaredLogger
+	chatService   ChatService
+	tokenService  shared.TokenService
+	wsAuthService shared.WSAuthService
+	maxUploadSize int64
+	logger        *zap.SugaredLogger
 }
 
-func NewChatController(chatService ChatService, tokenService shared.TokenService, logger *zap.SugaredLogger) *ChatController {
-	return &ChatController{chatService: chatService, tokenService: tokenService, logger: logger}
+func NewChatController(chatService ChatService, tokenService shared.TokenService, wsAuthService shared.WSAuthService, maxUploadSize int64, logger *zap.SugaredLogger) *ChatController {
+	return &ChatController{chatService: chatService, tokenService: tokenService, wsAuthService: wsAuthService, maxUploadSize: maxUploadSize, logger: logger}
 }
 
 func (c ChatController) RegisterRoutes(r *chi.Mux) {
 	r.Route("/v1/chat", func(r chi.Router) {
 		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Get("/", c.GetAllHandler())
-		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Get("/ws", c.BroadcastHandler())
+		r.With(middleware.WebSocketAuthMiddleware(c.tokenService, c.wsAuthService, c.logger)).Get("/ws", c.BroadcastHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Post("/ws/ticket", c.TicketHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Get("/search", c.SearchHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Post("/direct/{userID}", c.DirectHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Post("/join/{code}", c.JoinHandler())
 		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Get("/{id}", c.GetOneHandler())
 		r.With(middleware.AuthMiddleware(c.tokenService, c.logger), middleware.JsonBodyMiddleware[CreateRequest](c.logger)).Post("/", c.CreateHandler())
-		r.With(middleware.AuthMiddleware(c.tokenService, c.logger), middleware.JsonBodyMiddleware[CreateRequest](c.logger)).Patch("/{id}", c.UpdateHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger), middleware.JsonBodyMiddleware[UpdateRequest](c.logger)).Patch("/{id}", c.UpdateHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger), middleware.JsonBodyMiddleware[AddMembersRequest](c.logger)).Post("/{id}/members", c.AddMembersHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Delete("/{id}/members/{userID}", c.RemoveMemberHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger), middleware.JsonBodyMiddleware[RoleRequest](c.logger)).Patch("/{id}/members/{userID}", c.SetRoleHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Post("/{id}/leave", c.LeaveHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger), middleware.JsonBodyMiddleware[RetentionRequest](c.logger)).Patch("/{id}/retention", c.SetRetentionHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger), middleware.JsonBodyMiddleware[CreateInviteRequest](c.logger)).Post("/{id}/invites", c.CreateInviteHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Get("/{id}/invites", c.GetInvitesHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Delete("/{id}/invites/{inviteID}", c.RevokeInviteHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger), middleware.JsonBodyMiddleware[ReadRequest](c.logger)).Post("/{id}/read", c.MarkReadHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger), middleware.JsonBodyMiddleware[SettingsRequest](c.logger)).Patch("/{id}/settings", c.UpdateSettingsHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger), middleware.JsonBodyMiddleware[EditMessageRequest](c.logger)).Patch("/{id}/messages/{msgID}", c.EditMessageHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Delete("/{id}/messages/{msgID}", c.DeleteMessageHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Get("/{id}/messages/{msgID}/thread", c.GetThreadHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger), middleware.JsonBodyMiddleware[ForwardRequest](c.logger)).Post("/{id}/messages/{msgID}/forward", c.ForwardMessageHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger), middleware.JsonBodyMiddleware[ScheduleMessageRequest](c.logger)).Post("/{id}/scheduled", c.ScheduleMessageHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Get("/{id}/scheduled", c.GetScheduledHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger), middleware.JsonBodyMiddleware[EditScheduledRequest](c.logger)).Patch("/{id}/scheduled/{schedID}", c.EditScheduledHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Delete("/{id}/scheduled/{schedID}", c.CancelScheduledHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger), middleware.JsonBodyMiddleware[ReactionRequest](c.logger)).Post("/{id}/messages/{msgID}/reactions", c.AddReactionHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Delete("/{id}/messages/{msgID}/reactions/{emoji}", c.RemoveReactionHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Post("/{id}/messages/{msgID}/pin", c.PinMessageHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Delete("/{id}/messages/{msgID}/pin", c.UnpinMessageHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Get("/{id}/pins", c.GetPinsHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger), middleware.JsonBodyMiddleware[CreatePollRequest](c.logger)).Post("/{id}/polls", c.CreatePollHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger), middleware.JsonBodyMiddleware[VoteRequest](c.logger)).Post("/{id}/messages/{msgID}/votes", c.VoteHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Delete("/{id}/messages/{msgID}/votes", c.RetractVoteHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Post("/{id}/messages/{msgID}/poll/close", c.ClosePollHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger), middleware.MaxBodyMiddleware(c.maxUploadSize+multipartOverhead)).Post("/{id}/attachments", c.UploadAttachmentHandler())
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Get("/{id}/attachments/{attID}", c.GetAttachmentHandler())
+	})
+
+	r.Route("/v1/me", func(r chi.Router) {
+		r.With(middleware.AuthMiddleware(c.tokenService, c.logger)).Get("/mentions", c.GetMentionsHandler())
 	})
 }
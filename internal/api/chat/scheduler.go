@@ -0,0 +1,66 @@
+package chat
+
+import (
+	chatWS "socialAPI/internal/api/chat/ws"
+	r "socialAPI/internal/storage/repository"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Scheduler отправляет отложенные сообщения, когда наступает их время
+type Scheduler interface {
+	Run()
+}
+
+type scheduler struct {
+	repo      r.ScheduledMessageRepository
+	hub       chatWS.Hub
+	interval  time.Duration
+	batchSize int
+	logger    *zap.SugaredLogger
+}
+
+func NewScheduler(repo r.ScheduledMessageRepository, hub chatWS.Hub, interval time.Duration, batchSize int, logger *zap.SugaredLogger) Scheduler {
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	return &scheduler{
+		repo:      repo,
+		hub:       hub,
+		interval:  interval,
+		batchSize: max(batchSize, 1),
+		logger:    logger,
+	}
+}
+
+// Run раз в interval разбирает наступившие сообщения. Полная пачка значит, что очередь
+// не исчерпана, и следующая берётся сразу. Сообщение сохраняется и удаляется из очереди в одной
+// транзакции, поэтому отправляется ровно один раз при любом числе узлов и перезапусках
+func (s *scheduler) Run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for {
+			if s.deliver() < s.batchSize {
+				break
+			}
+		}
+	}
+}
+
+func (s *scheduler) deliver() int {
+	messages, err := s.repo.DeliverDue(time.Now(), s.batchSize)
+	if err != nil {
+		s.logger.Errorw("Failed to deliver scheduled messages", "error", err)
+		return 0
+	}
+
+	if len(messages) > 0 {
+		s.logger.Infow("Scheduled messages delivered", "count", len(messages))
+		s.hub.PublishSaved(messages)
+	}
+	return len(messages)
+}
@@ -0,0 +1,129 @@
+package chat
+
+import (
+	"context"
+	chatWS "socialAPI/internal/api/chat/ws"
+	"socialAPI/internal/setting/cfg"
+	"socialAPI/internal/storage/blob"
+	r "socialAPI/internal/storage/repository"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const blobDeleteTimeout = 10 * time.Second
+
+// Sweeper удаляет сообщения с истёкшим сроком хранения вместе с файлами вложений,
+// а также вложения, которые загрузили, но так и не отправили
+type Sweeper interface {
+	Run()
+}
+
+type sweeper struct {
+	repo          r.MessageRepository
+	attachments   r.AttachmentRepository
+	blobs         blob.BlobStore
+	hub           chatWS.Hub
+	maxAge        time.Duration
+	unattachedTTL time.Duration
+	interval      time.Duration
+	batchSize     int
+	logger        *zap.SugaredLogger
+}
+
+func NewSweeper(repo r.MessageRepository, attachments r.AttachmentRepository, blobs blob.BlobStore, hub chatWS.Hub, chatCfg cfg.ChatConfig, uploadCfg cfg.UploadConfig, logger *zap.SugaredLogger) Sweeper {
+	interval := chatCfg.SweepInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	unattachedTTL := uploadCfg.UnattachedTTL
+	if unattachedTTL <= 0 {
+		unattachedTTL = 24 * time.Hour
+	}
+
+	return &sweeper{
+		repo:          repo,
+		attachments:   attachments,
+		blobs:         blobs,
+		hub:           hub,
+		maxAge:        chatCfg.RetentionMaxAge,
+		unattachedTTL: unattachedTTL,
+		interval:      interval,
+		batchSize:     max(chatCfg.SweepBatchSize, 1),
+		logger:        logger,
+	}
+}
+
+// Run раз в interval удаляет неотправленные вложения и истёкшие сообщения пачками,
+// пока не останется неполная пачка
+func (s *sweeper) Run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for {
+			if s.sweepUnattached() < s.batchSize {
+				break
+			}
+		}
+		for {
+			if s.sweep() < s.batchSize {
+				break
+			}
+		}
+	}
+}
+
+func (s *sweeper) sweepUnattached() int {
+	deleted, keys, err := s.attachments.DeleteUnattached(time.Now().Add(-s.unattachedTTL), s.batchSize)
+	if err != nil {
+		s.logger.Errorw("Failed to delete unattached attachments", "error", err)
+		return 0
+	}
+
+	if deleted == 0 {
+		return 0
+	}
+
+	s.deleteBlobs(keys)
+
+	s.logger.Infow("Unattached attachments deleted", "count", deleted, "blobs", len(keys))
+	return deleted
+}
+
+func (s *sweeper) sweep() int {
+	expired, keys, err := s.repo.DeleteExpired(time.Now(), s.maxAge, s.batchSize)
+	if err != nil {
+		s.logger.Errorw("Failed to delete expired messages", "error", err)
+		return 0
+	}
+
+	if len(expired) == 0 {
+		return 0
+	}
+
+	byChat := make(map[uint][]uint)
+	for _, message := range expired {
+		byChat[message.ChatID] = append(byChat[message.ChatID], message.ID)
+	}
+	for chatID, messageIDs := range byChat {
+		s.hub.PublishExpired(chatID, messageIDs)
+	}
+
+	s.deleteBlobs(keys)
+
+	s.logger.Infow("Expired messages deleted", "count", len(expired), "blobs", len(keys))
+	return len(expired)
+}
+
+// Записи вложений уже удалены, поэтому файл, который не удалось стереть, остаётся сиротой
+// в хранилище, но больше никому не отдаётся
+func (s *sweeper) deleteBlobs(keys []string) {
+	for _, key := range keys {
+		ctx, cancel := context.WithTimeout(context.Background(), blobDeleteTimeout)
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			s.logger.Warnw("Failed to delete attachment blob", "key", key, "error", err)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,60 @@
+package chat_test
+
+import (
+	"socialAPI/internal/api/chat"
+	"socialAPI/internal/mocks"
+	"socialAPI/internal/setting/cfg"
+	"socialAPI/internal/storage/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func TestSweeper(t *testing.T) {
+	const batchSize = 2
+	maxAge := 24 * time.Hour
+
+	repo := new(mocks.MessageRepository)
+	attachments := new(mocks.AttachmentRepository)
+	blobs := new(mocks.BlobStore)
+	hub := new(mocks.Hub)
+	done := make(chan struct{})
+
+	// Неотправленные вложения удаляются в начале каждого тика
+	attachments.On("DeleteUnattached", mock.Anything, batchSize).Return(1, []string{"chats/5/upload", "chats/5/upload_thumb"}, nil).Once()
+	attachments.On("DeleteUnattached", mock.Anything, batchSize).Return(0, []string(nil), nil)
+	blobs.On("Delete", mock.Anything, "chats/5/upload").Return(nil).Once()
+	blobs.On("Delete", mock.Anything, "chats/5/upload_thumb").Return(nil).Once()
+
+	// Полная пачка - сборщик сразу берёт следующую, неполная - ждёт следующего тика
+	repo.On("DeleteExpired", mock.Anything, maxAge, batchSize).
+		Return([]repository.ExpiredMessage{{ID: 1, ChatID: 5}, {ID: 2, ChatID: 5}}, []string{"chats/5/file"}, nil).Once()
+	repo.On("DeleteExpired", mock.Anything, maxAge, batchSize).
+		Return([]repository.ExpiredMessage{{ID: 3, ChatID: 6}}, []string(nil), nil).Once()
+	repo.On("DeleteExpired", mock.Anything, maxAge, batchSize).
+		Return([]repository.ExpiredMessage(nil), []string(nil), nil).Run(func(mock.Arguments) {
+		select {
+		case <-done:
+		default:
+			close(done)
+		}
+	})
+	hub.On("PublishExpired", uint(5), []uint{1, 2}).Return().Once()
+	hub.On("PublishExpired", uint(6), []uint{3}).Return().Once()
+	blobs.On("Delete", mock.Anything, "chats/5/file").Return(nil).Once()
+
+	chatCfg := cfg.ChatConfig{RetentionMaxAge: maxAge, SweepInterval: 5 * time.Millisecond, SweepBatchSize: batchSize}
+	go chat.NewSweeper(repo, attachments, blobs, hub, chatCfg, cfg.UploadConfig{UnattachedTTL: time.Hour}, zap.NewNop().Sugar()).Run()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sweeper did not reach an empty batch")
+	}
+
+	hub.AssertExpectations(t)
+	attachments.AssertExpectations(t)
+	blobs.AssertExpectations(t)
+}
@@ -0,0 +1,175 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"socialAPI/internal/storage/blob"
+	r "socialAPI/internal/storage/repository"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Типы, для которых строится превью: их умеет декодировать стандартная библиотека
+var thumbnailTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+const (
+	// Изображения больше этого числа пикселей не декодируются, чтобы не выделять гигабайты памяти
+	maxThumbnailPixels = 50_000_000
+	thumbnailQuality   = 80
+	thumbnailTimeout   = 30 * time.Second
+)
+
+// Thumbnailer строит превью загруженных изображений в фоне
+type Thumbnailer interface {
+	Run()
+	Enqueue(attachment r.Attachment)
+}
+
+type thumbnailer struct {
+	store   blob.BlobStore
+	repo    r.AttachmentRepository
+	size    int
+	workers int
+	queue   chan r.Attachment
+	logger  *zap.SugaredLogger
+}
+
+func NewThumbnailer(store blob.BlobStore, repo r.AttachmentRepository, size, workers int, logger *zap.SugaredLogger) Thumbnailer {
+	return &thumbnailer{
+		store:   store,
+		repo:    repo,
+		size:    max(size, 1),
+		workers: max(workers, 1),
+		queue:   make(chan r.Attachment, 256),
+		logger:  logger,
+	}
+}
+
+func (t *thumbnailer) Run() {
+	for i := 1; i < t.workers; i++ {
+		go t.work()
+	}
+	t.work()
+}
+
+// Enqueue не блокирует загрузку: при переполненной очереди вложение остаётся без превью
+func (t *thumbnailer) Enqueue(attachment r.Attachment) {
+	select {
+	case t.queue <- attachment:
+	default:
+		t.logger.Warnw("Thumbnail queue is full, skipping attachment", "attachmentID", attachment.ID)
+	}
+}
+
+func (t *thumbnailer) work() {
+	for attachment := range t.queue {
+		if err := t.process(attachment); err != nil {
+			t.logger.Errorw("Failed to build thumbnail", "attachmentID", attachment.ID, "error", err)
+		}
+	}
+}
+
+func (t *thumbnailer) process(attachment r.Attachment) error {
+	ctx, cancel := context.WithTimeout(context.Background(), thumbnailTimeout)
+	defer cancel()
+
+	body, err := t.store.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if config.Width*config.Height > maxThumbnailPixels {
+		return fmt.Errorf("image is too large: %dx%d", config.Width, config.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	var thumbnail bytes.Buffer
+	if err := jpeg.Encode(&thumbnail, resize(img, t.size), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return err
+	}
+
+	key := attachment.StorageKey + ".thumb.jpg"
+	if err := t.store.Put(ctx, key, &thumbnail, int64(thumbnail.Len()), "image/jpeg"); err != nil {
+		return err
+	}
+
+	if err := t.repo.SetThumbnail(attachment.ID, key); err != nil {
+		return err
+	}
+
+	t.logger.Infow("Thumbnail built", "attachmentID", attachment.ID, "key", key)
+	return nil
+}
+
+// resize вписывает изображение в квадрат size x size с сохранением пропорций, усредняя пиксели
+// каждой области исходника. Прозрачные участки заливаются белым, так как превью сохраняется в JPEG
+func resize(img image.Image, size int) *image.RGBA {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	dstW, dstH := srcW, srcH
+	if srcW > size || srcH > size {
+		if srcW >= srcH {
+			dstW, dstH = size, max(srcH*size/srcW, 1)
+		} else {
+			dstW, dstH = max(srcW*size/srcH, 1), size
+		}
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Over)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0, y1 := y*srcH/dstH, max((y+1)*srcH/dstH, y*srcH/dstH+1)
+		for x := 0; x < dstW; x++ {
+			x0, x1 := x*srcW/dstW, max((x+1)*srcW/dstW, x*srcW/dstW+1)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*4 : sy*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+
+			count := (y1 - y0) * (x1 - x0)
+			offset := y*dst.Stride + x*4
+			for i := range sum {
+				dst.Pix[offset+i] = uint8(sum[i] / count)
+			}
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,65 @@
+package chat_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"socialAPI/internal/api/chat"
+	"socialAPI/internal/mocks"
+	"socialAPI/internal/storage/blob"
+	"socialAPI/internal/storage/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestThumbnailer(t *testing.T) {
+	ctx := context.Background()
+	store, err := blob.NewLocalBlobStore(t.TempDir())
+	require.NoError(t, err)
+
+	// Прозрачное изображение 400x100 должно вписаться в 100x25 на белом фоне
+	src := image.NewNRGBA(image.Rect(0, 0, 400, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 400; x++ {
+			src.Set(x, y, color.NRGBA{R: 255, A: 0})
+		}
+	}
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, src))
+	require.NoError(t, store.Put(ctx, "chats/1/image", bytes.NewReader(encoded.Bytes()), int64(encoded.Len()), "image/png"))
+
+	repo := new(mocks.AttachmentRepository)
+	done := make(chan struct{})
+	repo.On("SetThumbnail", uint(7), "chats/1/image.thumb.jpg").Return(nil).Run(func(mock.Arguments) { close(done) }).Once()
+
+	thumbnailer := chat.NewThumbnailer(store, repo, 100, 1, zap.NewNop().Sugar())
+	go thumbnailer.Run()
+	thumbnailer.Enqueue(repository.Attachment{ID: 7, StorageKey: "chats/1/image", ContentType: "image/png"})
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("thumbnail was not built")
+	}
+
+	body, err := store.Get(ctx, "chats/1/image.thumb.jpg")
+	require.NoError(t, err)
+	defer body.Close()
+
+	thumbnail, err := jpeg.Decode(body)
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 100, 25), thumbnail.Bounds())
+
+	r, g, b, _ := thumbnail.At(50, 12).RGBA()
+	assert.Greater(t, r>>8, uint32(240))
+	assert.Greater(t, g>>8, uint32(240))
+	assert.Greater(t, b>>8, uint32(240))
+}
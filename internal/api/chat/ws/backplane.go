@@ -0,0 +1,39 @@
+package ws
+
+// Backplane разносит события чатов между узлами.
+// Узел подписывается на чаты, в которых у него есть локальные клиенты, и получает их события в Listen.
+// События с UserIDs получают все узлы: заранее неизвестно, где подключены сессии пользователя
+type Backplane interface {
+	Publish(event Event) error
+	Subscribe(chatID uint) error
+	Unsubscribe(chatID uint) error
+	Listen(deliver func(Event))
+}
+
+// Реализация для одного узла: события сразу возвращаются в локальный хаб
+type memoryBackplane struct {
+	events chan Event
+}
+
+func NewMemoryBackplane() Backplane {
+	return &memoryBackplane{events: make(chan Event, 256)}
+}
+
+func (b *memoryBackplane) Publish(event Event) error {
+	b.events <- event
+	return nil
+}
+
+func (b *memoryBackplane) Subscribe(chatID uint) error {
+	return nil
+}
+
+func (b *memoryBackplane) Unsubscribe(chatID uint) error {
+	return nil
+}
+
+func (b *memoryBackplane) Listen(deliver func(Event)) {
+	for event := range b.events {
+		deliver(event)
+	}
+}
@@ -2,6 +2,9 @@ package ws
 
 import (
 	"encoding/json"
+	"errors"
+	"net"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -9,21 +12,70 @@ import (
 )
 
 type Client struct {
-	conn    *websocket.Conn
-	send    chan Message
-	hub     Hub
-	userID  uint
-	chatIDs map[uint]bool
-	logger  *zap.SugaredLogger
+	conn     Conn
+	send     chan Event
+	hub      Hub
+	userID   uint
+	connID   string
+	chatIDs  map[uint]bool
+	chatsMu  sync.RWMutex
+	session  Session
+	timeouts clientTimeouts
+	logger   *zap.SugaredLogger
+
+	// Ограничение частоты typing_start, используется только в ReadPump
+	typingThrottle time.Duration
+	lastTyping     map[uint]time.Time
+
+	// Курсоры переподключения (chatID -> последний полученный messageID) и состояние догрузки.
+	// Поля replaying и pending принадлежат циклу хаба
+	resume    map[uint]uint
+	replayed  map[uint]uint
+	replaying bool
+	pending   []Event
+
+	// Чаты, куда клиента добавили, пока узел не был подписан на них. Принадлежит циклу хаба
+	joining map[uint]*joinCatchUp
+}
+
+func NewClient(conn Conn, send chan Event, hub Hub, userID uint, chatIDs map[uint]bool, resume map[uint]uint, session Session, logger *zap.SugaredLogger) *Client {
+	return &Client{conn: conn, send: send, hub: hub, userID: userID, chatIDs: chatIDs, resume: resume, session: session, logger: logger}
+}
+
+// inChat сообщает, состоит ли клиент в чате. Набор чатов меняет только цикл хаба под chatsMu,
+// поэтому сам хаб читает chatIDs без блокировки, а другие горутины - через inChat
+func (c *Client) inChat(chatID uint) bool {
+	c.chatsMu.RLock()
+	defer c.chatsMu.RUnlock()
+	return c.chatIDs[chatID]
+}
+
+func (c *Client) start() {
+	go c.ReadPump()
+	go c.WritePump()
 }
 
-func NewClient(conn *websocket.Conn, send chan Message, hub Hub, userID uint, chatIDs map[uint]bool, logger *zap.SugaredLogger) *Client {
-	return &Client{conn: conn, send: send, hub: hub, userID: userID, chatIDs: chatIDs, logger: logger}
+func (c *Client) writeEvent(event Event) error {
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeouts.writeWait))
+	return c.conn.WriteJSON(event)
 }
 
 type IncomingMessage struct {
-	ChatID  uint   `json:"chat_id"`
-	Content string `json:"content"`
+	ChatID    uint   `json:"chat_id"`
+	Content   string `json:"content"`
+	ReplyToID *uint  `json:"reply_to_id,omitempty"`
+
+	AttachmentIDs []uint `json:"attachment_ids,omitempty"`
+	MentionIDs    []uint `json:"mention_ids,omitempty"`
+}
+
+// Кадр от клиента. Без type считается обычным сообщением
+type incomingFrame struct {
+	Type      EventType `json:"type"`
+	MessageID uint      `json:"message_id"`
+	Emoji     string    `json:"emoji"`
+	OptionIDs []uint    `json:"option_ids"`
+	IncomingMessage
 }
 
 func (c *Client) ReadPump() {
@@ -32,6 +84,12 @@ func (c *Client) ReadPump() {
 		c.conn.Close()
 	}()
 
+	// Каждый pong продлевает дедлайн чтения. Если пир молчит дольше pongWait, ReadMessage вернёт таймаут
+	c.conn.SetReadDeadline(time.Now().Add(c.timeouts.pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(c.timeouts.pongWait))
+	})
+
 	for {
 		_, msg, err := c.conn.ReadMessage()
 		if err != nil {
@@ -39,25 +97,70 @@ func (c *Client) ReadPump() {
 				c.logger.Infow("WebSocket connection closed", "clientID", c.userID)
 				return
 			}
+
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				metricDeadConnections.Add(1)
+				c.logger.Warnw("Peer stopped responding, reaping connection", "clientID", c.userID)
+				return
+			}
+
 			c.logger.Errorw("Error reading message", "error", err, "clientID", c.userID)
 			return
 		}
+		c.conn.SetReadDeadline(time.Now().Add(c.timeouts.pongWait))
 
-		var msgStruct IncomingMessage
-		if err := json.Unmarshal(msg, &msgStruct); err != nil {
+		var frame incomingFrame
+		if err := json.Unmarshal(msg, &frame); err != nil {
 			c.logger.Errorw("Error unmarshalling message", "error", err)
 			return
 		}
 
-		c.hub.BroadcastMessage(Message{
-			IncomingMessage: msgStruct,
-			SenderID:        c.userID,
-		})
+		switch frame.Type {
+		case "", EventMessage:
+			if !c.inChat(frame.ChatID) {
+				c.logger.Warnw("Message to a chat the client is not a member of", "clientID", c.userID, "chatID", frame.ChatID)
+				continue
+			}
+			c.hub.BroadcastMessage(Message{
+				IncomingMessage: frame.IncomingMessage,
+				SenderID:        c.userID,
+			})
+		case EventTypingStart:
+			if c.allowTyping(frame.ChatID) {
+				c.hub.SetTyping(c, frame.ChatID, true)
+			}
+		case EventTypingStop:
+			delete(c.lastTyping, frame.ChatID)
+			c.hub.SetTyping(c, frame.ChatID, false)
+		case EventRead:
+			if _, err := c.hub.MarkRead(c.userID, frame.ChatID, frame.MessageID); err != nil {
+				c.logger.Errorw("Error marking chat as read", "error", err, "clientID", c.userID, "chatID", frame.ChatID)
+			}
+		case EventReactionAdded, EventReactionRemoved:
+			if _, err := c.hub.React(c.userID, frame.ChatID, frame.MessageID, frame.Emoji, frame.Type == EventReactionAdded); err != nil {
+				c.logger.Warnw("Error changing reaction", "error", err, "clientID", c.userID, "chatID", frame.ChatID, "messageID", frame.MessageID)
+			}
+		case EventVote:
+			if _, err := c.hub.Vote(c.userID, frame.ChatID, frame.MessageID, frame.OptionIDs); err != nil {
+				c.logger.Warnw("Error voting in poll", "error", err, "clientID", c.userID, "chatID", frame.ChatID, "messageID", frame.MessageID)
+			}
+		default:
+			c.logger.Warnw("Unknown frame type", "type", frame.Type, "clientID", c.userID)
+		}
 	}
 }
 
 func (c *Client) WritePump() {
-	ticker := time.NewTicker(time.Second * 60)
+	ticker := time.NewTicker(c.timeouts.pingPeriod)
+
+	var expired <-chan time.Time
+	if !c.session.ExpiresAt.IsZero() {
+		expiry := time.NewTimer(time.Until(c.session.ExpiresAt))
+		defer expiry.Stop()
+		expired = expiry.C
+	}
+
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
@@ -66,22 +169,33 @@ func (c *Client) WritePump() {
 
 	for {
 		select {
-		case message, ok := <-c.send:
-			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		case event, ok := <-c.send:
 			if !ok {
+				c.conn.SetWriteDeadline(time.Now().Add(c.timeouts.writeWait))
 				c.logger.Infow("Channel closed, sending close message", "clientID", c.userID)
 				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
 
-			if err := c.conn.WriteJSON(message); err != nil {
-				c.logger.Errorw("Error sending message", "error", err, "clientID", c.userID)
+			if err := c.writeEvent(event); err != nil {
+				c.logger.Errorw("Error sending event", "error", err, "clientID", c.userID)
 				return
 			}
-			c.logger.Infow("Message sent", "senderID", message.SenderID, "chatID", message.ChatID, "content", message.Content)
+			c.logger.Infow("Event sent", "type", event.Type, "chatID", event.ChatID, "messageID", event.MessageID, "clientID", c.userID)
+
+		case <-expired:
+			c.logger.Infow("Session expired, closing connection", "clientID", c.userID)
+			c.closeWith(CloseSessionExpired, "session expired")
+			return
 
 		case <-ticker.C:
-			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if !c.sessionActive() {
+				c.logger.Infow("Session revoked, closing connection", "clientID", c.userID)
+				c.closeWith(CloseSessionRevoked, "session revoked")
+				return
+			}
+
+			c.conn.SetWriteDeadline(time.Now().Add(c.timeouts.writeWait))
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				c.logger.Errorw("Error sending ping", "error", err, "clientID", c.userID)
 				return
@@ -90,3 +204,8 @@ func (c *Client) WritePump() {
 		}
 	}
 }
+
+func (c *Client) closeWith(code int, reason string) {
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeouts.writeWait))
+	c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
+}
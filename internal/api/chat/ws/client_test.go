@@ -0,0 +1,237 @@
+package ws
+
+import (
+	"encoding/binary"
+	"errors"
+	"socialAPI/internal/setting/cfg"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// Фейковое соединение: ничего не присылает, а на ping отвечает pong, только если responsive
+type fakeConn struct {
+	mu           sync.Mutex
+	responsive   bool
+	readDeadline time.Time
+	pongHandler  func(string) error
+	pings        int
+	closeCode    int
+	events       []Event
+	closed       bool
+}
+
+func (c *fakeConn) ReadMessage() (int, []byte, error) {
+	for {
+		c.mu.Lock()
+		closed, deadline := c.closed, c.readDeadline
+		c.mu.Unlock()
+
+		if closed {
+			return 0, nil, errors.New("use of closed connection")
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return 0, nil, timeoutError{}
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (c *fakeConn) WriteMessage(messageType int, data []byte) error {
+	c.mu.Lock()
+	if messageType == websocket.PingMessage {
+		c.pings++
+	}
+	if messageType == websocket.CloseMessage && len(data) >= 2 {
+		c.closeCode = int(binary.BigEndian.Uint16(data))
+	}
+	respond := messageType == websocket.PingMessage && c.responsive
+	handler := c.pongHandler
+	c.mu.Unlock()
+
+	if respond && handler != nil {
+		return handler("")
+	}
+	return nil
+}
+
+func (c *fakeConn) WriteJSON(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if event, ok := v.(Event); ok {
+		c.events = append(c.events, event)
+	}
+	return nil
+}
+
+func (c *fakeConn) eventTypes() []EventType {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	types := make([]EventType, 0, len(c.events))
+	for _, event := range c.events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func (c *fakeConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	return nil
+}
+
+func (c *fakeConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func (c *fakeConn) SetPongHandler(h func(string) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pongHandler = h
+}
+
+func (c *fakeConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *fakeConn) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+func (c *fakeConn) sentCloseCode() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closeCode
+}
+
+type nopPresence struct{}
+
+func (nopPresence) Run()                      {}
+func (nopPresence) Connected(uint, string)    {}
+func (nopPresence) Disconnected(uint, string) {}
+func (nopPresence) Refresh(map[string]uint)   {}
+
+type sessionValidatorFunc func(userID uint) (bool, error)
+
+func (f sessionValidatorFunc) IsSessionActive(userID uint) (bool, error) {
+	return f(userID)
+}
+
+func (c *fakeConn) pingCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pings
+}
+
+func TestClient_Liveness(t *testing.T) {
+	const pongWait = 60 * time.Millisecond
+
+	tests := []struct {
+		name       string
+		responsive bool
+		wantReaped bool
+	}{
+		{
+			name:       "silent peer is reaped after pong wait",
+			responsive: false,
+			wantReaped: true,
+		},
+		{
+			name:       "peer answering pings stays connected",
+			responsive: true,
+			wantReaped: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := zap.NewNop().Sugar()
+			h := NewHub(nil, nil, NewMemoryBackplane(), nopPresence{}, cfg.HubConfig{PongWait: pongWait, PingPeriod: pongWait / 3}, logger)
+			go h.Run()
+
+			conn := &fakeConn{responsive: tt.responsive}
+			reapedBefore := metricDeadConnections.Value()
+
+			h.RegisterClient(NewClient(conn, make(chan Event, 1), h, 1, map[uint]bool{1: true}, nil, Session{}, logger))
+
+			if tt.wantReaped {
+				assert.Eventually(t, conn.isClosed, 10*pongWait, 5*time.Millisecond)
+				assert.Equal(t, reapedBefore+1, metricDeadConnections.Value())
+				return
+			}
+
+			time.Sleep(5 * pongWait)
+			assert.False(t, conn.isClosed())
+			assert.GreaterOrEqual(t, conn.pingCount(), 3)
+			assert.Equal(t, reapedBefore, metricDeadConnections.Value())
+
+			conn.Close()
+		})
+	}
+}
+
+func TestClient_SessionEnd(t *testing.T) {
+	const pingPeriod = 20 * time.Millisecond
+
+	tests := []struct {
+		name     string
+		session  Session
+		wantCode int
+	}{
+		{
+			name:     "connection is closed when the token expires",
+			session:  Session{ExpiresAt: time.Now().Add(3 * pingPeriod)},
+			wantCode: CloseSessionExpired,
+		},
+		{
+			name: "connection is closed when the session is revoked",
+			session: Session{
+				ExpiresAt: time.Now().Add(time.Hour),
+				Validator: sessionValidatorFunc(func(uint) (bool, error) { return false, nil }),
+			},
+			wantCode: CloseSessionRevoked,
+		},
+		{
+			name: "storage errors do not close the connection",
+			session: Session{
+				Validator: sessionValidatorFunc(func(uint) (bool, error) { return false, errors.New("redis is down") }),
+			},
+			wantCode: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := zap.NewNop().Sugar()
+			h := NewHub(nil, nil, NewMemoryBackplane(), nopPresence{}, cfg.HubConfig{PongWait: time.Hour, PingPeriod: pingPeriod}, logger)
+			go h.Run()
+
+			conn := &fakeConn{responsive: true}
+			h.RegisterClient(NewClient(conn, make(chan Event, 1), h, 1, map[uint]bool{1: true}, nil, tt.session, logger))
+
+			if tt.wantCode == 0 {
+				time.Sleep(5 * pingPeriod)
+				assert.False(t, conn.isClosed())
+				conn.Close()
+				return
+			}
+
+			assert.Eventually(t, conn.isClosed, 50*pingPeriod, 5*time.Millisecond)
+			assert.Equal(t, tt.wantCode, conn.sentCloseCode())
+		})
+	}
+}
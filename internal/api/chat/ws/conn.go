@@ -0,0 +1,31 @@
+package ws
+
+import (
+	"expvar"
+	"time"
+)
+
+// Conn - часть *websocket.Conn, которой пользуется клиент. Нужна, чтобы подменять соединение в тестах
+type Conn interface {
+	ReadMessage() (messageType int, p []byte, err error)
+	WriteMessage(messageType int, data []byte) error
+	WriteJSON(v interface{}) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+	SetPongHandler(h func(appData string) error)
+	Close() error
+}
+
+// Тайминги проверки живости соединения
+type clientTimeouts struct {
+	pongWait   time.Duration // сколько ждём pong (или любой кадр) до признания соединения мёртвым
+	pingPeriod time.Duration // как часто отправляем ping, должно быть меньше pongWait
+	writeWait  time.Duration // дедлайн на запись одного кадра
+}
+
+// Метрики, доступны на /debug/vars
+var (
+	metricActiveConnections = expvar.NewInt("ws_active_connections")
+	metricDeadConnections   = expvar.NewInt("ws_dead_connections_reaped")
+	metricSlowConnections   = expvar.NewInt("ws_slow_connections_dropped")
+)
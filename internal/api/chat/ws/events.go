@@ -0,0 +1,70 @@
+package ws
+
+import "encoding/json"
+
+// Типы событий, отправляемых клиенту
+type EventType string
+
+const (
+	EventMessage         EventType = "message"
+	EventReplayTruncated EventType = "replay_truncated"
+	EventChatJoined      EventType = "chat_joined"
+	EventChatLeft        EventType = "chat_left"
+	EventTypingStart     EventType = "typing_start"
+	EventTypingStop      EventType = "typing_stop"
+	EventPresence        EventType = "presence"
+	EventRead            EventType = "read"
+	EventMessageUpdated  EventType = "message_updated"
+	EventMessageDeleted  EventType = "message_deleted"
+	EventReactionAdded   EventType = "reaction_added"
+	EventReactionRemoved EventType = "reaction_removed"
+	EventPinned          EventType = "pinned"
+	EventUnpinned        EventType = "unpinned"
+	EventNotification    EventType = "notification"
+	EventMessageExpired  EventType = "message_expired"
+	EventPollUpdated     EventType = "poll_updated"
+
+	// Кадр клиента с голосом в опросе, клиенту в ответ приходит poll_updated
+	EventVote EventType = "vote"
+)
+
+// Конверт события. ChatID определяет, каким клиентам событие будет доставлено,
+// MessageID позволяет клиенту запомнить курсор для переподключения.
+// Если задан UserIDs, событие адресовано всем сессиям этих пользователей, а не участникам чата.
+// SenderID задаётся у эфемерных событий: сессиям автора они не доставляются
+type Event struct {
+	Type      EventType       `json:"type"`
+	ChatID    uint            `json:"chat_id,omitempty"`
+	MessageID uint            `json:"message_id,omitempty"`
+	UserIDs   []uint          `json:"user_ids,omitempty"`
+	SenderID  uint            `json:"sender_id,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+func NewEvent(eventType EventType, chatID uint, data interface{}) (Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{Type: eventType, ChatID: chatID, Data: payload}, nil
+}
+
+func newMessageEvent(msg Message) (Event, error) {
+	event, err := NewEvent(EventMessage, msg.ChatID, msg)
+	event.MessageID = msg.ID
+	return event, err
+}
+
+// Данные события replay_truncated: в этих чатах пропущено больше limit сообщений,
+// историю нужно догрузить через REST
+type ReplayTruncated struct {
+	ChatIDs []uint `json:"chat_ids"`
+	Limit   int    `json:"limit"`
+}
+
+// Данные события chat_joined. AfterID - последнее сообщение чата до входа: всё, что новее,
+// участник получит живыми событиями или догрузкой, если узел ещё не был подписан на чат
+type MembershipChange struct {
+	AfterID uint `json:"after_id"`
+}
@@ -1,7 +1,12 @@
 package ws
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"socialAPI/internal/setting/cfg"
 	r "socialAPI/internal/storage/repository"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -12,14 +17,19 @@ type HubCommandType int
 const (
 	CommandRegister HubCommandType = iota
 	CommandUnregister
-	CommandBroadcast
+	CommandDeliver
+	CommandReplayDone
+	CommandTyping
+	CommandSubscribed
+	CommandJoinCaughtUp
 )
 
-// Структура команды
+// Структура команды. Events - догруженные сообщения для CommandJoinCaughtUp
 type HubCommand struct {
-	Type    HubCommandType
-	Client  *Client
-	Message Message
+	Type   HubCommandType
+	Client *Client
+	Event  Event
+	Events []Event
 }
 
 // Интерфейс Hub
@@ -28,44 +38,183 @@ type Hub interface {
 	RegisterClient(client *Client)
 	UnregisterClient(client *Client)
 	BroadcastMessage(msg Message)
+	UpdateMembership(chatID, afterID uint, joined, left []uint)
+	SetTyping(client *Client, chatID uint, typing bool)
+	MarkRead(userID, chatID, messageID uint) (bool, error)
+	PublishMessage(record r.Message)
+	PublishSaved(records []r.Message)
+	PublishMessageChange(eventType EventType, record r.Message)
+	React(userID, chatID, messageID uint, emoji string, add bool) (*Reaction, error)
+	PublishPin(eventType EventType, chatID uint, pin Pin)
+	PublishExpired(chatID uint, messageIDs []uint)
+	Vote(userID, chatID, messageID uint, optionIDs []uint) (*r.PollDTO, error)
+	PublishPoll(chatID, messageID uint, poll *r.Poll)
 }
 
 // Структура сообщения
 type Message struct {
 	IncomingMessage
-	SenderID uint `json:"sender_id"`
+	ID        uint       `json:"id"`
+	SenderID  uint       `json:"sender_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+	Deleted   bool       `json:"deleted,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	Kind   r.MessageKind  `json:"kind"`
+	System *r.SystemEvent `json:"system,omitempty"`
+	Poll   *r.PollDTO     `json:"poll,omitempty"`
+
+	ForwardedFrom *r.ForwardOrigin `json:"forwarded_from,omitempty"`
+
+	Mentions    []uint `json:"mentions,omitempty"`
+	MentionsAll bool   `json:"mentions_all,omitempty"`
+	// Все упомянутые, включая участников из @all. Нужны только для уведомлений и не передаются
+	mentioned []uint
+
+	ReplyTo    *r.MessagePreviewDTO `json:"reply_to,omitempty"`
+	ReplyCount int                  `json:"reply_count,omitempty"`
+
+	Attachments []r.AttachmentDTO `json:"attachments,omitempty"`
+}
+
+func messageFromRecord(record r.Message) Message {
+	return Message{
+		IncomingMessage: IncomingMessage{ChatID: record.ChatID, Content: record.Content, ReplyToID: record.ReplyToID},
+		ReplyTo:         record.ReplyTo.ConvertToPreviewDTO(),
+		ReplyCount:      record.ReplyCount,
+		Attachments:     r.ConvertAttachmentsToDTO(record.Attachments),
+		ID:              record.ID,
+		SenderID:        record.SenderID,
+		CreatedAt:       record.CreatedAt,
+		EditedAt:        record.EditedAt,
+		Deleted:         record.DeletedAt != nil,
+		ExpiresAt:       record.ExpiresAt,
+		Kind:            record.Kind,
+		System:          record.System,
+		Poll:            record.Poll.ConvertToDTO(0),
+		ForwardedFrom:   record.ForwardedFrom,
+		Mentions:        record.Mentions,
+		MentionsAll:     record.MentionsAll,
+		mentioned:       record.Mentioned,
+	}
+}
+
+func newClientTimeouts(cfg cfg.HubConfig) clientTimeouts {
+	timeouts := clientTimeouts{pongWait: cfg.PongWait, pingPeriod: cfg.PingPeriod, writeWait: cfg.WriteWait}
+	if timeouts.pongWait <= 0 {
+		timeouts.pongWait = 60 * time.Second
+	}
+	if timeouts.pingPeriod <= 0 || timeouts.pingPeriod >= timeouts.pongWait {
+		timeouts.pingPeriod = timeouts.pongWait * 9 / 10
+	}
+	if timeouts.writeWait <= 0 {
+		timeouts.writeWait = 10 * time.Second
+	}
+	return timeouts
 }
 
 // Реализация Hub
 type hub struct {
-	clients     map[*Client]bool
-	commands    chan HubCommand
-	messageRepo r.MessageRepository
-	chatRepo    r.ChatRepository
-	logger      *zap.SugaredLogger
+	clients       map[*Client]bool
+	subscriptions map[uint]int // количество локальных клиентов в каждом чате
+	subscriber    *subscriptions
+	unconfirmed   map[uint]bool // чаты, подписка на которые ещё не подтверждена backplane
+	commands      chan HubCommand
+	persister     *persister
+	backplane     Backplane
+	presence      Presence
+	presenceTTL   time.Duration
+	nodeID        string
+	lastConnID    uint64
+	messageRepo   r.MessageRepository
+	chatRepo      r.ChatRepository
+	replayLimit   int
+	timeouts      clientTimeouts
+	typing        map[*Client]map[uint]time.Time // chatID -> момент, когда индикатор набора погаснет
+	ephemeral     chan Event
+	typingTTL     time.Duration
+	typingLimit   time.Duration
+	reactions     map[string]bool // допустимые эмодзи реакций
+	logger        *zap.SugaredLogger
 }
 
 // Конструктор
-func NewHub(messageRepo r.MessageRepository, chatRepo r.ChatRepository, logger *zap.SugaredLogger) Hub {
-	return &hub{
-		clients:     make(map[*Client]bool),
-		commands:    make(chan HubCommand),
-		messageRepo: messageRepo,
-		chatRepo:    chatRepo,
-		logger:      logger,
+func NewHub(messageRepo r.MessageRepository, chatRepo r.ChatRepository, backplane Backplane, presence Presence, cfg cfg.HubConfig, logger *zap.SugaredLogger) Hub {
+	h := &hub{
+		clients:       make(map[*Client]bool),
+		subscriptions: make(map[uint]int),
+		unconfirmed:   make(map[uint]bool),
+		commands:      make(chan HubCommand),
+		backplane:     backplane,
+		presence:      presence,
+		presenceTTL:   cfg.PresenceTTL,
+		nodeID:        newNodeID(),
+		messageRepo:   messageRepo,
+		chatRepo:      chatRepo,
+		replayLimit:   max(cfg.ReplayLimit, 1),
+		timeouts:      newClientTimeouts(cfg),
+		typing:        make(map[*Client]map[uint]time.Time),
+		ephemeral:     make(chan Event, 256),
+		typingTTL:     cfg.TypingTTL,
+		typingLimit:   cfg.TypingThrottle,
+		reactions:     make(map[string]bool, len(cfg.AllowedReactions)),
+		logger:        logger,
 	}
+	for _, emoji := range cfg.AllowedReactions {
+		h.reactions[emoji] = true
+	}
+	if h.typingTTL <= 0 {
+		h.typingTTL = 5 * time.Second
+	}
+	if h.presenceTTL <= 0 {
+		h.presenceTTL = time.Minute
+	}
+	h.subscriber = newSubscriptions(backplane, h.subscribed, logger)
+	h.persister = newPersister(cfg, messageRepo, chatRepo, h.publishMessage, h.publishNotification, logger)
+
+	return h
 }
 
-// Запуск хаба
+// Запуск хаба. Цикл занимается только маршрутизацией, сохранение сообщений идёт в воркерах,
+// а доставка между узлами в backplane
 func (h *hub) Run() {
-	for cmd := range h.commands {
-		switch cmd.Type {
-		case CommandRegister:
-			h.handleRegister(cmd.Client)
-		case CommandUnregister:
-			h.handleUnregister(cmd.Client)
-		case CommandBroadcast:
-			h.handleBroadcast(cmd.Message)
+	h.persister.start()
+	go h.backplane.Listen(h.deliverEvent)
+	go h.subscriber.run()
+	go h.publishEphemeral()
+	go h.presence.Run()
+
+	typingTicker := time.NewTicker(h.typingTTL / 2)
+	defer typingTicker.Stop()
+
+	// Соединения в хранилище присутствия живут presenceTTL, продлеваем их заранее
+	presenceTicker := time.NewTicker(h.presenceTTL / 3)
+	defer presenceTicker.Stop()
+
+	for {
+		select {
+		case cmd := <-h.commands:
+			switch cmd.Type {
+			case CommandRegister:
+				h.handleRegister(cmd.Client)
+			case CommandUnregister:
+				h.handleUnregister(cmd.Client)
+			case CommandDeliver:
+				h.handleDeliver(cmd.Event)
+			case CommandReplayDone:
+				h.handleReplayDone(cmd.Client)
+			case CommandTyping:
+				h.handleTyping(cmd.Client, cmd.Event)
+			case CommandSubscribed:
+				h.handleSubscribed(cmd.Event.ChatID)
+			case CommandJoinCaughtUp:
+				h.handleJoinCaughtUp(cmd.Client, cmd.Event.ChatID, cmd.Events)
+			}
+		case now := <-typingTicker.C:
+			h.expireTyping(now)
+		case <-presenceTicker.C:
+			h.refreshPresence()
 		}
 	}
 }
@@ -80,9 +229,89 @@ func (h *hub) UnregisterClient(client *Client) {
 	h.commands <- HubCommand{Type: CommandUnregister, Client: client}
 }
 
-// Отправка сообщения
+// Отправка сообщения: сохранение в воркере, публикация в backplane, доставка через цикл хаба
 func (h *hub) BroadcastMessage(msg Message) {
-	h.commands <- HubCommand{Type: CommandBroadcast, Message: msg}
+	h.persister.enqueue(msg)
+}
+
+// Публикация уже сохранённого сообщения всем узлам
+func (h *hub) publishMessage(msg Message) {
+	event, err := newMessageEvent(msg)
+	if err != nil {
+		h.logger.Errorw("Error encoding message event",
+			"messageID", msg.ID,
+			"chatID", msg.ChatID,
+			"error", err)
+		return
+	}
+
+	if err := h.backplane.Publish(event); err != nil {
+		h.logger.Errorw("Error publishing message",
+			"messageID", msg.ID,
+			"chatID", msg.ChatID,
+			"error", err)
+	}
+}
+
+// Изменение состава чата: всем сессиям добавленных и удалённых пользователей на всех узлах
+// уходят chat_joined и chat_left, а хабы обновляют маршрутизацию. afterID - последнее сообщение
+// чата до входа, с него новые участники догружают историю, пока узел подписывается на чат
+func (h *hub) UpdateMembership(chatID, afterID uint, joined, left []uint) {
+	h.publishMembership(EventChatJoined, chatID, MembershipChange{AfterID: afterID}, joined)
+	h.publishMembership(EventChatLeft, chatID, nil, left)
+}
+
+func (h *hub) publishMembership(eventType EventType, chatID uint, data interface{}, userIDs []uint) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	event := Event{Type: eventType, ChatID: chatID}
+	if data != nil {
+		var err error
+		if event, err = NewEvent(eventType, chatID, data); err != nil {
+			h.logger.Errorw("Error encoding membership change", "type", eventType, "chatID", chatID, "error", err)
+			return
+		}
+	}
+	event.UserIDs = userIDs
+
+	if err := h.backplane.Publish(event); err != nil {
+		h.logger.Errorw("Error publishing membership change",
+			"type", eventType,
+			"chatID", chatID,
+			"userIDs", userIDs,
+			"error", err)
+	}
+}
+
+// Рассылка уже сохранённого сообщения, созданного в обход клиента, например системного.
+// В отличие от изменений, сдвигает курсор переподключения
+func (h *hub) PublishMessage(record r.Message) {
+	h.publishMessage(messageFromRecord(record))
+}
+
+// Рассылка изменённого или удалённого сообщения участникам чата. MessageID в конверте не задаётся:
+// это не новое сообщение и курсор переподключения сдвигать не должно
+func (h *hub) PublishMessageChange(eventType EventType, record r.Message) {
+	event, err := NewEvent(eventType, record.ChatID, messageFromRecord(record))
+	if err != nil {
+		h.logger.Errorw("Error encoding message change event", "type", eventType, "messageID", record.ID, "error", err)
+		return
+	}
+
+	if err := h.backplane.Publish(event); err != nil {
+		h.logger.Errorw("Error publishing message change",
+			"type", eventType,
+			"messageID", record.ID,
+			"chatID", record.ChatID,
+			"error", err)
+	}
+}
+
+// Доставка события, пришедшего из backplane, локальным клиентам
+func (h *hub) deliverEvent(event Event) {
+	h.commands <- HubCommand{Type: CommandDeliver, Event: event}
 }
 
 // Внутренние обработчики:
@@ -90,10 +319,25 @@ func (h *hub) BroadcastMessage(msg Message) {
 func (h *hub) handleRegister(client *Client) {
 	h.logger.Infow("Registering new client", "clientID", client.userID)
 
+	client.timeouts = h.timeouts
+	client.typingThrottle = h.typingLimit
+	h.lastConnID++
+	client.connID = fmt.Sprintf("%s:%d", h.nodeID, h.lastConnID)
 	h.clients[client] = true
+	metricActiveConnections.Add(1)
+	h.presence.Connected(client.userID, client.connID)
+	for chatID := range client.chatIDs {
+		h.subscribe(chatID)
+	}
 
-	go client.ReadPump()
-	go client.WritePump()
+	// Пока идёт догрузка пропущенных сообщений, живые события копятся в client.pending
+	if len(client.resume) > 0 {
+		client.replaying = true
+		go h.replay(client)
+		return
+	}
+
+	client.start()
 }
 
 func (h *hub) handleUnregister(client *Client) {
@@ -102,42 +346,136 @@ func (h *hub) handleUnregister(client *Client) {
 	if _, ok := h.clients[client]; ok {
 		delete(h.clients, client)
 		close(client.send)
+		metricActiveConnections.Add(-1)
+		h.presence.Disconnected(client.userID, client.connID)
+
+		for chatID := range h.typing[client] {
+			h.stopTyping(client, chatID)
+		}
+		for chatID := range client.chatIDs {
+			h.unsubscribe(chatID)
+		}
 	}
 }
 
-func (h *hub) handleBroadcast(msg Message) {
-	h.logger.Infow("Broadcasting message",
-		"senderID", msg.SenderID,
-		"chatID", msg.ChatID,
-		"content", msg.Content)
+func (h *hub) refreshPresence() {
+	conns := make(map[string]uint, len(h.clients))
+	for client := range h.clients {
+		conns[client.connID] = client.userID
+	}
+	h.presence.Refresh(conns)
+}
 
-	exists, err := h.chatRepo.ExistsID(msg.ChatID)
-	if err != nil {
-		h.logger.Errorw("Error checking chat existence",
-			"chatID", msg.ChatID,
-			"error", err)
-		return
+// Идентификатор узла делает connID уникальными между экземплярами приложения
+func newNodeID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to generate node ID: %v", err))
 	}
+	return hex.EncodeToString(b)
+}
+
+func (h *hub) subscribe(chatID uint) {
+	h.subscriptions[chatID]++
+	if h.subscriptions[chatID] == 1 {
+		h.unconfirmed[chatID] = true
+		h.subscriber.set(chatID, true)
+	}
+}
 
-	if !exists {
-		h.logger.Warnw("Chat does not exist",
-			"chatID", msg.ChatID)
+func (h *hub) unsubscribe(chatID uint) {
+	h.subscriptions[chatID]--
+	if h.subscriptions[chatID] > 0 {
 		return
 	}
 
-	err = h.messageRepo.Create(msg.ChatID, msg.SenderID, msg.Content)
-	if err != nil {
-		h.logger.Errorw("Error creating message",
-			"chatID", msg.ChatID,
-			"error", err)
+	delete(h.subscriptions, chatID)
+	delete(h.unconfirmed, chatID)
+	h.subscriber.set(chatID, false)
+}
+
+func (h *hub) handleDeliver(event Event) {
+	h.logger.Infow("Delivering event",
+		"type", event.Type,
+		"chatID", event.ChatID,
+		"messageID", event.MessageID)
+
+	if len(event.UserIDs) > 0 {
+		h.handleUserEvent(event)
 		return
 	}
 
 	for client := range h.clients {
-		select {
-		case client.send <- msg:
-		default:
+		if !client.chatIDs[event.ChatID] {
+			continue
+		}
+		if event.SenderID != 0 && event.SenderID == client.userID {
+			continue
+		}
+		if join := client.joining[event.ChatID]; join != nil {
+			h.holdForJoin(client, join, event)
+			continue
+		}
+
+		h.sendTo(client, event)
+	}
+}
+
+// Событие для конкретных пользователей. Для chat_joined и chat_left
+// сначала меняется набор чатов клиента и подписки узла, затем клиент получает событие
+func (h *hub) handleUserEvent(event Event) {
+	userIDs := make(map[uint]bool, len(event.UserIDs))
+	for _, id := range event.UserIDs {
+		userIDs[id] = true
+	}
+	event.UserIDs = nil
+
+	for client := range h.clients {
+		if !userIDs[client.userID] {
+			continue
+		}
+
+		switch event.Type {
+		case EventChatJoined:
+			if client.chatIDs[event.ChatID] {
+				continue
+			}
+			client.chatsMu.Lock()
+			client.chatIDs[event.ChatID] = true
+			client.chatsMu.Unlock()
+			h.subscribe(event.ChatID)
+			h.startJoin(client, event)
+		case EventChatLeft:
+			if !client.chatIDs[event.ChatID] {
+				continue
+			}
+			delete(client.joining, event.ChatID)
+			h.stopTyping(client, event.ChatID)
+			client.chatsMu.Lock()
+			delete(client.chatIDs, event.ChatID)
+			client.chatsMu.Unlock()
+			h.unsubscribe(event.ChatID)
+		}
+
+		h.sendTo(client, event)
+	}
+}
+
+func (h *hub) sendTo(client *Client, event Event) {
+	if client.replaying {
+		if len(client.pending) >= cap(client.send) {
+			metricSlowConnections.Add(1)
 			h.handleUnregister(client)
+			return
 		}
+		client.pending = append(client.pending, event)
+		return
+	}
+
+	select {
+	case client.send <- event:
+	default:
+		metricSlowConnections.Add(1)
+		h.handleUnregister(client)
 	}
 }
@@ -0,0 +1,73 @@
+package ws
+
+import (
+	"fmt"
+	"socialAPI/internal/setting/cfg"
+	r "socialAPI/internal/storage/repository"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Имитация базы с фиксированной задержкой на каждый запрос
+const dbLatency = 200 * time.Microsecond
+
+type slowChatRepo struct {
+	r.ChatRepository
+}
+
+func (slowChatRepo) FilterActiveSenders(senders []r.ChatSender) (map[r.ChatSender]bool, error) {
+	time.Sleep(dbLatency)
+	return allActive(senders), nil
+}
+
+func (slowChatRepo) GetNotifiableUserIDs(chatIDs []uint) (map[uint][]uint, error) {
+	time.Sleep(dbLatency)
+	return nil, nil
+}
+
+type slowMessageRepo struct {
+	r.MessageRepository
+	saved atomic.Int64
+	done  chan struct{}
+	total int64
+}
+
+func (m *slowMessageRepo) CreateBatch(messages []*r.Message) error {
+	time.Sleep(dbLatency)
+	if m.saved.Add(int64(len(messages))) == m.total {
+		close(m.done)
+	}
+	return nil
+}
+
+func BenchmarkHubBroadcast(b *testing.B) {
+	configs := []struct {
+		name string
+		cfg  cfg.HubConfig
+	}{
+		// Поведение до выноса сохранения из цикла хаба: по одному сообщению за раз, последовательно
+		{name: "serial", cfg: cfg.HubConfig{PersistWorkers: 1, PersistBatchSize: 1, PersistQueueSize: 1}},
+		{name: "workers=4/batch=100", cfg: cfg.HubConfig{PersistWorkers: 4, PersistBatchSize: 100, PersistQueueSize: 1024}},
+		{name: "workers=8/batch=100", cfg: cfg.HubConfig{PersistWorkers: 8, PersistBatchSize: 100, PersistQueueSize: 1024}},
+	}
+
+	for _, tc := range configs {
+		b.Run(tc.name, func(b *testing.B) {
+			messageRepo := &slowMessageRepo{done: make(chan struct{}), total: int64(b.N)}
+			h := NewHub(messageRepo, slowChatRepo{}, NewMemoryBackplane(), nopPresence{}, tc.cfg, zap.NewNop().Sugar())
+			go h.Run()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				h.BroadcastMessage(Message{
+					IncomingMessage: IncomingMessage{ChatID: uint(i%16) + 1, Content: fmt.Sprintf("message %d", i)},
+					SenderID:        1,
+				})
+			}
+			<-messageRepo.done
+		})
+	}
+}
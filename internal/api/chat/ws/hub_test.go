@@ -0,0 +1,501 @@
+package ws
+
+import (
+	"socialAPI/internal/setting/cfg"
+	r "socialAPI/internal/storage/repository"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// joinMessageRepo отдаёт догрузке новых участников заранее заданные сообщения
+type joinMessageRepo struct {
+	r.MessageRepository
+	records []r.Message
+}
+
+func (repo joinMessageRepo) ListAfter(cursors map[uint]uint, limit int) ([]r.Message, error) {
+	var messages []r.Message
+	for _, record := range repo.records {
+		if cursor, ok := cursors[record.ChatID]; ok && record.ID > cursor {
+			messages = append(messages, record)
+		}
+	}
+	return messages, nil
+}
+
+func TestHub_UpdateMembership(t *testing.T) {
+	const chatID = uint(5)
+
+	logger := zap.NewNop().Sugar()
+	h := NewHub(joinMessageRepo{}, nil, NewMemoryBackplane(), nopPresence{}, cfg.HubConfig{PongWait: time.Hour}, logger).(*hub)
+	go h.Run()
+
+	// Две сессии добавляемого пользователя и сессия пользователя, которого удаляют из чата
+	joinerA, joinerB, leaver := &fakeConn{responsive: true}, &fakeConn{responsive: true}, &fakeConn{responsive: true}
+	h.RegisterClient(NewClient(joinerA, make(chan Event, 8), h, 1, map[uint]bool{}, nil, Session{}, logger))
+	h.RegisterClient(NewClient(joinerB, make(chan Event, 8), h, 1, map[uint]bool{}, nil, Session{}, logger))
+	h.RegisterClient(NewClient(leaver, make(chan Event, 8), h, 2, map[uint]bool{chatID: true}, nil, Session{}, logger))
+
+	h.UpdateMembership(chatID, 0, []uint{1}, []uint{2})
+	h.backplane.Publish(Event{Type: EventMessage, ChatID: chatID, MessageID: 1})
+
+	want := []EventType{EventChatJoined, EventMessage}
+	for _, conn := range []*fakeConn{joinerA, joinerB} {
+		assert.Eventually(t, func() bool { return assert.ObjectsAreEqual(want, conn.eventTypes()) }, time.Second, 5*time.Millisecond)
+	}
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]EventType{EventChatLeft}, leaver.eventTypes())
+	}, time.Second, 5*time.Millisecond)
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, []EventType{EventChatLeft}, leaver.eventTypes())
+
+	for _, conn := range []*fakeConn{joinerA, joinerB, leaver} {
+		conn.Close()
+	}
+}
+
+func TestHub_Typing(t *testing.T) {
+	const (
+		chatID    = uint(7)
+		typingTTL = 40 * time.Millisecond
+	)
+
+	logger := zap.NewNop().Sugar()
+	h := NewHub(nil, nil, NewMemoryBackplane(), nopPresence{}, cfg.HubConfig{PongWait: time.Hour, TypingTTL: typingTTL}, logger).(*hub)
+	go h.Run()
+
+	// Автор печатает с одного устройства, его второе устройство и чужой клиент вне чата события не получают
+	typist, typistOtherDevice := &fakeConn{responsive: true}, &fakeConn{responsive: true}
+	member, outsider := &fakeConn{responsive: true}, &fakeConn{responsive: true}
+	typistClient := NewClient(typist, make(chan Event, 8), h, 1, map[uint]bool{chatID: true}, nil, Session{}, logger)
+	h.RegisterClient(typistClient)
+	h.RegisterClient(NewClient(typistOtherDevice, make(chan Event, 8), h, 1, map[uint]bool{chatID: true}, nil, Session{}, logger))
+	h.RegisterClient(NewClient(member, make(chan Event, 8), h, 2, map[uint]bool{chatID: true}, nil, Session{}, logger))
+	h.RegisterClient(NewClient(outsider, make(chan Event, 8), h, 3, map[uint]bool{chatID + 1: true}, nil, Session{}, logger))
+
+	h.SetTyping(typistClient, chatID, true)
+
+	// Без обновления индикатор гаснет сам
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]EventType{EventTypingStart, EventTypingStop}, member.eventTypes())
+	}, 10*typingTTL, 5*time.Millisecond)
+
+	assert.Empty(t, typist.eventTypes())
+	assert.Empty(t, typistOtherDevice.eventTypes())
+	assert.Empty(t, outsider.eventTypes())
+
+	for _, conn := range []*fakeConn{typist, typistOtherDevice, member, outsider} {
+		conn.Close()
+	}
+}
+
+func TestClient_AllowTyping(t *testing.T) {
+	client := &Client{typingThrottle: time.Hour}
+
+	assert.True(t, client.allowTyping(1))
+	assert.False(t, client.allowTyping(1))
+	assert.True(t, client.allowTyping(2))
+}
+
+type readChatRepo struct {
+	r.ChatRepository
+}
+
+func (readChatRepo) MarkRead(chatID, userID, messageID uint) (bool, error) {
+	return messageID > 10, nil
+}
+
+func TestHub_MarkRead(t *testing.T) {
+	const chatID = uint(9)
+
+	logger := zap.NewNop().Sugar()
+	h := NewHub(nil, readChatRepo{}, NewMemoryBackplane(), nopPresence{}, cfg.HubConfig{PongWait: time.Hour}, logger).(*hub)
+	go h.Run()
+
+	reader, member := &fakeConn{responsive: true}, &fakeConn{responsive: true}
+	h.RegisterClient(NewClient(reader, make(chan Event, 8), h, 1, map[uint]bool{chatID: true}, nil, Session{}, logger))
+	h.RegisterClient(NewClient(member, make(chan Event, 8), h, 2, map[uint]bool{chatID: true}, nil, Session{}, logger))
+
+	// Позиция не сдвинулась - событие не рассылается
+	advanced, err := h.MarkRead(1, chatID, 5)
+	assert.NoError(t, err)
+	assert.False(t, advanced)
+
+	advanced, err = h.MarkRead(1, chatID, 15)
+	assert.NoError(t, err)
+	assert.True(t, advanced)
+
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]EventType{EventRead}, member.eventTypes())
+	}, time.Second, 5*time.Millisecond)
+	assert.Empty(t, reader.eventTypes())
+
+	reader.Close()
+	member.Close()
+}
+
+type reactionChatRepo struct {
+	r.ChatRepository
+}
+
+func (reactionChatRepo) IsMember(chatID, userID uint) (bool, error) {
+	return userID != 3, nil
+}
+
+// reactionMessageRepo хранит реакции в памяти, сообщения 1..10 принадлежат чату 9
+type reactionMessageRepo struct {
+	r.MessageRepository
+	mu        sync.Mutex
+	reactions map[r.MessageReaction]bool
+}
+
+func (repo *reactionMessageRepo) GetByID(messageID uint) (*r.Message, error) {
+	if messageID > 10 {
+		return &r.Message{ID: messageID, ChatID: 10}, nil
+	}
+	return &r.Message{ID: messageID, ChatID: 9}, nil
+}
+
+func (repo *reactionMessageRepo) AddReaction(messageID, userID uint, emoji string) (bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	key := r.MessageReaction{MessageID: messageID, UserID: userID, Emoji: emoji}
+	added := !repo.reactions[key]
+	repo.reactions[key] = true
+	return added, nil
+}
+
+func (repo *reactionMessageRepo) RemoveReaction(messageID, userID uint, emoji string) (bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	key := r.MessageReaction{MessageID: messageID, UserID: userID, Emoji: emoji}
+	removed := repo.reactions[key]
+	delete(repo.reactions, key)
+	return removed, nil
+}
+
+func (repo *reactionMessageRepo) CountReactions(messageID uint, emoji string) (int64, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	var count int64
+	for key := range repo.reactions {
+		if key.MessageID == messageID && key.Emoji == emoji {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func TestHub_React(t *testing.T) {
+	const chatID = uint(9)
+
+	logger := zap.NewNop().Sugar()
+	messageRepo := &reactionMessageRepo{reactions: make(map[r.MessageReaction]bool)}
+	hubCfg := cfg.HubConfig{PongWait: time.Hour, AllowedReactions: []string{"👍", "🔥"}}
+	h := NewHub(messageRepo, reactionChatRepo{}, NewMemoryBackplane(), nopPresence{}, hubCfg, logger).(*hub)
+	go h.Run()
+
+	author, member := &fakeConn{responsive: true}, &fakeConn{responsive: true}
+	h.RegisterClient(NewClient(author, make(chan Event, 8), h, 1, map[uint]bool{chatID: true}, nil, Session{}, logger))
+	h.RegisterClient(NewClient(member, make(chan Event, 8), h, 2, map[uint]bool{chatID: true}, nil, Session{}, logger))
+
+	_, err := h.React(1, chatID, 5, "💩", true)
+	assert.ErrorIs(t, err, ErrReactionNotAllowed)
+
+	_, err = h.React(3, chatID, 5, "👍", true)
+	assert.ErrorIs(t, err, ErrNotChatMember)
+
+	_, err = h.React(1, chatID, 15, "👍", true)
+	assert.ErrorIs(t, err, ErrMessageNotFound)
+
+	reaction, err := h.React(1, chatID, 5, "👍", true)
+	assert.NoError(t, err)
+	assert.Equal(t, &Reaction{MessageID: 5, UserID: 1, Emoji: "👍", Count: 1}, reaction)
+
+	// Повторная реакция не меняет набор и не рассылается
+	reaction, err = h.React(1, chatID, 5, "👍", true)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), reaction.Count)
+
+	reaction, err = h.React(1, chatID, 5, "👍", false)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), reaction.Count)
+
+	want := []EventType{EventReactionAdded, EventReactionRemoved}
+	for _, conn := range []*fakeConn{author, member} {
+		assert.Eventually(t, func() bool {
+			return assert.ObjectsAreEqual(want, conn.eventTypes())
+		}, time.Second, 5*time.Millisecond)
+	}
+
+	author.Close()
+	member.Close()
+}
+
+// pollMessageRepo: сообщение 5 чата 9 - опрос, 6 - обычное сообщение. Vote хранит голоса в памяти
+type pollMessageRepo struct {
+	r.MessageRepository
+	mu    sync.Mutex
+	votes map[uint][]uint
+}
+
+func (repo *pollMessageRepo) GetByID(messageID uint) (*r.Message, error) {
+	if messageID == 5 {
+		return &r.Message{ID: messageID, ChatID: 9, Kind: r.MessageKindPoll}, nil
+	}
+	return &r.Message{ID: messageID, ChatID: 9, Kind: r.MessageKindText}, nil
+}
+
+func (repo *pollMessageRepo) Vote(messageID, userID uint, optionIDs []uint) (*r.Poll, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.votes[userID] = optionIDs
+
+	poll := &r.Poll{MessageID: messageID, Options: []r.PollOption{{ID: 1, Text: "yes"}, {ID: 2, Text: "no", Position: 1}}}
+	for voterID, options := range repo.votes {
+		for _, optionID := range options {
+			poll.Votes = append(poll.Votes, r.PollVote{MessageID: messageID, UserID: voterID, OptionID: optionID})
+		}
+	}
+	return poll, nil
+}
+
+func TestHub_Vote(t *testing.T) {
+	const chatID = uint(9)
+
+	logger := zap.NewNop().Sugar()
+	h := NewHub(&pollMessageRepo{votes: make(map[uint][]uint)}, reactionChatRepo{}, NewMemoryBackplane(), nopPresence{}, cfg.HubConfig{PongWait: time.Hour}, logger).(*hub)
+	go h.Run()
+
+	voter, member := &fakeConn{responsive: true}, &fakeConn{responsive: true}
+	h.RegisterClient(NewClient(voter, make(chan Event, 8), h, 1, map[uint]bool{chatID: true}, nil, Session{}, logger))
+	h.RegisterClient(NewClient(member, make(chan Event, 8), h, 2, map[uint]bool{chatID: true}, nil, Session{}, logger))
+
+	_, err := h.Vote(3, chatID, 5, []uint{1})
+	assert.ErrorIs(t, err, ErrNotChatMember)
+
+	_, err = h.Vote(1, chatID, 6, []uint{1})
+	assert.ErrorIs(t, err, ErrNotAPoll)
+
+	poll, err := h.Vote(1, chatID, 5, []uint{2})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, poll.TotalVoters)
+	assert.Equal(t, r.PollOptionDTO{ID: 2, Text: "no", Votes: 1, Voted: true, VoterIDs: []uint{1}}, poll.Options[1])
+
+	for _, conn := range []*fakeConn{voter, member} {
+		assert.Eventually(t, func() bool {
+			return assert.ObjectsAreEqual([]EventType{EventPollUpdated}, conn.eventTypes())
+		}, time.Second, 5*time.Millisecond)
+	}
+
+	voter.Close()
+	member.Close()
+}
+
+// allActive считает всех отправителей участниками своих чатов
+func allActive(senders []r.ChatSender) map[r.ChatSender]bool {
+	active := make(map[r.ChatSender]bool, len(senders))
+	for _, sender := range senders {
+		active[sender] = true
+	}
+	return active
+}
+
+// notifyChatRepo: в чате 11 участники 1, 2 и 3, третий заглушил чат. Пользователь 4 в чате не состоит
+type notifyChatRepo struct {
+	r.ChatRepository
+}
+
+func (notifyChatRepo) FilterActiveSenders(senders []r.ChatSender) (map[r.ChatSender]bool, error) {
+	active := allActive(senders)
+	delete(active, r.ChatSender{ChatID: 11, SenderID: 4})
+	return active, nil
+}
+
+func (notifyChatRepo) GetNotifiableUserIDs(chatIDs []uint) (map[uint][]uint, error) {
+	return map[uint][]uint{11: {1, 2}}, nil
+}
+
+type notifyMessageRepo struct {
+	r.MessageRepository
+}
+
+func (notifyMessageRepo) CreateBatch(messages []*r.Message) error {
+	for i, msg := range messages {
+		msg.ID = uint(i + 1)
+		msg.CreatedAt = time.Now()
+		msg.Mentions, msg.Mentioned = msg.MentionIDs, msg.MentionIDs
+	}
+	return nil
+}
+
+func TestHub_Notifications(t *testing.T) {
+	const chatID = uint(11)
+
+	logger := zap.NewNop().Sugar()
+	h := NewHub(notifyMessageRepo{}, notifyChatRepo{}, NewMemoryBackplane(), nopPresence{}, cfg.HubConfig{PongWait: time.Hour}, logger).(*hub)
+	go h.Run()
+
+	sender, member, muted := &fakeConn{responsive: true}, &fakeConn{responsive: true}, &fakeConn{responsive: true}
+	h.RegisterClient(NewClient(sender, make(chan Event, 8), h, 1, map[uint]bool{chatID: true}, nil, Session{}, logger))
+	h.RegisterClient(NewClient(member, make(chan Event, 8), h, 2, map[uint]bool{chatID: true}, nil, Session{}, logger))
+	h.RegisterClient(NewClient(muted, make(chan Event, 8), h, 3, map[uint]bool{chatID: true}, nil, Session{}, logger))
+
+	h.BroadcastMessage(Message{IncomingMessage: IncomingMessage{ChatID: chatID, Content: "hello"}, SenderID: 1})
+
+	// Сообщение получают все, уведомление - только не заглушивший чат участник, кроме отправителя
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]EventType{EventMessage, EventNotification}, member.eventTypes())
+	}, time.Second, 5*time.Millisecond)
+	for _, conn := range []*fakeConn{sender, muted} {
+		assert.Eventually(t, func() bool {
+			return assert.ObjectsAreEqual([]EventType{EventMessage}, conn.eventTypes())
+		}, time.Second, 5*time.Millisecond)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, []EventType{EventMessage}, sender.eventTypes())
+	assert.Equal(t, []EventType{EventMessage}, muted.eventTypes())
+
+	// Упомянутый участник получает уведомление, даже если заглушил чат
+	h.BroadcastMessage(Message{IncomingMessage: IncomingMessage{ChatID: chatID, Content: "@carol@example.com", MentionIDs: []uint{3}}, SenderID: 1})
+
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]EventType{EventMessage, EventMessage, EventNotification}, muted.eventTypes())
+	}, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]EventType{EventMessage, EventNotification, EventMessage, EventNotification}, member.eventTypes())
+	}, time.Second, 5*time.Millisecond)
+
+	for _, conn := range []*fakeConn{sender, member, muted} {
+		conn.Close()
+	}
+}
+
+// savedMessageRepo запоминает тексты сохранённых сообщений
+type savedMessageRepo struct {
+	r.MessageRepository
+	mu    sync.Mutex
+	saved []string
+}
+
+func (repo *savedMessageRepo) CreateBatch(messages []*r.Message) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	for _, msg := range messages {
+		msg.ID = uint(len(repo.saved) + 1)
+		repo.saved = append(repo.saved, msg.Content)
+	}
+	return nil
+}
+
+func (repo *savedMessageRepo) contents() []string {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	return append([]string(nil), repo.saved...)
+}
+
+func TestHub_RejectsMessagesFromNonMembers(t *testing.T) {
+	const chatID = uint(11)
+
+	logger := zap.NewNop().Sugar()
+	messageRepo := &savedMessageRepo{}
+	h := NewHub(messageRepo, notifyChatRepo{}, NewMemoryBackplane(), nopPresence{}, cfg.HubConfig{PongWait: time.Hour}, logger).(*hub)
+	go h.Run()
+
+	member := &fakeConn{responsive: true}
+	h.RegisterClient(NewClient(member, make(chan Event, 8), h, 2, map[uint]bool{chatID: true}, nil, Session{}, logger))
+
+	// Пользователь 4 не состоит в чате: сообщение не сохраняется и не рассылается
+	h.BroadcastMessage(Message{IncomingMessage: IncomingMessage{ChatID: chatID, Content: "intruder"}, SenderID: 4})
+	h.BroadcastMessage(Message{IncomingMessage: IncomingMessage{ChatID: chatID, Content: "hello"}, SenderID: 1})
+
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]string{"hello"}, messageRepo.contents())
+	}, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]EventType{EventMessage, EventNotification}, member.eventTypes())
+	}, time.Second, 5*time.Millisecond)
+
+	member.Close()
+}
+
+// stuckBackplane зависает на подписке, пока тест не отпустит release
+type stuckBackplane struct {
+	Backplane
+	release    chan struct{}
+	subscribed chan uint
+}
+
+func (b *stuckBackplane) Subscribe(chatID uint) error {
+	<-b.release
+	b.subscribed <- chatID
+	return nil
+}
+
+func TestHub_SlowSubscribeDoesNotBlockDelivery(t *testing.T) {
+	const chatID = uint(5)
+
+	logger := zap.NewNop().Sugar()
+	backplane := &stuckBackplane{Backplane: NewMemoryBackplane(), release: make(chan struct{}), subscribed: make(chan uint, 1)}
+	h := NewHub(nil, nil, backplane, nopPresence{}, cfg.HubConfig{PongWait: time.Hour}, logger).(*hub)
+	go h.Run()
+
+	// Подписка на новый чат висит, но уже подключённый клиент продолжает получать события
+	waiting, other := &fakeConn{responsive: true}, &fakeConn{responsive: true}
+	h.RegisterClient(NewClient(waiting, make(chan Event, 8), h, 1, map[uint]bool{chatID: true}, nil, Session{}, logger))
+	h.RegisterClient(NewClient(other, make(chan Event, 8), h, 2, map[uint]bool{chatID + 1: true}, nil, Session{}, logger))
+	h.backplane.Publish(Event{Type: EventMessage, ChatID: chatID, MessageID: 1})
+
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]EventType{EventMessage}, waiting.eventTypes())
+	}, time.Second, 5*time.Millisecond)
+
+	close(backplane.release)
+	subscribed := []uint{<-backplane.subscribed, <-backplane.subscribed}
+	assert.ElementsMatch(t, []uint{chatID, chatID + 1}, subscribed)
+
+	waiting.Close()
+	other.Close()
+}
+
+func TestHub_JoinCatchUpAfterSubscribe(t *testing.T) {
+	const chatID = uint(5)
+
+	logger := zap.NewNop().Sugar()
+	joined := r.Message{ID: 7, ChatID: chatID, SenderID: 1, Kind: r.MessageKindSystem}
+	earlier := r.Message{ID: 3, ChatID: chatID, SenderID: 1, Content: "before the join"}
+	backplane := &stuckBackplane{Backplane: NewMemoryBackplane(), release: make(chan struct{}), subscribed: make(chan uint, 1)}
+	h := NewHub(joinMessageRepo{records: []r.Message{earlier, joined}}, nil, backplane, nopPresence{}, cfg.HubConfig{PongWait: time.Hour}, logger).(*hub)
+	go h.Run()
+
+	joiner := &fakeConn{responsive: true}
+	h.RegisterClient(NewClient(joiner, make(chan Event, 8), h, 2, map[uint]bool{}, nil, Session{}, logger))
+
+	// Пока подписка висит, живое событие копится, а после подписки догрузка отдаёт сообщения
+	// новее курсора. Системное сообщение пришло обоими путями, но доставляется один раз
+	h.UpdateMembership(chatID, joined.ID-1, []uint{2}, nil)
+	h.PublishMessage(joined)
+	h.backplane.Publish(Event{Type: EventReactionAdded, ChatID: chatID})
+
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]EventType{EventChatJoined}, joiner.eventTypes())
+	}, time.Second, 5*time.Millisecond)
+
+	close(backplane.release)
+	<-backplane.subscribed
+
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]EventType{EventChatJoined, EventMessage, EventReactionAdded}, joiner.eventTypes())
+	}, time.Second, 5*time.Millisecond)
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, []EventType{EventChatJoined, EventMessage, EventReactionAdded}, joiner.eventTypes())
+
+	joiner.Close()
+}
@@ -0,0 +1,119 @@
+package ws
+
+import "encoding/json"
+
+// Догрузка для нового участника. Пока узел подписывается на канал чата, сообщения в нём
+// до узла не доходят, поэтому после подтверждения подписки всё, что новее курсора из chat_joined,
+// читается из базы. Живые события чата на это время копятся и отдаются после догрузки без дублей
+type joinCatchUp struct {
+	afterID uint
+	loading bool
+	pending []Event
+}
+
+// startJoin запоминает курсор, если подписка узла на чат ещё не подтверждена
+func (h *hub) startJoin(client *Client, event Event) {
+	if !h.unconfirmed[event.ChatID] {
+		return
+	}
+
+	var change MembershipChange
+	if len(event.Data) > 0 {
+		if err := json.Unmarshal(event.Data, &change); err != nil {
+			h.logger.Errorw("Error decoding chat_joined event", "chatID", event.ChatID, "error", err)
+			return
+		}
+	}
+
+	if client.joining == nil {
+		client.joining = make(map[uint]*joinCatchUp)
+	}
+	client.joining[event.ChatID] = &joinCatchUp{afterID: change.AfterID}
+}
+
+func (h *hub) holdForJoin(client *Client, join *joinCatchUp, event Event) {
+	if len(join.pending) >= cap(client.send) {
+		metricSlowConnections.Add(1)
+		h.handleUnregister(client)
+		return
+	}
+	join.pending = append(join.pending, event)
+}
+
+// Вызывается из горутины подписок, когда backplane подтвердил подписку
+func (h *hub) subscribed(chatID uint) {
+	h.commands <- HubCommand{Type: CommandSubscribed, Event: Event{ChatID: chatID}}
+}
+
+func (h *hub) handleSubscribed(chatID uint) {
+	if h.subscriptions[chatID] == 0 {
+		return
+	}
+	delete(h.unconfirmed, chatID)
+
+	for client := range h.clients {
+		join := client.joining[chatID]
+		if join == nil || join.loading {
+			continue
+		}
+		join.loading = true
+		go h.loadJoin(client, chatID, join.afterID)
+	}
+}
+
+// loadJoin читает сообщения после курсора и возвращает их циклу хаба. Если их больше replayLimit,
+// клиент получит replay_truncated и возьмёт историю через REST
+func (h *hub) loadJoin(client *Client, chatID, afterID uint) {
+	var events []Event
+
+	records, err := h.messageRepo.ListAfter(map[uint]uint{chatID: afterID}, h.replayLimit+1)
+	if err != nil {
+		h.logger.Errorw("Error loading messages for new member", "clientID", client.userID, "chatID", chatID, "error", err)
+	}
+
+	if err != nil || len(records) > h.replayLimit {
+		event, err := NewEvent(EventReplayTruncated, 0, ReplayTruncated{ChatIDs: []uint{chatID}, Limit: h.replayLimit})
+		if err != nil {
+			h.logger.Errorw("Error encoding replay_truncated event", "error", err)
+		} else {
+			events = append(events, event)
+		}
+	} else {
+		for _, record := range records {
+			event, err := newMessageEvent(messageFromRecord(record))
+			if err != nil {
+				h.logger.Errorw("Error encoding message event", "messageID", record.ID, "error", err)
+				continue
+			}
+			events = append(events, event)
+		}
+	}
+
+	h.commands <- HubCommand{Type: CommandJoinCaughtUp, Client: client, Event: Event{ChatID: chatID}, Events: events}
+}
+
+func (h *hub) handleJoinCaughtUp(client *Client, chatID uint, events []Event) {
+	join := client.joining[chatID]
+	if _, ok := h.clients[client]; !ok || join == nil {
+		return
+	}
+	delete(client.joining, chatID)
+
+	var lastID uint
+	for _, event := range events {
+		lastID = max(lastID, event.MessageID)
+	}
+	for _, event := range join.pending {
+		if event.MessageID == 0 || event.MessageID > lastID {
+			events = append(events, event)
+		}
+	}
+
+	// Медленного клиента sendTo отключает, дальше писать в закрытый канал нельзя
+	for _, event := range events {
+		if _, ok := h.clients[client]; !ok {
+			return
+		}
+		h.sendTo(client, event)
+	}
+}
@@ -0,0 +1,74 @@
+package ws
+
+import (
+	"slices"
+	r "socialAPI/internal/storage/repository"
+)
+
+// Данные события notification: о новом сообщении уведомляются участники, не заглушившие чат,
+// и упомянутые в нём пользователи, даже если чат заглушён. Mention - получатель упомянут.
+// Событие адресовано пользователям, поэтому курсор переподключения не сдвигает
+type Notification struct {
+	Message *r.MessagePreviewDTO `json:"message"`
+	Mention bool                 `json:"mention,omitempty"`
+}
+
+// Рассылка уведомления о сохранённом сообщении всем сессиям получателей и упомянутых, кроме отправителя
+func (h *hub) publishNotification(msg Message, userIDs []uint) {
+	mentioned := slices.DeleteFunc(slices.Clone(msg.mentioned), func(id uint) bool { return id == msg.SenderID })
+	isMentioned := make(map[uint]bool, len(mentioned))
+	for _, id := range mentioned {
+		isMentioned[id] = true
+	}
+	userIDs = slices.DeleteFunc(slices.Clone(userIDs), func(id uint) bool { return id == msg.SenderID || isMentioned[id] })
+
+	h.notify(msg, userIDs, false)
+	h.notify(msg, mentioned, true)
+}
+
+func (h *hub) notify(msg Message, userIDs []uint, mention bool) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	record := r.Message{ID: msg.ID, SenderID: msg.SenderID, Content: msg.Content}
+	event, err := NewEvent(EventNotification, msg.ChatID, Notification{Message: record.ConvertToPreviewDTO(), Mention: mention})
+	if err != nil {
+		h.logger.Errorw("Error encoding notification event", "messageID", msg.ID, "chatID", msg.ChatID, "error", err)
+		return
+	}
+	event.UserIDs = userIDs
+
+	if err := h.backplane.Publish(event); err != nil {
+		h.logger.Errorw("Error publishing notification",
+			"messageID", msg.ID,
+			"chatID", msg.ChatID,
+			"error", err)
+	}
+}
+
+// Рассылка сообщений пользователей, сохранённых в обход сокета, например отложенных.
+// Как и сообщения из сокета, сдвигают курсор переподключения и сопровождаются уведомлениями
+func (h *hub) PublishSaved(records []r.Message) {
+	if len(records) == 0 {
+		return
+	}
+
+	chatIDs := make([]uint, 0, len(records))
+	for _, record := range records {
+		if !slices.Contains(chatIDs, record.ChatID) {
+			chatIDs = append(chatIDs, record.ChatID)
+		}
+	}
+
+	recipients, err := h.chatRepo.GetNotifiableUserIDs(chatIDs)
+	if err != nil {
+		h.logger.Errorw("Error loading notification recipients", "chatIDs", chatIDs, "error", err)
+	}
+
+	for _, record := range records {
+		msg := messageFromRecord(record)
+		h.publishMessage(msg)
+		h.publishNotification(msg, recipients[msg.ChatID])
+	}
+}
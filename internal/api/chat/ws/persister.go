@@ -0,0 +1,253 @@
+package ws
+
+import (
+	"socialAPI/internal/setting/cfg"
+	r "socialAPI/internal/storage/repository"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Пачка сохраняется до persistAttempts раз с удваивающейся паузой, затем сообщения
+// сохраняются по одному, чтобы ошибка в одном из них не уносила с собой остальные
+const (
+	persistAttempts     = 3
+	persistRetryBackoff = 50 * time.Millisecond
+)
+
+// Пул воркеров, сохраняющих сообщения пачками вне цикла хаба.
+// Сообщения одного чата всегда попадают в одну очередь, поэтому порядок внутри чата сохраняется.
+type persister struct {
+	queues      []chan Message
+	batchSize   int
+	messageRepo r.MessageRepository
+	chatRepo    r.ChatRepository
+	deliver     func(Message)
+	notify      func(Message, []uint)
+	logger      *zap.SugaredLogger
+}
+
+func newPersister(cfg cfg.HubConfig, messageRepo r.MessageRepository, chatRepo r.ChatRepository, deliver func(Message), notify func(Message, []uint), logger *zap.SugaredLogger) *persister {
+	workers := max(cfg.PersistWorkers, 1)
+
+	queues := make([]chan Message, workers)
+	for i := range queues {
+		queues[i] = make(chan Message, max(cfg.PersistQueueSize, 1))
+	}
+
+	return &persister{
+		queues:      queues,
+		batchSize:   max(cfg.PersistBatchSize, 1),
+		messageRepo: messageRepo,
+		chatRepo:    chatRepo,
+		deliver:     deliver,
+		notify:      notify,
+		logger:      logger,
+	}
+}
+
+// Запуск воркеров
+func (p *persister) start() {
+	for _, queue := range p.queues {
+		go p.work(queue)
+	}
+}
+
+// Постановка сообщения в очередь его чата
+func (p *persister) enqueue(msg Message) {
+	p.queues[msg.ChatID%uint(len(p.queues))] <- msg
+}
+
+// Воркер ждёт первое сообщение, затем забирает всё, что уже накопилось в очереди (не больше batchSize),
+// и сохраняет это одной пачкой. Под нагрузкой пачки растут сами, а в простое задержки нет.
+func (p *persister) work(queue <-chan Message) {
+	batch := make([]Message, 0, p.batchSize)
+
+	for msg := range queue {
+		batch = append(batch, msg)
+
+	drain:
+		for len(batch) < p.batchSize {
+			select {
+			case next, ok := <-queue:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, next)
+			default:
+				break drain
+			}
+		}
+
+		p.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+func (p *persister) flush(batch []Message) {
+	chatIDs := make([]uint, 0, len(batch))
+	senders := make([]r.ChatSender, 0, len(batch))
+	seen := make(map[uint]bool)
+	for _, msg := range batch {
+		if !seen[msg.ChatID] {
+			seen[msg.ChatID] = true
+			chatIDs = append(chatIDs, msg.ChatID)
+		}
+		senders = append(senders, r.ChatSender{ChatID: msg.ChatID, SenderID: msg.SenderID})
+	}
+
+	// Отправитель мог покинуть чат или чат мог уйти в архив, пока сообщение ждало в очереди
+	active, err := p.chatRepo.FilterActiveSenders(senders)
+	if err != nil {
+		p.logger.Errorw("Error checking chat membership",
+			"chatIDs", chatIDs,
+			"error", err)
+		return
+	}
+
+	parents, err := p.loadReplyParents(batch)
+	if err != nil {
+		p.logger.Errorw("Error loading replied messages",
+			"chatIDs", chatIDs,
+			"error", err)
+		return
+	}
+
+	accepted := make([]Message, 0, len(batch))
+	for _, msg := range batch {
+		if !active[r.ChatSender{ChatID: msg.ChatID, SenderID: msg.SenderID}] {
+			p.logger.Warnw("Sender is not a member of the chat",
+				"chatID", msg.ChatID,
+				"senderID", msg.SenderID)
+			continue
+		}
+
+		if msg.ReplyToID != nil {
+			parent, ok := parents[*msg.ReplyToID]
+			if !ok || parent.ChatID != msg.ChatID {
+				p.logger.Warnw("Replied message is not in the same chat",
+					"chatID", msg.ChatID,
+					"replyToID", *msg.ReplyToID)
+				continue
+			}
+			msg.ReplyTo = parent.ConvertToPreviewDTO()
+		}
+
+		msg.Kind = r.MessageKindText
+		accepted = append(accepted, msg)
+	}
+
+	if len(accepted) == 0 {
+		return
+	}
+
+	accepted, records := p.save(accepted, chatIDs)
+	if len(records) == 0 {
+		return
+	}
+
+	// Без получателей уведомлений сообщения всё равно доставляются, теряются только уведомления
+	recipients, err := p.chatRepo.GetNotifiableUserIDs(chatIDs)
+	if err != nil {
+		p.logger.Errorw("Error loading notification recipients",
+			"chatIDs", chatIDs,
+			"error", err)
+	}
+
+	for i, record := range records {
+		msg := accepted[i]
+		msg.ID = record.ID
+		msg.CreatedAt = record.CreatedAt
+		msg.ExpiresAt = record.ExpiresAt
+		// Чужие и уже привязанные вложения репозиторий пропускает, клиент получает только привязанные
+		msg.Attachments = r.ConvertAttachmentsToDTO(record.Attachments)
+		msg.AttachmentIDs = nil
+		for _, attachment := range record.Attachments {
+			msg.AttachmentIDs = append(msg.AttachmentIDs, attachment.ID)
+		}
+		// Упоминания не участников репозиторий отбрасывает, клиент получает только проверенные
+		msg.MentionIDs = nil
+		msg.Mentions, msg.MentionsAll, msg.mentioned = record.Mentions, record.MentionsAll, record.Mentioned
+		p.deliver(msg)
+		p.notify(msg, recipients[msg.ChatID])
+	}
+}
+
+// save сохраняет сообщения и возвращает сохранённые вместе с их записями. Неудачная попытка
+// откатывается целиком, но репозиторий успевает заполнить поля записей, поэтому каждая попытка
+// начинается с новых записей
+func (p *persister) save(accepted []Message, chatIDs []uint) ([]Message, []*r.Message) {
+	backoff := persistRetryBackoff
+	for attempt := 1; ; attempt++ {
+		records := make([]*r.Message, len(accepted))
+		for i, msg := range accepted {
+			records[i] = newRecord(msg)
+		}
+
+		err := p.messageRepo.CreateBatch(records)
+		if err == nil {
+			return accepted, records
+		}
+
+		p.logger.Errorw("Error creating messages",
+			"chatIDs", chatIDs,
+			"count", len(records),
+			"attempt", attempt,
+			"error", err)
+
+		if attempt == persistAttempts {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+
+	if len(accepted) == 1 {
+		return nil, nil
+	}
+
+	saved := make([]Message, 0, len(accepted))
+	records := make([]*r.Message, 0, len(accepted))
+	for _, msg := range accepted {
+		record := newRecord(msg)
+		if err := p.messageRepo.CreateBatch([]*r.Message{record}); err != nil {
+			p.logger.Errorw("Dropping message that could not be saved",
+				"chatID", msg.ChatID,
+				"senderID", msg.SenderID,
+				"error", err)
+			continue
+		}
+		saved = append(saved, msg)
+		records = append(records, record)
+	}
+	return saved, records
+}
+
+func newRecord(msg Message) *r.Message {
+	return &r.Message{ChatID: msg.ChatID, SenderID: msg.SenderID, Content: msg.Content, Kind: msg.Kind, ReplyToID: msg.ReplyToID, AttachmentIDs: msg.AttachmentIDs, MentionIDs: msg.MentionIDs}
+}
+
+// Цитируемые сообщения пачки по ID. Запрос выполняется, только если в пачке есть ответы
+func (p *persister) loadReplyParents(batch []Message) (map[uint]*r.Message, error) {
+	var parentIDs []uint
+	for _, msg := range batch {
+		if msg.ReplyToID != nil {
+			parentIDs = append(parentIDs, *msg.ReplyToID)
+		}
+	}
+
+	parents := make(map[uint]*r.Message, len(parentIDs))
+	if len(parentIDs) == 0 {
+		return parents, nil
+	}
+
+	records, err := p.messageRepo.GetByIDs(parentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range records {
+		parents[records[i].ID] = &records[i]
+	}
+	return parents, nil
+}
@@ -0,0 +1,79 @@
+package ws
+
+import (
+	"errors"
+	"slices"
+	"socialAPI/internal/setting/cfg"
+	r "socialAPI/internal/storage/repository"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// flakyMessageRepo отказывает в первых failures сохранениях и в любой пачке с текстом poison
+type flakyMessageRepo struct {
+	savedMessageRepo
+	failures int
+	poison   string
+	calls    int
+}
+
+func (repo *flakyMessageRepo) CreateBatch(messages []*r.Message) error {
+	repo.calls++
+	if repo.failures > 0 {
+		repo.failures--
+		return errors.New("connection reset")
+	}
+	if slices.ContainsFunc(messages, func(msg *r.Message) bool { return msg.Content == repo.poison }) {
+		return errors.New("invalid message")
+	}
+	return repo.savedMessageRepo.CreateBatch(messages)
+}
+
+func TestPersister_RetriesFailedBatches(t *testing.T) {
+	const chatID = uint(11)
+
+	batch := []Message{
+		{IncomingMessage: IncomingMessage{ChatID: chatID, Content: "first"}, SenderID: 1},
+		{IncomingMessage: IncomingMessage{ChatID: chatID, Content: "poison"}, SenderID: 1},
+		{IncomingMessage: IncomingMessage{ChatID: chatID, Content: "second"}, SenderID: 2},
+	}
+
+	tests := []struct {
+		name      string
+		repo      *flakyMessageRepo
+		wantSaved []string
+		wantCalls int
+	}{
+		{
+			name:      "transient error is retried",
+			repo:      &flakyMessageRepo{failures: 2},
+			wantSaved: []string{"first", "poison", "second"},
+			wantCalls: 3,
+		},
+		{
+			name:      "failing message does not drop the rest",
+			repo:      &flakyMessageRepo{poison: "poison"},
+			wantSaved: []string{"first", "second"},
+			wantCalls: persistAttempts + len(batch),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var delivered []string
+			deliver := func(msg Message) {
+				assert.NotZero(t, msg.ID)
+				delivered = append(delivered, msg.Content)
+			}
+			p := newPersister(cfg.HubConfig{}, tt.repo, notifyChatRepo{}, deliver, func(Message, []uint) {}, zap.NewNop().Sugar())
+
+			p.flush(slices.Clone(batch))
+
+			assert.Equal(t, tt.wantSaved, tt.repo.contents())
+			assert.Equal(t, tt.wantSaved, delivered)
+			assert.Equal(t, tt.wantCalls, tt.repo.calls)
+		})
+	}
+}
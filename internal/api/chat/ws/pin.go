@@ -0,0 +1,28 @@
+package ws
+
+import (
+	r "socialAPI/internal/storage/repository"
+	"time"
+)
+
+// Данные событий pinned и unpinned. UserID - кто закрепил или открепил сообщение,
+// Message и PinnedAt передаются только при закреплении
+type Pin struct {
+	MessageID uint                 `json:"message_id"`
+	UserID    uint                 `json:"user_id"`
+	PinnedAt  *time.Time           `json:"pinned_at,omitempty"`
+	Message   *r.MessagePreviewDTO `json:"message,omitempty"`
+}
+
+// Рассылка закрепления или открепления всем сессиям участников чата, включая сессии автора
+func (h *hub) PublishPin(eventType EventType, chatID uint, pin Pin) {
+	event, err := NewEvent(eventType, chatID, pin)
+	if err != nil {
+		h.logger.Errorw("Error encoding pin event", "type", eventType, "chatID", chatID, "error", err)
+		return
+	}
+
+	if err := h.backplane.Publish(event); err != nil {
+		h.logger.Errorw("Error publishing pin event", "type", eventType, "chatID", chatID, "messageID", pin.MessageID, "error", err)
+	}
+}
@@ -0,0 +1,47 @@
+package ws
+
+import (
+	"errors"
+	r "socialAPI/internal/storage/repository"
+)
+
+var ErrNotAPoll = errors.New("message is not a poll")
+
+// Данные события poll_updated. Опрос передаётся без отметок voted, их клиент берёт из своих голосов
+type PollUpdate struct {
+	MessageID uint       `json:"message_id"`
+	Poll      *r.PollDTO `json:"poll"`
+}
+
+// Голосование из кадра или REST: голоса пользователя заменяются на optionIDs, пустой список снимает голоса.
+// Выполняется в горутине вызывающего. Возвращает опрос с точки зрения проголосовавшего
+func (h *hub) Vote(userID, chatID, messageID uint, optionIDs []uint) (*r.PollDTO, error) {
+	message, err := h.memberMessage(userID, chatID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.Kind != r.MessageKindPoll {
+		return nil, ErrNotAPoll
+	}
+
+	poll, err := h.messageRepo.Vote(messageID, userID, optionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	h.PublishPoll(chatID, messageID, poll)
+	return poll.ConvertToDTO(userID), nil
+}
+
+// Рассылка нового состояния опроса всем сессиям участников чата, включая сессии проголосовавшего
+func (h *hub) PublishPoll(chatID, messageID uint, poll *r.Poll) {
+	event, err := NewEvent(EventPollUpdated, chatID, PollUpdate{MessageID: messageID, Poll: poll.ConvertToDTO(0)})
+	if err != nil {
+		h.logger.Errorw("Error encoding poll event", "chatID", chatID, "messageID", messageID, "error", err)
+		return
+	}
+
+	if err := h.backplane.Publish(event); err != nil {
+		h.logger.Errorw("Error publishing poll event", "chatID", chatID, "messageID", messageID, "error", err)
+	}
+}
@@ -0,0 +1,170 @@
+package ws
+
+import (
+	"socialAPI/internal/storage/cache"
+	r "socialAPI/internal/storage/repository"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Presence отслеживает, есть ли у пользователя живые соединения на каком-либо узле.
+// При переходе онлайн/офлайн друзья и собеседники пользователя получают событие presence
+type Presence interface {
+	Run()
+	Connected(userID uint, connID string)
+	Disconnected(userID uint, connID string)
+	Refresh(conns map[string]uint)
+}
+
+// Данные события presence
+type PresenceState struct {
+	UserID     uint       `json:"user_id"`
+	Online     bool       `json:"online"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+}
+
+type presenceUpdate struct {
+	connected bool
+	userID    uint
+	connID    string
+}
+
+// Обращения к хранилищу и базе идут в отдельной горутине, цикл хаба только ставит обновления в очередь
+// и никогда не ждёт. Переходы онлайн/офлайн не теряются: каждое соединение даёт не больше двух записей,
+// поэтому очередь ограничена числом соединений. Продления сводятся к последнему снимку
+type presence struct {
+	store     cache.PresenceStore
+	userRepo  r.UserRepository
+	backplane Backplane
+	mu        sync.Mutex
+	pending   []presenceUpdate
+	refresh   map[string]uint // connID -> userID, последний снимок локальных соединений
+	wake      chan struct{}
+	logger    *zap.SugaredLogger
+}
+
+func NewPresence(store cache.PresenceStore, userRepo r.UserRepository, backplane Backplane, logger *zap.SugaredLogger) Presence {
+	return &presence{
+		store:     store,
+		userRepo:  userRepo,
+		backplane: backplane,
+		wake:      make(chan struct{}, 1),
+		logger:    logger,
+	}
+}
+
+func (p *presence) Connected(userID uint, connID string) {
+	p.mu.Lock()
+	p.pending = append(p.pending, presenceUpdate{connected: true, userID: userID, connID: connID})
+	p.mu.Unlock()
+	p.signal()
+}
+
+// Закрытое соединение убирается из ожидающего снимка, иначе продление вернуло бы его в хранилище
+func (p *presence) Disconnected(userID uint, connID string) {
+	p.mu.Lock()
+	p.pending = append(p.pending, presenceUpdate{userID: userID, connID: connID})
+	delete(p.refresh, connID)
+	p.mu.Unlock()
+	p.signal()
+}
+
+// Продление всех локальных соединений. Новый снимок заменяет ещё не обработанный
+func (p *presence) Refresh(conns map[string]uint) {
+	p.mu.Lock()
+	p.refresh = conns
+	p.mu.Unlock()
+	p.signal()
+}
+
+func (p *presence) signal() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (p *presence) Run() {
+	for range p.wake {
+		p.mu.Lock()
+		updates, conns := p.pending, p.refresh
+		p.pending, p.refresh = nil, nil
+		p.mu.Unlock()
+
+		for _, update := range updates {
+			if update.connected {
+				p.connect(update.userID, update.connID)
+			} else {
+				p.disconnect(update.userID, update.connID)
+			}
+		}
+
+		for connID, userID := range conns {
+			if err := p.store.Refresh(userID, connID); err != nil {
+				p.logger.Errorw("Error refreshing connection in presence store", "userID", userID, "error", err)
+			}
+		}
+	}
+}
+
+func (p *presence) connect(userID uint, connID string) {
+	online, err := p.store.Connect(userID, connID)
+	if err != nil {
+		p.logger.Errorw("Error registering connection in presence store", "userID", userID, "error", err)
+		return
+	}
+	if online {
+		p.notify(PresenceState{UserID: userID, Online: true})
+	}
+}
+
+func (p *presence) disconnect(userID uint, connID string) {
+	offline, err := p.store.Disconnect(userID, connID)
+	if err != nil {
+		p.logger.Errorw("Error removing connection from presence store", "userID", userID, "error", err)
+		return
+	}
+	if !offline {
+		return
+	}
+
+	now := time.Now()
+	if err := p.userRepo.SetLastSeen(userID, now); err != nil {
+		p.logger.Errorw("Error saving last seen time", "userID", userID, "error", err)
+	}
+	p.notify(PresenceState{UserID: userID, Online: false, LastSeenAt: &now})
+}
+
+// Рассылка presence друзьям и собеседникам, если пользователь не скрыл присутствие
+func (p *presence) notify(state PresenceState) {
+	users, err := p.userRepo.GetPresence([]uint{state.UserID})
+	if err != nil {
+		p.logger.Errorw("Error loading presence settings", "userID", state.UserID, "error", err)
+		return
+	}
+	if len(users) == 0 || users[0].HidePresence {
+		return
+	}
+
+	contacts, err := p.userRepo.GetContactIDs(state.UserID)
+	if err != nil {
+		p.logger.Errorw("Error loading contacts", "userID", state.UserID, "error", err)
+		return
+	}
+	if len(contacts) == 0 {
+		return
+	}
+
+	event, err := NewEvent(EventPresence, 0, state)
+	if err != nil {
+		p.logger.Errorw("Error encoding presence event", "userID", state.UserID, "error", err)
+		return
+	}
+	event.UserIDs = contacts
+
+	if err := p.backplane.Publish(event); err != nil {
+		p.logger.Errorw("Error publishing presence event", "userID", state.UserID, "error", err)
+	}
+}
@@ -0,0 +1,139 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"socialAPI/internal/storage/cache"
+	r "socialAPI/internal/storage/repository"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type presenceUserRepo struct {
+	r.UserRepository
+	hidden   bool
+	mu       sync.Mutex
+	lastSeen map[uint]time.Time
+}
+
+func (repo *presenceUserRepo) GetPresence(IDs []uint) ([]r.User, error) {
+	return []r.User{{ID: IDs[0], HidePresence: repo.hidden}}, nil
+}
+
+func (repo *presenceUserRepo) GetContactIDs(userID uint) ([]uint, error) {
+	return []uint{2, 3}, nil
+}
+
+func (repo *presenceUserRepo) SetLastSeen(userID uint, at time.Time) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.lastSeen[userID] = at
+	return nil
+}
+
+func TestPresence_Transitions(t *testing.T) {
+	tests := []struct {
+		name       string
+		hidden     bool
+		wantEvents []bool // значения Online в разосланных событиях
+	}{
+		{
+			name:       "contacts are notified when the first connection opens and the last one closes",
+			wantEvents: []bool{true, false},
+		},
+		{
+			name:       "hidden user is not announced",
+			hidden:     true,
+			wantEvents: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &presenceUserRepo{hidden: tt.hidden, lastSeen: make(map[uint]time.Time)}
+			backplane := &memoryBackplane{events: make(chan Event, 8)}
+			p := NewPresence(cache.NewMemoryPresenceStore(time.Minute), repo, backplane, zap.NewNop().Sugar())
+			go p.Run()
+
+			// Два устройства одного пользователя: события только на первом подключении и последнем отключении
+			p.Connected(1, "node:1")
+			p.Connected(1, "node:2")
+			p.Disconnected(1, "node:1")
+			p.Disconnected(1, "node:2")
+
+			assert.Eventually(t, func() bool {
+				repo.mu.Lock()
+				defer repo.mu.Unlock()
+				return !repo.lastSeen[1].IsZero() && len(backplane.events) == len(tt.wantEvents)
+			}, time.Second, 5*time.Millisecond)
+
+			var got []bool
+			for len(backplane.events) > 0 {
+				event := <-backplane.events
+				assert.Equal(t, EventPresence, event.Type)
+				assert.Equal(t, []uint{2, 3}, event.UserIDs)
+
+				var state PresenceState
+				require.NoError(t, json.Unmarshal(event.Data, &state))
+				assert.Equal(t, uint(1), state.UserID)
+				if !state.Online {
+					assert.NotNil(t, state.LastSeenAt)
+				}
+				got = append(got, state.Online)
+			}
+			assert.Equal(t, tt.wantEvents, got)
+		})
+	}
+}
+
+func TestPresence_KeepsOfflineTransitionsUnderLoad(t *testing.T) {
+	const users = 3000
+
+	repo := &presenceUserRepo{hidden: true, lastSeen: make(map[uint]time.Time)}
+	store := cache.NewMemoryPresenceStore(time.Minute)
+	p := NewPresence(store, repo, NewMemoryBackplane(), zap.NewNop().Sugar())
+
+	// Обновления копятся, пока горутина присутствия не запущена: ни одно отключение не теряется
+	for id := uint(1); id <= users; id++ {
+		connID := fmt.Sprintf("node:%d", id)
+		p.Connected(id, connID)
+		p.Refresh(map[string]uint{connID: id})
+		p.Disconnected(id, connID)
+	}
+	go p.Run()
+
+	assert.Eventually(t, func() bool {
+		repo.mu.Lock()
+		defer repo.mu.Unlock()
+		return len(repo.lastSeen) == users
+	}, 5*time.Second, 5*time.Millisecond)
+
+	// Снимок продления, полученный до отключения, не возвращает соединение в хранилище
+	online, err := store.Online([]uint{users})
+	require.NoError(t, err)
+	assert.False(t, online[users])
+}
+
+func TestMemoryPresenceStore_ExpiresStaleConnections(t *testing.T) {
+	store := cache.NewMemoryPresenceStore(20 * time.Millisecond)
+
+	online, err := store.Connect(1, "node:1")
+	require.NoError(t, err)
+	assert.True(t, online)
+
+	time.Sleep(30 * time.Millisecond)
+
+	state, err := store.Online([]uint{1})
+	require.NoError(t, err)
+	assert.False(t, state[1])
+
+	// Соединение упавшего узла истекло, поэтому новое снова делает пользователя онлайн
+	online, err = store.Connect(1, "node:2")
+	require.NoError(t, err)
+	assert.True(t, online)
+}
@@ -0,0 +1,92 @@
+package ws
+
+import (
+	"errors"
+	r "socialAPI/internal/storage/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrReactionNotAllowed = errors.New("reaction is not allowed")
+	ErrNotChatMember      = errors.New("user is not a member of the chat")
+	ErrMessageNotFound    = errors.New("message not found")
+)
+
+// Данные событий reaction_added и reaction_removed. Count - число реакций этим эмодзи после изменения
+type Reaction struct {
+	MessageID uint   `json:"message_id"`
+	UserID    uint   `json:"user_id"`
+	Emoji     string `json:"emoji"`
+	Count     int64  `json:"count"`
+}
+
+// Добавление или снятие реакции из кадра или REST. Выполняется в горутине вызывающего, не в цикле хаба.
+// Событие получают все сессии участников чата, включая сессии автора, если набор реакций изменился
+func (h *hub) React(userID, chatID, messageID uint, emoji string, add bool) (*Reaction, error) {
+	if !h.reactions[emoji] {
+		return nil, ErrReactionNotAllowed
+	}
+
+	_, err := h.memberMessage(userID, chatID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	changed, eventType := false, EventReactionRemoved
+	if add {
+		eventType = EventReactionAdded
+		changed, err = h.messageRepo.AddReaction(messageID, userID, emoji)
+	} else {
+		changed, err = h.messageRepo.RemoveReaction(messageID, userID, emoji)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := h.messageRepo.CountReactions(messageID, emoji)
+	if err != nil {
+		return nil, err
+	}
+
+	reaction := &Reaction{MessageID: messageID, UserID: userID, Emoji: emoji, Count: count}
+	if !changed {
+		return reaction, nil
+	}
+
+	event, err := NewEvent(eventType, chatID, reaction)
+	if err != nil {
+		h.logger.Errorw("Error encoding reaction event", "chatID", chatID, "error", err)
+		return reaction, nil
+	}
+
+	if err := h.backplane.Publish(event); err != nil {
+		h.logger.Errorw("Error publishing reaction event", "chatID", chatID, "messageID", messageID, "error", err)
+	}
+
+	return reaction, nil
+}
+
+// memberMessage возвращает неудалённое сообщение чата, если пользователь - участник этого чата
+func (h *hub) memberMessage(userID, chatID, messageID uint) (*r.Message, error) {
+	isMember, err := h.chatRepo.IsMember(chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotChatMember
+	}
+
+	message, err := h.messageRepo.GetByID(messageID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if message.ChatID != chatID || message.DeletedAt != nil {
+		return nil, ErrMessageNotFound
+	}
+
+	return message, nil
+}
@@ -0,0 +1,29 @@
+package ws
+
+// Данные события read: участник прочитал чат до MessageID включительно
+type ReadReceipt struct {
+	UserID    uint `json:"user_id"`
+	MessageID uint `json:"message_id"`
+}
+
+// Отметка о прочтении из кадра read или REST. Выполняется в горутине вызывающего, не в цикле хаба.
+// Остальные участники чата получают событие read, только если позиция прочтения сдвинулась
+func (h *hub) MarkRead(userID, chatID, messageID uint) (bool, error) {
+	advanced, err := h.chatRepo.MarkRead(chatID, userID, messageID)
+	if err != nil || !advanced {
+		return advanced, err
+	}
+
+	event, err := NewEvent(EventRead, chatID, ReadReceipt{UserID: userID, MessageID: messageID})
+	if err != nil {
+		h.logger.Errorw("Error encoding read event", "chatID", chatID, "error", err)
+		return true, nil
+	}
+	event.SenderID = userID
+
+	if err := h.backplane.Publish(event); err != nil {
+		h.logger.Errorw("Error publishing read event", "chatID", chatID, "userID", userID, "error", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,68 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+// Реализация для нескольких узлов: каждое событие публикуется в канал своего чата
+type redisBackplane struct {
+	client *redis.Client
+	pubsub *redis.PubSub
+	logger *zap.SugaredLogger
+}
+
+func NewRedisBackplane(client *redis.Client, logger *zap.SugaredLogger) Backplane {
+	return &redisBackplane{
+		client: client,
+		pubsub: client.Subscribe(context.Background(), usersChannel),
+		logger: logger,
+	}
+}
+
+// Общий канал событий, адресованных пользователям. На него подписан каждый узел
+const usersChannel = "users:events"
+
+func chatChannel(chatID uint) string {
+	return fmt.Sprintf("chat:%d:events", chatID)
+}
+
+func eventChannel(event Event) string {
+	if len(event.UserIDs) > 0 {
+		return usersChannel
+	}
+	return chatChannel(event.ChatID)
+}
+
+func (b *redisBackplane) Publish(event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return b.client.Publish(context.Background(), eventChannel(event), payload).Err()
+}
+
+func (b *redisBackplane) Subscribe(chatID uint) error {
+	return b.pubsub.Subscribe(context.Background(), chatChannel(chatID))
+}
+
+func (b *redisBackplane) Unsubscribe(chatID uint) error {
+	return b.pubsub.Unsubscribe(context.Background(), chatChannel(chatID))
+}
+
+func (b *redisBackplane) Listen(deliver func(Event)) {
+	for redisMsg := range b.pubsub.Channel() {
+		var event Event
+		if err := json.Unmarshal([]byte(redisMsg.Payload), &event); err != nil {
+			b.logger.Errorw("Error unmarshalling backplane event", "channel", redisMsg.Channel, "error", err)
+			continue
+		}
+
+		deliver(event)
+	}
+}
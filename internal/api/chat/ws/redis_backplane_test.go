@@ -0,0 +1,224 @@
+//go:build integration
+
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"socialAPI/internal/setting/cfg"
+	r "socialAPI/internal/storage/repository"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type memoryChatRepo struct {
+	r.ChatRepository
+}
+
+func (memoryChatRepo) FilterActiveSenders(senders []r.ChatSender) (map[r.ChatSender]bool, error) {
+	return allActive(senders), nil
+}
+
+func (memoryChatRepo) GetNotifiableUserIDs(chatIDs []uint) (map[uint][]uint, error) {
+	return nil, nil
+}
+
+// messageLog - общая для узлов "база" сообщений
+type messageLog struct {
+	mu      sync.Mutex
+	lastID  uint
+	records []r.Message
+}
+
+func (l *messageLog) add(msg *r.Message) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastID++
+	msg.ID, msg.CreatedAt = l.lastID, time.Now()
+	l.records = append(l.records, *msg)
+}
+
+type memoryMessageRepo struct {
+	r.MessageRepository
+	log *messageLog
+}
+
+func (m memoryMessageRepo) CreateBatch(messages []*r.Message) error {
+	for _, msg := range messages {
+		m.log.add(msg)
+	}
+	return nil
+}
+
+func (m memoryMessageRepo) ListAfter(cursors map[uint]uint, limit int) ([]r.Message, error) {
+	m.log.mu.Lock()
+	defer m.log.mu.Unlock()
+
+	var messages []r.Message
+	counts := make(map[uint]int)
+	for _, record := range m.log.records {
+		cursor, ok := cursors[record.ChatID]
+		if ok && record.ID > cursor && counts[record.ChatID] < limit {
+			counts[record.ChatID]++
+			messages = append(messages, record)
+		}
+	}
+	return messages, nil
+}
+
+func newTestRedisClient(t *testing.T) *redis.Client {
+	host, port := os.Getenv("REDIS_HOST"), os.Getenv("REDIS_PORT")
+	if host == "" {
+		host = "localhost"
+	}
+	if port == "" {
+		port = "6379"
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: host + ":" + port, Password: os.Getenv("REDIS_PASSWORD")})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Skipf("redis is not available: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+// Узел: хаб с Redis backplane и HTTP-сервером, регистрирующим клиентов в заданных чатах
+func newTestNode(t *testing.T, log *messageLog) (Hub, *httptest.Server) {
+	logger := zap.NewNop().Sugar()
+	h := NewHub(memoryMessageRepo{log: log}, memoryChatRepo{}, NewRedisBackplane(newTestRedisClient(t), logger), nopPresence{}, cfg.HubConfig{}, logger)
+	go h.Run()
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := upgrader.Upgrade(w, req, nil)
+		if err != nil {
+			return
+		}
+
+		var userID, chatID uint
+		fmt.Sscan(req.URL.Query().Get("user"), &userID)
+		fmt.Sscan(req.URL.Query().Get("chat"), &chatID)
+
+		h.RegisterClient(NewClient(conn, make(chan Event, 256), h, userID, map[uint]bool{chatID: true}, nil, Session{}, logger))
+	}))
+	t.Cleanup(server.Close)
+
+	return h, server
+}
+
+func dial(t *testing.T, server *httptest.Server, userID, chatID uint) *websocket.Conn {
+	url := fmt.Sprintf("ws%s?user=%d&chat=%d", strings.TrimPrefix(server.URL, "http"), userID, chatID)
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+// Ждём, пока узлы подпишутся на канал чата
+func waitSubscribers(t *testing.T, chatID uint, want int64) {
+	client := newTestRedisClient(t)
+	require.Eventually(t, func() bool {
+		counts, err := client.PubSubNumSub(context.Background(), chatChannel(chatID)).Result()
+		return err == nil && counts[chatChannel(chatID)] >= want
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestRedisBackplane_DeliversAcrossHubs(t *testing.T) {
+	log := &messageLog{}
+	chatID := uint(time.Now().UnixNano() % 1_000_000)
+
+	hubA, serverA := newTestNode(t, log)
+	_, serverB := newTestNode(t, log)
+
+	connA := dial(t, serverA, 1, chatID)
+	connB := dial(t, serverB, 2, chatID)
+	outsider := dial(t, serverB, 3, chatID+1)
+	waitSubscribers(t, chatID, 2)
+
+	hubA.BroadcastMessage(Message{
+		IncomingMessage: IncomingMessage{ChatID: chatID, Content: "hello from A"},
+		SenderID:        1,
+	})
+
+	for _, conn := range []*websocket.Conn{connA, connB} {
+		var event Event
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		require.NoError(t, conn.ReadJSON(&event))
+		assert.Equal(t, EventMessage, event.Type)
+
+		var got Message
+		require.NoError(t, json.Unmarshal(event.Data, &got))
+
+		assert.Equal(t, chatID, got.ChatID)
+		assert.Equal(t, "hello from A", got.Content)
+		assert.Equal(t, uint(1), got.SenderID)
+		assert.NotZero(t, got.ID)
+	}
+
+	outsider.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+	var unexpected Event
+	assert.Error(t, outsider.ReadJSON(&unexpected))
+}
+
+func readEvent(t *testing.T, conn *websocket.Conn) Event {
+	var event Event
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	require.NoError(t, conn.ReadJSON(&event))
+	return event
+}
+
+func TestRedisBackplane_NewMemberGetsMessagesSentWhileSubscribing(t *testing.T) {
+	log := &messageLog{}
+	chatID := uint(time.Now().UnixNano() % 1_000_000)
+
+	hubA, serverA := newTestNode(t, log)
+	_, serverB := newTestNode(t, log)
+
+	dial(t, serverA, 1, chatID)
+	waitSubscribers(t, chatID, 1)
+
+	// Узел B ещё не подписан на чат: системное сообщение и первое сообщение после входа
+	// публикуются сразу за chat_joined и без догрузки до него бы не дошли
+	joiner := dial(t, serverB, 2, chatID+1)
+	time.Sleep(50 * time.Millisecond)
+
+	joined := r.Message{ChatID: chatID, SenderID: 1, Kind: r.MessageKindSystem,
+		System: &r.SystemEvent{Action: r.SystemMembersAdded, UserIDs: []uint{2}}}
+	log.add(&joined)
+	hubA.UpdateMembership(chatID, joined.ID-1, []uint{2}, nil)
+	hubA.PublishMessage(joined)
+	hubA.BroadcastMessage(Message{IncomingMessage: IncomingMessage{ChatID: chatID, Content: "welcome"}, SenderID: 1})
+
+	assert.Equal(t, EventChatJoined, readEvent(t, joiner).Type)
+
+	var got []string
+	for range 2 {
+		event := readEvent(t, joiner)
+		require.Equal(t, EventMessage, event.Type)
+
+		var msg Message
+		require.NoError(t, json.Unmarshal(event.Data, &msg))
+		got = append(got, msg.Content)
+	}
+	assert.Equal(t, []string{"", "welcome"}, got)
+
+	// Сообщения, пришедшие и живыми событиями, и догрузкой, не дублируются
+	joiner.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+	var unexpected Event
+	assert.Error(t, joiner.ReadJSON(&unexpected))
+}
@@ -0,0 +1,100 @@
+package ws
+
+import "sort"
+
+// Догрузка пропущенных сообщений при переподключении. Выполняется в отдельной горутине до запуска WritePump,
+// поэтому пишет в соединение напрямую. Чаты, где пропущено больше replayLimit сообщений,
+// не догружаются: клиент получает replay_truncated и берёт историю через REST
+func (h *hub) replay(client *Client) {
+	defer func() {
+		h.commands <- HubCommand{Type: CommandReplayDone, Client: client}
+	}()
+
+	client.replayed = make(map[uint]uint)
+
+	records, err := h.messageRepo.ListAfter(client.resume, h.replayLimit+1)
+	if err != nil {
+		h.logger.Errorw("Error loading missed messages", "clientID", client.userID, "error", err)
+		h.writeTruncated(client, mapKeys(client.resume))
+		return
+	}
+
+	counts := make(map[uint]int)
+	for _, record := range records {
+		counts[record.ChatID]++
+	}
+
+	var truncated []uint
+	for chatID, count := range counts {
+		if count > h.replayLimit {
+			truncated = append(truncated, chatID)
+		}
+	}
+
+	for _, record := range records {
+		if counts[record.ChatID] > h.replayLimit {
+			continue
+		}
+
+		event, err := newMessageEvent(messageFromRecord(record))
+		if err != nil {
+			h.logger.Errorw("Error encoding message event", "messageID", record.ID, "error", err)
+			continue
+		}
+
+		if err := client.writeEvent(event); err != nil {
+			h.logger.Errorw("Error replaying message", "clientID", client.userID, "messageID", record.ID, "error", err)
+			return
+		}
+		client.replayed[record.ChatID] = record.ID
+	}
+
+	if len(truncated) > 0 {
+		h.writeTruncated(client, truncated)
+	}
+
+	h.logger.Infow("Missed messages replayed", "clientID", client.userID, "count", len(records), "truncatedChats", truncated)
+}
+
+func (h *hub) writeTruncated(client *Client, chatIDs []uint) {
+	sort.Slice(chatIDs, func(i, j int) bool { return chatIDs[i] < chatIDs[j] })
+
+	event, err := NewEvent(EventReplayTruncated, 0, ReplayTruncated{ChatIDs: chatIDs, Limit: h.replayLimit})
+	if err != nil {
+		h.logger.Errorw("Error encoding replay_truncated event", "error", err)
+		return
+	}
+
+	if err := client.writeEvent(event); err != nil {
+		h.logger.Errorw("Error sending replay_truncated event", "clientID", client.userID, "error", err)
+	}
+}
+
+// Догрузка закончена: отдаём накопленные живые события без дублей и запускаем обычную работу клиента
+func (h *hub) handleReplayDone(client *Client) {
+	if _, ok := h.clients[client]; !ok {
+		client.conn.Close()
+		return
+	}
+
+	pending := client.pending
+	client.pending = nil
+	client.replaying = false
+
+	for _, event := range pending {
+		if event.MessageID != 0 && event.MessageID <= client.replayed[event.ChatID] {
+			continue
+		}
+		h.sendTo(client, event)
+	}
+
+	client.start()
+}
+
+func mapKeys(m map[uint]uint) []uint {
+	keys := make([]uint, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,19 @@
+package ws
+
+// Данные события message_expired: сообщения удалены по сроку хранения и больше недоступны
+type Expired struct {
+	MessageIDs []uint `json:"message_ids"`
+}
+
+// Рассылка удалённых по сроку хранения сообщений участникам чата
+func (h *hub) PublishExpired(chatID uint, messageIDs []uint) {
+	event, err := NewEvent(EventMessageExpired, chatID, Expired{MessageIDs: messageIDs})
+	if err != nil {
+		h.logger.Errorw("Error encoding message expired event", "chatID", chatID, "error", err)
+		return
+	}
+
+	if err := h.backplane.Publish(event); err != nil {
+		h.logger.Errorw("Error publishing expired messages", "chatID", chatID, "count", len(messageIDs), "error", err)
+	}
+}
@@ -0,0 +1,36 @@
+package ws
+
+import "time"
+
+// Коды закрытия соединения при окончании сессии
+const (
+	CloseSessionExpired = 4001
+	CloseSessionRevoked = 4003
+)
+
+// SessionValidator проверяет, что сессия пользователя не отозвана
+type SessionValidator interface {
+	IsSessionActive(userID uint) (bool, error)
+}
+
+// Session - данные авторизации, с которыми открыто соединение.
+// В ExpiresAt соединение закрывается, отзыв проверяется вместе с каждым ping
+type Session struct {
+	ExpiresAt time.Time
+	Validator SessionValidator
+}
+
+func (c *Client) sessionActive() bool {
+	if c.session.Validator == nil {
+		return true
+	}
+
+	active, err := c.session.Validator.IsSessionActive(c.userID)
+	if err != nil {
+		// Не рвём соединение из-за временной недоступности хранилища
+		c.logger.Errorw("Error checking session", "clientID", c.userID, "error", err)
+		return true
+	}
+
+	return active
+}
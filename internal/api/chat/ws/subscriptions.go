@@ -0,0 +1,113 @@
+package ws
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Пауза перед повтором неудавшейся подписки или отписки растёт от min до max
+const (
+	subscribeRetryMin = 100 * time.Millisecond
+	subscribeRetryMax = 10 * time.Second
+)
+
+// Подписки узла на каналы чатов. Цикл хаба только запоминает нужное состояние,
+// обращения к backplane идут в отдельной горутине, поэтому медленный Redis не останавливает доставку.
+// Для каждого чата хранится лишь последнее желаемое состояние: подписка и сразу отписка
+// до обработки сводятся к отсутствию изменений
+type subscriptions struct {
+	backplane    Backplane
+	onSubscribed func(chatID uint) // подписка действует, сообщения чата доходят до узла
+	mu           sync.Mutex
+	pending      map[uint]bool // chatID -> нужна ли подписка
+	applied      map[uint]bool // чаты, на которые узел подписан в backplane, только для горутины run
+	wake         chan struct{}
+	logger       *zap.SugaredLogger
+}
+
+func newSubscriptions(backplane Backplane, onSubscribed func(chatID uint), logger *zap.SugaredLogger) *subscriptions {
+	return &subscriptions{
+		backplane:    backplane,
+		onSubscribed: onSubscribed,
+		pending:      make(map[uint]bool),
+		applied:      make(map[uint]bool),
+		wake:         make(chan struct{}, 1),
+		logger:       logger,
+	}
+}
+
+// Не блокирует вызывающего: изменение подхватит горутина run
+func (s *subscriptions) set(chatID uint, subscribed bool) {
+	s.mu.Lock()
+	s.pending[chatID] = subscribed
+	s.mu.Unlock()
+	s.signal()
+}
+
+func (s *subscriptions) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Неудавшиеся изменения возвращаются в очередь и повторяются после паузы,
+// иначе узел навсегда перестал бы получать сообщения чата
+func (s *subscriptions) run() {
+	var backoff time.Duration
+	for range s.wake {
+		s.mu.Lock()
+		pending := s.pending
+		s.pending = make(map[uint]bool)
+		s.mu.Unlock()
+
+		failed := make(map[uint]bool)
+		for chatID, subscribed := range pending {
+			// Хаб ждёт подтверждения после каждой новой подписки, даже если узел так и не отписался
+			if subscribed && s.applied[chatID] {
+				s.onSubscribed(chatID)
+				continue
+			}
+			if s.applied[chatID] == subscribed {
+				continue
+			}
+
+			if subscribed {
+				if err := s.backplane.Subscribe(chatID); err != nil {
+					s.logger.Errorw("Error subscribing to chat", "chatID", chatID, "error", err)
+					failed[chatID] = true
+					continue
+				}
+				s.applied[chatID] = true
+				s.onSubscribed(chatID)
+			} else {
+				if err := s.backplane.Unsubscribe(chatID); err != nil {
+					s.logger.Errorw("Error unsubscribing from chat", "chatID", chatID, "error", err)
+					failed[chatID] = false
+					continue
+				}
+				delete(s.applied, chatID)
+			}
+		}
+
+		if len(failed) == 0 {
+			backoff = 0
+			continue
+		}
+
+		// Более свежее желаемое состояние, пришедшее за это время, важнее повтора
+		s.mu.Lock()
+		for chatID, subscribed := range failed {
+			if _, ok := s.pending[chatID]; !ok {
+				s.pending[chatID] = subscribed
+			}
+		}
+		s.mu.Unlock()
+
+		backoff = min(max(backoff*2, subscribeRetryMin), subscribeRetryMax)
+		time.Sleep(backoff)
+		s.signal()
+	}
+}
@@ -0,0 +1,62 @@
+package ws
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// flakyBackplane отказывает в первых failures подписках и отписках
+type flakyBackplane struct {
+	Backplane
+	mu       sync.Mutex
+	failures int
+	calls    []string
+}
+
+func (b *flakyBackplane) call(op string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls = append(b.calls, op)
+	if b.failures > 0 {
+		b.failures--
+		return errors.New("redis is unavailable")
+	}
+	return nil
+}
+
+func (b *flakyBackplane) Subscribe(chatID uint) error   { return b.call("subscribe") }
+func (b *flakyBackplane) Unsubscribe(chatID uint) error { return b.call("unsubscribe") }
+
+func TestSubscriptions_RetriesFailures(t *testing.T) {
+	const chatID = uint(5)
+
+	backplane := &flakyBackplane{failures: 2}
+	confirmed := make(chan uint, 1)
+	s := newSubscriptions(backplane, func(chatID uint) { confirmed <- chatID }, zap.NewNop().Sugar())
+	go s.run()
+
+	// Две неудачи подряд, затем подписка проходит и хаб получает подтверждение
+	s.set(chatID, true)
+	select {
+	case got := <-confirmed:
+		assert.Equal(t, chatID, got)
+	case <-time.After(2 * time.Second):
+		t.Fatal("subscription was not retried")
+	}
+
+	backplane.mu.Lock()
+	backplane.failures = 1
+	backplane.mu.Unlock()
+
+	s.set(chatID, false)
+	assert.Eventually(t, func() bool {
+		backplane.mu.Lock()
+		defer backplane.mu.Unlock()
+		return assert.ObjectsAreEqual([]string{"subscribe", "subscribe", "subscribe", "unsubscribe", "unsubscribe"}, backplane.calls)
+	}, 2*time.Second, 5*time.Millisecond)
+}
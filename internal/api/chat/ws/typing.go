@@ -0,0 +1,109 @@
+package ws
+
+import "time"
+
+// Индикатор набора текста. Эфемерное событие: в базу не сохраняется, участникам чата
+// рассылается через backplane. Состояние хранит хаб узла, к которому подключён автор:
+// если typing_start не обновлялся дольше typingTTL, хаб сам рассылает typing_stop
+type Typing struct {
+	UserID    uint      `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// Отметка typing_start/typing_stop от клиента. Вызывается из ReadPump
+func (h *hub) SetTyping(client *Client, chatID uint, typing bool) {
+	eventType := EventTypingStop
+	if typing {
+		eventType = EventTypingStart
+	}
+
+	h.commands <- HubCommand{Type: CommandTyping, Client: client, Event: Event{Type: eventType, ChatID: chatID}}
+}
+
+func (h *hub) handleTyping(client *Client, event Event) {
+	if _, ok := h.clients[client]; !ok {
+		return
+	}
+
+	if !client.chatIDs[event.ChatID] {
+		h.logger.Warnw("Typing event for a chat the client is not a member of", "clientID", client.userID, "chatID", event.ChatID)
+		return
+	}
+
+	if event.Type == EventTypingStop {
+		h.stopTyping(client, event.ChatID)
+		return
+	}
+
+	if h.typing[client] == nil {
+		h.typing[client] = make(map[uint]time.Time)
+	}
+	expiresAt := time.Now().Add(h.typingTTL)
+	h.typing[client][event.ChatID] = expiresAt
+
+	// Повторный typing_start тоже рассылается: получатели продлевают индикатор до нового ExpiresAt
+	h.publishTyping(EventTypingStart, client.userID, event.ChatID, expiresAt)
+}
+
+func (h *hub) stopTyping(client *Client, chatID uint) {
+	if _, ok := h.typing[client][chatID]; !ok {
+		return
+	}
+
+	delete(h.typing[client], chatID)
+	if len(h.typing[client]) == 0 {
+		delete(h.typing, client)
+	}
+
+	h.publishTyping(EventTypingStop, client.userID, chatID, time.Time{})
+}
+
+// Снимаем индикаторы, которые клиент не обновил вовремя
+func (h *hub) expireTyping(now time.Time) {
+	for client, chats := range h.typing {
+		for chatID, expiresAt := range chats {
+			if now.After(expiresAt) {
+				h.stopTyping(client, chatID)
+			}
+		}
+	}
+}
+
+func (h *hub) publishTyping(eventType EventType, userID, chatID uint, expiresAt time.Time) {
+	event, err := NewEvent(eventType, chatID, Typing{UserID: userID, ExpiresAt: expiresAt})
+	if err != nil {
+		h.logger.Errorw("Error encoding typing event", "chatID", chatID, "error", err)
+		return
+	}
+	event.SenderID = userID
+
+	// Цикл хаба не должен ждать backplane: при переполнении очереди событие отбрасывается
+	select {
+	case h.ephemeral <- event:
+	default:
+		h.logger.Warnw("Ephemeral event queue is full, dropping typing event", "chatID", chatID, "userID", userID)
+	}
+}
+
+// Публикация эфемерных событий вне цикла хаба
+func (h *hub) publishEphemeral() {
+	for event := range h.ephemeral {
+		if err := h.backplane.Publish(event); err != nil {
+			h.logger.Errorw("Error publishing typing event", "type", event.Type, "chatID", event.ChatID, "error", err)
+		}
+	}
+}
+
+// Ограничение частоты typing_start от одного клиента. Вызывается только из ReadPump
+func (c *Client) allowTyping(chatID uint) bool {
+	now := time.Now()
+	if last, ok := c.lastTyping[chatID]; ok && now.Sub(last) < c.typingThrottle {
+		return false
+	}
+
+	if c.lastTyping == nil {
+		c.lastTyping = make(map[uint]time.Time)
+	}
+	c.lastTyping[chatID] = now
+	return true
+}
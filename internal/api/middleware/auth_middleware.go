@@ -6,11 +6,15 @@ import (
 	"socialAPI/internal/lib"
 	"socialAPI/internal/shared"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
 )
 
-var UserIDKey key = "userID"
+var (
+	UserIDKey         key = "userID"
+	TokenExpiresAtKey key = "tokenExpiresAt"
+)
 
 func AuthMiddleware(tokenService shared.TokenService, logger *zap.SugaredLogger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -38,6 +42,7 @@ func AuthMiddleware(tokenService shared.TokenService, logger *zap.SugaredLogger)
 			}
 
 			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, TokenExpiresAtKey, time.Unix(claims.ExpiresAt, 0))
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"socialAPI/internal/lib"
+	"socialAPI/internal/shared"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// TicketSubprotocol - имя подпротокола, в паре с которым браузер передаёт тикет:
+// new WebSocket(url, ["ticket", "<ticket>"])
+const TicketSubprotocol = "ticket"
+
+// WebSocketAuthMiddleware принимает заголовок Authorization, как AuthMiddleware, а если его нет -
+// одноразовый тикет из query-параметра ticket или из заголовка Sec-WebSocket-Protocol
+func WebSocketAuthMiddleware(tokenService shared.TokenService, wsAuthService shared.WSAuthService, logger *zap.SugaredLogger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		bearer := AuthMiddleware(tokenService, logger)(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "" {
+				bearer.ServeHTTP(w, r)
+				return
+			}
+
+			ticket := ticketFromRequest(r)
+			if ticket == "" {
+				logger.Warnw("WebSocket credentials are missing")
+				lib.SendMessage(w, r, http.StatusUnauthorized, "Authorization header or ticket is required")
+				return
+			}
+
+			data, err := wsAuthService.RedeemTicket(ticket)
+			if err != nil {
+				logger.Warnw("Invalid WebSocket ticket", "error", err.Error())
+				lib.SendMessage(w, r, http.StatusUnauthorized, "Invalid ticket")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserIDKey, data.UserID)
+			ctx = context.WithValue(ctx, TokenExpiresAtKey, data.ExpiresAt)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func ticketFromRequest(r *http.Request) string {
+	if ticket := r.URL.Query().Get("ticket"); ticket != "" {
+		return ticket
+	}
+
+	protocols := strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",")
+	for i := 0; i+1 < len(protocols); i++ {
+		if strings.TrimSpace(protocols[i]) == TicketSubprotocol {
+			return strings.TrimSpace(protocols[i+1])
+		}
+	}
+
+	return ""
+}
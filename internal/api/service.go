@@ -11,6 +11,7 @@ import (
 type Service interface {
 	Auth() auth.AuthService
 	Token() shared.TokenService
+	WSAuth() shared.WSAuthService
 	User() user.UserService
 	Friendship() friendship.FriendshipService
 	Chat() chat.ChatService
@@ -19,13 +20,14 @@ type Service interface {
 type service struct {
 	auth       auth.AuthService
 	token      shared.TokenService
+	wsAuth     shared.WSAuthService
 	user       user.UserService
 	friendship friendship.FriendshipService
 	chat       chat.ChatService
 }
 
-func NewService(a auth.AuthService, t shared.TokenService, u user.UserService, fr friendship.FriendshipService, c chat.ChatService) Service {
-	return &service{auth: a, token: t, user: u, friendship: fr, chat: c}
+func NewService(a auth.AuthService, t shared.TokenService, ws shared.WSAuthService, u user.UserService, fr friendship.FriendshipService, c chat.ChatService) Service {
+	return &service{auth: a, token: t, wsAuth: ws, user: u, friendship: fr, chat: c}
 }
 
 func (s service) Auth() auth.AuthService {
@@ -36,6 +38,10 @@ func (s service) Token() shared.TokenService {
 	return s.token
 }
 
+func (s service) WSAuth() shared.WSAuthService {
+	return s.wsAuth
+}
+
 func (s service) User() user.UserService {
 	return s.user
 }
@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"socialAPI/internal/api/middleware"
 	"socialAPI/internal/lib"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/render"
 )
@@ -40,3 +42,66 @@ func (c UserController) GetAllHandler() http.HandlerFunc {
 		render.JSON(w, r, users)
 	}
 }
+
+// Максимальное количество пользователей в одном запросе присутствия
+const maxPresenceIDs = 100
+
+func (c UserController) GetPresenceHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		viewerID := r.Context().Value(middleware.UserIDKey).(uint)
+		idsParam := r.URL.Query().Get("ids")
+
+		userIDs, err := parseIDs(idsParam)
+		if err != nil || len(userIDs) == 0 || len(userIDs) > maxPresenceIDs {
+			c.logger.Warnw("Invalid ids parameter", "ids", idsParam)
+			lib.SendMessage(w, r, http.StatusBadRequest, "ids must be a comma-separated list of 1-100 user IDs")
+			return
+		}
+
+		c.logger.Infow("Get presence request", "viewerID", viewerID, "userIDs", userIDs)
+
+		presence, hErr := c.userService.GetPresence(viewerID, userIDs)
+		if hErr != nil {
+			c.logger.Warnw("Failed to retrieve presence", "viewerID", viewerID, "error", hErr.Error())
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, presence)
+	}
+}
+
+func (c UserController) SetPrivacyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(middleware.UserIDKey).(uint)
+		req := r.Context().Value(middleware.DataKey).(PrivacyRequest)
+
+		c.logger.Infow("Update privacy request", "userID", userID)
+
+		hErr := c.userService.SetPrivacy(userID, req)
+		if hErr != nil {
+			c.logger.Warnw("Failed to update privacy settings", "userID", userID, "error", hErr.Error())
+			lib.SendMessage(w, r, hErr.StatusCode, hErr.Error())
+			return
+		}
+
+		lib.SendMessage(w, r, http.StatusOK, "updated successfully")
+	}
+}
+
+func parseIDs(param string) ([]uint, error) {
+	var ids []uint
+	for _, part := range strings.Split(param, ",") {
+		if part == "" {
+			continue
+		}
+
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
@@ -0,0 +1,13 @@
+package user
+
+import "time"
+
+type PresenceResponse struct {
+	UserID     uint       `json:"user_id"`
+	Online     bool       `json:"online"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+}
+
+type PrivacyRequest struct {
+	HidePresence *bool `json:"hide_presence" validate:"required"`
+}
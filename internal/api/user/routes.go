@@ -21,5 +21,7 @@ func NewUserController(userService UserService, tokenService shared.TokenService
 func (u UserController) RegisterRoutes(r *chi.Mux) {
 	r.Route("/v1/user", func(r chi.Router) {
 		r.With(middleware.AuthMiddleware(u.tokenService, u.logger)).Get("/", u.GetAllHandler())
+		r.With(middleware.AuthMiddleware(u.tokenService, u.logger)).Get("/presence", u.GetPresenceHandler())
+		r.With(middleware.AuthMiddleware(u.tokenService, u.logger), middleware.JsonBodyMiddleware[PrivacyRequest](u.logger)).Patch("/me/privacy", u.SetPrivacyHandler())
 	})
 }
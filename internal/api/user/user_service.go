@@ -2,6 +2,7 @@ package user
 
 import (
 	"socialAPI/internal/shared"
+	"socialAPI/internal/storage/cache"
 	r "socialAPI/internal/storage/repository"
 
 	"go.uber.org/zap"
@@ -9,15 +10,18 @@ import (
 
 type UserService interface {
 	GetAllUsers(excludeID *uint) ([]r.User, *shared.HttpError)
+	GetPresence(viewerID uint, userIDs []uint) ([]PresenceResponse, *shared.HttpError)
+	SetPrivacy(userID uint, req PrivacyRequest) *shared.HttpError
 }
 
 type userService struct {
 	userRepo r.UserRepository
+	presence cache.PresenceStore
 	logger   *zap.SugaredLogger
 }
 
-func NewUserService(userRepo r.UserRepository, logger *zap.SugaredLogger) UserService {
-	return &userService{userRepo: userRepo, logger: logger}
+func NewUserService(userRepo r.UserRepository, presence cache.PresenceStore, logger *zap.SugaredLogger) UserService {
+	return &userService{userRepo: userRepo, presence: presence, logger: logger}
 }
 
 func (s userService) GetAllUsers(excludeID *uint) ([]r.User, *shared.HttpError) {
@@ -33,3 +37,85 @@ func (s userService) GetAllUsers(excludeID *uint) ([]r.User, *shared.HttpError)
 
 	return users, nil
 }
+
+// GetPresence отдаёт присутствие только самого зрителя, его друзей и собеседников по чатам.
+// Остальные пользователи всегда выглядят офлайн без времени последнего визита
+func (s userService) GetPresence(viewerID uint, userIDs []uint) ([]PresenceResponse, *shared.HttpError) {
+	s.logger.Infow("Fetching presence", "viewerID", viewerID, "userIDs", userIDs)
+
+	contactIDs, err := s.userRepo.GetContactIDs(viewerID)
+	if err != nil {
+		s.logger.Errorw("Failed to fetch contacts", "viewerID", viewerID, "error", err)
+		return nil, shared.InternalError
+	}
+
+	contacts := make(map[uint]bool, len(contactIDs)+1)
+	contacts[viewerID] = true
+	for _, id := range contactIDs {
+		contacts[id] = true
+	}
+
+	var visibleIDs []uint
+	strangers := make(map[uint]bool)
+	for _, id := range userIDs {
+		if contacts[id] {
+			visibleIDs = append(visibleIDs, id)
+		} else {
+			strangers[id] = true
+		}
+	}
+
+	var users []r.User
+	var online map[uint]bool
+	if len(visibleIDs) > 0 {
+		users, err = s.userRepo.GetPresence(visibleIDs)
+		if err != nil {
+			s.logger.Errorw("Failed to fetch presence settings", "userIDs", visibleIDs, "error", err)
+			return nil, shared.InternalError
+		}
+
+		online, err = s.presence.Online(visibleIDs)
+		if err != nil {
+			s.logger.Errorw("Failed to fetch online state", "userIDs", visibleIDs, "error", err)
+			return nil, shared.InternalError
+		}
+	}
+
+	presence := make([]PresenceResponse, 0, len(users)+len(strangers))
+	for _, user := range users {
+		// Скрывшие присутствие пользователи видны остальным как офлайн без времени последнего визита
+		if user.HidePresence && user.ID != viewerID {
+			presence = append(presence, PresenceResponse{UserID: user.ID})
+			continue
+		}
+
+		presence = append(presence, PresenceResponse{
+			UserID:     user.ID,
+			Online:     online[user.ID],
+			LastSeenAt: user.LastSeenAt,
+		})
+	}
+
+	for _, id := range userIDs {
+		if strangers[id] {
+			presence = append(presence, PresenceResponse{UserID: id})
+			delete(strangers, id)
+		}
+	}
+
+	s.logger.Infow("Presence successfully fetched", "viewerID", viewerID, "userCount", len(presence))
+
+	return presence, nil
+}
+
+func (s userService) SetPrivacy(userID uint, req PrivacyRequest) *shared.HttpError {
+	s.logger.Infow("Updating privacy settings", "userID", userID, "hidePresence", *req.HidePresence)
+
+	if err := s.userRepo.SetHidePresence(userID, *req.HidePresence); err != nil {
+		s.logger.Errorw("Failed to update privacy settings", "userID", userID, "error", err)
+		return shared.InternalError
+	}
+
+	s.logger.Infow("Privacy settings updated", "userID", userID)
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"socialAPI/internal/shared"
 	r "socialAPI/internal/storage/repository"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -25,17 +26,20 @@ var (
 
 type userServiceMocks struct {
 	userRepo *mocks.UserRepository
+	presence *mocks.PresenceStore
 	userSrv  user.UserService
 }
 
 func setupUserService() userServiceMocks {
 	userRepo := new(mocks.UserRepository)
+	presence := new(mocks.PresenceStore)
 	logger := zap.NewNop().Sugar()
 
-	srv := user.NewUserService(userRepo, logger)
+	srv := user.NewUserService(userRepo, presence, logger)
 
 	return userServiceMocks{
 		userRepo: userRepo,
+		presence: presence,
 		userSrv:  srv,
 	}
 }
@@ -103,3 +107,141 @@ func TestFriendshipService_SendFriendRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestUserService_GetPresence(t *testing.T) {
+	lastSeen := time.Now().Add(-time.Hour)
+	ids := []uint{1, 2, 3}
+
+	tests := []struct {
+		name       string
+		setup      func(m userServiceMocks)
+		want       []user.PresenceResponse
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name: "failed to fetch contacts",
+			setup: func(m userServiceMocks) {
+				m.userRepo.On("GetContactIDs", userID).Return(nil, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "failed to fetch presence settings",
+			setup: func(m userServiceMocks) {
+				m.userRepo.On("GetContactIDs", userID).Return([]uint{2, 3}, nil)
+				m.userRepo.On("GetPresence", ids).Return(nil, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "failed to fetch online state",
+			setup: func(m userServiceMocks) {
+				m.userRepo.On("GetContactIDs", userID).Return([]uint{2, 3}, nil)
+				m.userRepo.On("GetPresence", ids).Return([]r.User{{ID: 2}}, nil)
+				m.presence.On("Online", ids).Return(nil, errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "hidden users are reported offline to others",
+			setup: func(m userServiceMocks) {
+				m.userRepo.On("GetContactIDs", userID).Return([]uint{2, 3}, nil)
+				m.userRepo.On("GetPresence", ids).Return([]r.User{
+					{ID: 1, HidePresence: true, LastSeenAt: &lastSeen},
+					{ID: 2, LastSeenAt: &lastSeen},
+					{ID: 3, HidePresence: true, LastSeenAt: &lastSeen},
+				}, nil)
+				m.presence.On("Online", ids).Return(map[uint]bool{1: true, 2: false, 3: true}, nil)
+			},
+			want: []user.PresenceResponse{
+				{UserID: 1, Online: true, LastSeenAt: &lastSeen},
+				{UserID: 2, Online: false, LastSeenAt: &lastSeen},
+				{UserID: 3},
+			},
+		},
+		{
+			name: "strangers are reported offline",
+			setup: func(m userServiceMocks) {
+				m.userRepo.On("GetContactIDs", userID).Return([]uint{2}, nil)
+				m.userRepo.On("GetPresence", []uint{1, 2}).Return([]r.User{
+					{ID: 1, LastSeenAt: &lastSeen},
+					{ID: 2, LastSeenAt: &lastSeen},
+				}, nil)
+				m.presence.On("Online", []uint{1, 2}).Return(map[uint]bool{1: true, 2: true}, nil)
+			},
+			want: []user.PresenceResponse{
+				{UserID: 1, Online: true, LastSeenAt: &lastSeen},
+				{UserID: 2, Online: true, LastSeenAt: &lastSeen},
+				{UserID: 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := setupUserService()
+			tt.setup(m)
+
+			presence, err := m.userSrv.GetPresence(userID, ids)
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.want, presence)
+			}
+
+			m.userRepo.AssertExpectations(t)
+			m.presence.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_SetPrivacy(t *testing.T) {
+	hide := true
+
+	tests := []struct {
+		name       string
+		setup      func(m userServiceMocks)
+		wantErr    bool
+		errMessage string
+	}{
+		{
+			name: "failed to update privacy settings",
+			setup: func(m userServiceMocks) {
+				m.userRepo.On("SetHidePresence", userID, hide).Return(errExample)
+			},
+			wantErr:    true,
+			errMessage: shared.InternalError.Error(),
+		},
+		{
+			name: "privacy settings updated",
+			setup: func(m userServiceMocks) {
+				m.userRepo.On("SetHidePresence", userID, hide).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := setupUserService()
+			tt.setup(m)
+
+			err := m.userSrv.SetPrivacy(userID, user.PrivacyRequest{HidePresence: &hide})
+
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.errMessage, err.Error())
+			} else {
+				assert.Nil(t, err)
+			}
+
+			m.userRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -61,3 +61,18 @@ func GetListFromEnv(key string, separator string, fallback []string) []string {
 	log.Printf("Environment variable %s not set, using fallback value: %v", key, fallback)
 	return fallback
 }
+
+// GetBoolFromEnv получает логическое значение из окружения или использует fallback.
+func GetBoolFromEnv(key string, fallback bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Printf("Invalid boolean for %s: %v, using default value %t", key, err, fallback)
+			return fallback
+		}
+		log.Printf("Environment variable %s set to: %t", key, boolValue)
+		return boolValue
+	}
+	log.Printf("Environment variable %s not set, using fallback value: %t", key, fallback)
+	return fallback
+}
@@ -13,17 +13,86 @@ type ChatRepository struct {
 	mock.Mock
 }
 
-// Create provides a mock function with given fields: name, userIDs
-func (_m *ChatRepository) Create(name *string, userIDs []uint) error {
-	ret := _m.Called(name, userIDs)
+// AddMembers provides a mock function with given fields: chatID, actorID, userIDs
+func (_m *ChatRepository) AddMembers(chatID uint, actorID uint, userIDs []uint) ([]uint, *repository.Message, error) {
+	ret := _m.Called(chatID, actorID, userIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddMembers")
+	}
+
+	var r0 []uint
+	var r1 *repository.Message
+	var r2 error
+	if rf, ok := ret.Get(0).(func(uint, uint, []uint) ([]uint, *repository.Message, error)); ok {
+		return rf(chatID, actorID, userIDs)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, []uint) []uint); ok {
+		r0 = rf(chatID, actorID, userIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]uint)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, []uint) *repository.Message); ok {
+		r1 = rf(chatID, actorID, userIDs)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*repository.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(uint, uint, []uint) error); ok {
+		r2 = rf(chatID, actorID, userIDs)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Create provides a mock function with given fields: creatorID, name, userIDs
+func (_m *ChatRepository) Create(creatorID uint, name *string, userIDs []uint) (*repository.Message, error) {
+	ret := _m.Called(creatorID, name, userIDs)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Create")
 	}
 
+	var r0 *repository.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, *string, []uint) (*repository.Message, error)); ok {
+		return rf(creatorID, name, userIDs)
+	}
+	if rf, ok := ret.Get(0).(func(uint, *string, []uint) *repository.Message); ok {
+		r0 = rf(creatorID, name, userIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, *string, []uint) error); ok {
+		r1 = rf(creatorID, name, userIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateInvite provides a mock function with given fields: invite
+func (_m *ChatRepository) CreateInvite(invite *repository.ChatInvite) error {
+	ret := _m.Called(invite)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateInvite")
+	}
+
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*string, []uint) error); ok {
-		r0 = rf(name, userIDs)
+	if rf, ok := ret.Get(0).(func(*repository.ChatInvite) error); ok {
+		r0 = rf(invite)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -59,27 +128,29 @@ func (_m *ChatRepository) ExistsID(chatID uint) (bool, error) {
 	return r0, r1
 }
 
-// ExistsSetUserIDs provides a mock function with given fields: userIDs
-func (_m *ChatRepository) ExistsSetUserIDs(userIDs []uint) (bool, error) {
-	ret := _m.Called(userIDs)
+// FilterActiveSenders provides a mock function with given fields: senders
+func (_m *ChatRepository) FilterActiveSenders(senders []repository.ChatSender) (map[repository.ChatSender]bool, error) {
+	ret := _m.Called(senders)
 
 	if len(ret) == 0 {
-		panic("no return value specified for ExistsSetUserIDs")
+		panic("no return value specified for FilterActiveSenders")
 	}
 
-	var r0 bool
+	var r0 map[repository.ChatSender]bool
 	var r1 error
-	if rf, ok := ret.Get(0).(func([]uint) (bool, error)); ok {
-		return rf(userIDs)
+	if rf, ok := ret.Get(0).(func([]repository.ChatSender) (map[repository.ChatSender]bool, error)); ok {
+		return rf(senders)
 	}
-	if rf, ok := ret.Get(0).(func([]uint) bool); ok {
-		r0 = rf(userIDs)
+	if rf, ok := ret.Get(0).(func([]repository.ChatSender) map[repository.ChatSender]bool); ok {
+		r0 = rf(senders)
 	} else {
-		r0 = ret.Get(0).(bool)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[repository.ChatSender]bool)
+		}
 	}
 
-	if rf, ok := ret.Get(1).(func([]uint) error); ok {
-		r1 = rf(userIDs)
+	if rf, ok := ret.Get(1).(func([]repository.ChatSender) error); ok {
+		r1 = rf(senders)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -87,29 +158,29 @@ func (_m *ChatRepository) ExistsSetUserIDs(userIDs []uint) (bool, error) {
 	return r0, r1
 }
 
-// GetAll provides a mock function with no fields
-func (_m *ChatRepository) GetAll() ([]*repository.Chat, error) {
-	ret := _m.Called()
+// GetChatIDsByUserID provides a mock function with given fields: userID
+func (_m *ChatRepository) GetChatIDsByUserID(userID uint) ([]uint, error) {
+	ret := _m.Called(userID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetAll")
+		panic("no return value specified for GetChatIDsByUserID")
 	}
 
-	var r0 []*repository.Chat
+	var r0 []uint
 	var r1 error
-	if rf, ok := ret.Get(0).(func() ([]*repository.Chat, error)); ok {
-		return rf()
+	if rf, ok := ret.Get(0).(func(uint) ([]uint, error)); ok {
+		return rf(userID)
 	}
-	if rf, ok := ret.Get(0).(func() []*repository.Chat); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(uint) []uint); ok {
+		r0 = rf(userID)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]*repository.Chat)
+			r0 = ret.Get(0).([]uint)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func() error); ok {
-		r1 = rf()
+	if rf, ok := ret.Get(1).(func(uint) error); ok {
+		r1 = rf(userID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -117,29 +188,89 @@ func (_m *ChatRepository) GetAll() ([]*repository.Chat, error) {
 	return r0, r1
 }
 
-// GetChatIDsByUserID provides a mock function with given fields: userID
-func (_m *ChatRepository) GetChatIDsByUserID(userID uint) ([]uint, error) {
-	ret := _m.Called(userID)
+// GetInvites provides a mock function with given fields: chatID
+func (_m *ChatRepository) GetInvites(chatID uint) ([]repository.ChatInvite, error) {
+	ret := _m.Called(chatID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetChatIDsByUserID")
+		panic("no return value specified for GetInvites")
 	}
 
-	var r0 []uint
+	var r0 []repository.ChatInvite
 	var r1 error
-	if rf, ok := ret.Get(0).(func(uint) ([]uint, error)); ok {
-		return rf(userID)
+	if rf, ok := ret.Get(0).(func(uint) ([]repository.ChatInvite, error)); ok {
+		return rf(chatID)
 	}
-	if rf, ok := ret.Get(0).(func(uint) []uint); ok {
-		r0 = rf(userID)
+	if rf, ok := ret.Get(0).(func(uint) []repository.ChatInvite); ok {
+		r0 = rf(chatID)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]uint)
+			r0 = ret.Get(0).([]repository.ChatInvite)
 		}
 	}
 
 	if rf, ok := ret.Get(1).(func(uint) error); ok {
-		r1 = rf(userID)
+		r1 = rf(chatID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetMembers provides a mock function with given fields: chatID
+func (_m *ChatRepository) GetMembers(chatID uint) ([]repository.ChatMember, error) {
+	ret := _m.Called(chatID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMembers")
+	}
+
+	var r0 []repository.ChatMember
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint) ([]repository.ChatMember, error)); ok {
+		return rf(chatID)
+	}
+	if rf, ok := ret.Get(0).(func(uint) []repository.ChatMember); ok {
+		r0 = rf(chatID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.ChatMember)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint) error); ok {
+		r1 = rf(chatID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetNotifiableUserIDs provides a mock function with given fields: chatIDs
+func (_m *ChatRepository) GetNotifiableUserIDs(chatIDs []uint) (map[uint][]uint, error) {
+	ret := _m.Called(chatIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetNotifiableUserIDs")
+	}
+
+	var r0 map[uint][]uint
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]uint) (map[uint][]uint, error)); ok {
+		return rf(chatIDs)
+	}
+	if rf, ok := ret.Get(0).(func([]uint) map[uint][]uint); ok {
+		r0 = rf(chatIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[uint][]uint)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]uint) error); ok {
+		r1 = rf(chatIDs)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -177,22 +308,426 @@ func (_m *ChatRepository) GetOne(chatID uint) (*repository.Chat, error) {
 	return r0, r1
 }
 
-// Update provides a mock function with given fields: id, name, userIDs
-func (_m *ChatRepository) Update(id uint, name *string, userIDs []uint) error {
-	ret := _m.Called(id, name, userIDs)
+// GetOrCreateDirect provides a mock function with given fields: userID, peerID
+func (_m *ChatRepository) GetOrCreateDirect(userID uint, peerID uint) (uint, bool, error) {
+	ret := _m.Called(userID, peerID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Update")
+		panic("no return value specified for GetOrCreateDirect")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(uint, *string, []uint) error); ok {
-		r0 = rf(id, name, userIDs)
+	var r0 uint
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(uint, uint) (uint, bool, error)); ok {
+		return rf(userID, peerID)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint) uint); ok {
+		r0 = rf(userID, peerID)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(uint)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(uint, uint) bool); ok {
+		r1 = rf(userID, peerID)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(uint, uint) error); ok {
+		r2 = rf(userID, peerID)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetRole provides a mock function with given fields: chatID, userID
+func (_m *ChatRepository) GetRole(chatID uint, userID uint) (repository.ChatRole, error) {
+	ret := _m.Called(chatID, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRole")
+	}
+
+	var r0 repository.ChatRole
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint) (repository.ChatRole, error)); ok {
+		return rf(chatID, userID)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint) repository.ChatRole); ok {
+		r0 = rf(chatID, userID)
+	} else {
+		r0 = ret.Get(0).(repository.ChatRole)
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint) error); ok {
+		r1 = rf(chatID, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSummaries provides a mock function with given fields: userID, archived
+func (_m *ChatRepository) GetSummaries(userID uint, archived bool) ([]repository.ChatSummary, error) {
+	ret := _m.Called(userID, archived)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSummaries")
+	}
+
+	var r0 []repository.ChatSummary
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, bool) ([]repository.ChatSummary, error)); ok {
+		return rf(userID, archived)
+	}
+	if rf, ok := ret.Get(0).(func(uint, bool) []repository.ChatSummary); ok {
+		r0 = rf(userID, archived)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.ChatSummary)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, bool) error); ok {
+		r1 = rf(userID, archived)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetType provides a mock function with given fields: chatID
+func (_m *ChatRepository) GetType(chatID uint) (repository.ChatType, error) {
+	ret := _m.Called(chatID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetType")
+	}
+
+	var r0 repository.ChatType
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint) (repository.ChatType, error)); ok {
+		return rf(chatID)
+	}
+	if rf, ok := ret.Get(0).(func(uint) repository.ChatType); ok {
+		r0 = rf(chatID)
+	} else {
+		r0 = ret.Get(0).(repository.ChatType)
+	}
+
+	if rf, ok := ret.Get(1).(func(uint) error); ok {
+		r1 = rf(chatID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserIDs provides a mock function with given fields: chatID
+func (_m *ChatRepository) GetUserIDs(chatID uint) ([]uint, error) {
+	ret := _m.Called(chatID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserIDs")
+	}
+
+	var r0 []uint
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint) ([]uint, error)); ok {
+		return rf(chatID)
+	}
+	if rf, ok := ret.Get(0).(func(uint) []uint); ok {
+		r0 = rf(chatID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]uint)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint) error); ok {
+		r1 = rf(chatID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IsMember provides a mock function with given fields: chatID, userID
+func (_m *ChatRepository) IsMember(chatID uint, userID uint) (bool, error) {
+	ret := _m.Called(chatID, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsMember")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint) (bool, error)); ok {
+		return rf(chatID, userID)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint) bool); ok {
+		r0 = rf(chatID, userID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint) error); ok {
+		r1 = rf(chatID, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// JoinByInvite provides a mock function with given fields: code, userID
+func (_m *ChatRepository) JoinByInvite(code string, userID uint) (uint, *repository.Message, error) {
+	ret := _m.Called(code, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for JoinByInvite")
+	}
+
+	var r0 uint
+	var r1 *repository.Message
+	var r2 error
+	if rf, ok := ret.Get(0).(func(string, uint) (uint, *repository.Message, error)); ok {
+		return rf(code, userID)
+	}
+	if rf, ok := ret.Get(0).(func(string, uint) uint); ok {
+		r0 = rf(code, userID)
+	} else {
+		r0 = ret.Get(0).(uint)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, uint) *repository.Message); ok {
+		r1 = rf(code, userID)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*repository.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(string, uint) error); ok {
+		r2 = rf(code, userID)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// MarkRead provides a mock function with given fields: chatID, userID, messageID
+func (_m *ChatRepository) MarkRead(chatID uint, userID uint, messageID uint) (bool, error) {
+	ret := _m.Called(chatID, userID, messageID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkRead")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint, uint) (bool, error)); ok {
+		return rf(chatID, userID, messageID)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, uint) bool); ok {
+		r0 = rf(chatID, userID, messageID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, uint) error); ok {
+		r1 = rf(chatID, userID, messageID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RemoveMember provides a mock function with given fields: chatID, actorID, userID
+func (_m *ChatRepository) RemoveMember(chatID uint, actorID uint, userID uint) ([]repository.Message, error) {
+	ret := _m.Called(chatID, actorID, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveMember")
+	}
+
+	var r0 []repository.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint, uint) ([]repository.Message, error)); ok {
+		return rf(chatID, actorID, userID)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, uint) []repository.Message); ok {
+		r0 = rf(chatID, actorID, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, uint) error); ok {
+		r1 = rf(chatID, actorID, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Rename provides a mock function with given fields: chatID, actorID, name
+func (_m *ChatRepository) Rename(chatID uint, actorID uint, name string) (*repository.Message, error) {
+	ret := _m.Called(chatID, actorID, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Rename")
+	}
+
+	var r0 *repository.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint, string) (*repository.Message, error)); ok {
+		return rf(chatID, actorID, name)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, string) *repository.Message); ok {
+		r0 = rf(chatID, actorID, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, string) error); ok {
+		r1 = rf(chatID, actorID, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeInvite provides a mock function with given fields: chatID, inviteID
+func (_m *ChatRepository) RevokeInvite(chatID uint, inviteID uint) (bool, error) {
+	ret := _m.Called(chatID, inviteID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeInvite")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint) (bool, error)); ok {
+		return rf(chatID, inviteID)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint) bool); ok {
+		r0 = rf(chatID, inviteID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint) error); ok {
+		r1 = rf(chatID, inviteID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetRetention provides a mock function with given fields: chatID, actorID, retentionSeconds, messageTTLSeconds
+func (_m *ChatRepository) SetRetention(chatID uint, actorID uint, retentionSeconds *int64, messageTTLSeconds *int64) (*repository.Message, error) {
+	ret := _m.Called(chatID, actorID, retentionSeconds, messageTTLSeconds)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetRetention")
+	}
+
+	var r0 *repository.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint, *int64, *int64) (*repository.Message, error)); ok {
+		return rf(chatID, actorID, retentionSeconds, messageTTLSeconds)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, *int64, *int64) *repository.Message); ok {
+		r0 = rf(chatID, actorID, retentionSeconds, messageTTLSeconds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, *int64, *int64) error); ok {
+		r1 = rf(chatID, actorID, retentionSeconds, messageTTLSeconds)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetRole provides a mock function with given fields: chatID, actorID, userID, role
+func (_m *ChatRepository) SetRole(chatID uint, actorID uint, userID uint, role repository.ChatRole) (*repository.Message, error) {
+	ret := _m.Called(chatID, actorID, userID, role)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetRole")
+	}
+
+	var r0 *repository.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, repository.ChatRole) (*repository.Message, error)); ok {
+		return rf(chatID, actorID, userID, role)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, repository.ChatRole) *repository.Message); ok {
+		r0 = rf(chatID, actorID, userID, role)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, uint, repository.ChatRole) error); ok {
+		r1 = rf(chatID, actorID, userID, role)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateSettings provides a mock function with given fields: chatID, userID, changes
+func (_m *ChatRepository) UpdateSettings(chatID uint, userID uint, changes map[string]interface{}) (*repository.ChatMember, error) {
+	ret := _m.Called(chatID, userID, changes)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateSettings")
+	}
+
+	var r0 *repository.ChatMember
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint, map[string]interface{}) (*repository.ChatMember, error)); ok {
+		return rf(chatID, userID, changes)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, map[string]interface{}) *repository.ChatMember); ok {
+		r0 = rf(chatID, userID, changes)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.ChatMember)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, map[string]interface{}) error); ok {
+		r1 = rf(chatID, userID, changes)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // NewChatRepository creates a new instance of ChatRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
@@ -3,14 +3,15 @@
 package mocks
 
 import (
+	io "io"
 	http "net/http"
 	chat "socialAPI/internal/api/chat"
-
-	mock "github.com/stretchr/testify/mock"
-
+	ws "socialAPI/internal/api/chat/ws"
+	shared "socialAPI/internal/shared"
 	repository "socialAPI/internal/storage/repository"
+	time "time"
 
-	shared "socialAPI/internal/shared"
+	mock "github.com/stretchr/testify/mock"
 )
 
 // ChatService is an autogenerated mock type for the ChatService type
@@ -18,17 +19,193 @@ type ChatService struct {
 	mock.Mock
 }
 
-// Create provides a mock function with given fields: req
-func (_m *ChatService) Create(req chat.CreateRequest) *shared.HttpError {
-	ret := _m.Called(req)
+// AddMembers provides a mock function with given fields: actorID, chatID, req
+func (_m *ChatService) AddMembers(actorID uint, chatID uint, req chat.AddMembersRequest) *shared.HttpError {
+	ret := _m.Called(actorID, chatID, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddMembers")
+	}
+
+	var r0 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, chat.AddMembersRequest) *shared.HttpError); ok {
+		r0 = rf(actorID, chatID, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*shared.HttpError)
+		}
+	}
+
+	return r0
+}
+
+// AddReaction provides a mock function with given fields: userID, chatID, messageID, req
+func (_m *ChatService) AddReaction(userID uint, chatID uint, messageID uint, req chat.ReactionRequest) (*ws.Reaction, *shared.HttpError) {
+	ret := _m.Called(userID, chatID, messageID, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddReaction")
+	}
+
+	var r0 *ws.Reaction
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, chat.ReactionRequest) (*ws.Reaction, *shared.HttpError)); ok {
+		return rf(userID, chatID, messageID, req)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, chat.ReactionRequest) *ws.Reaction); ok {
+		r0 = rf(userID, chatID, messageID, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ws.Reaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, uint, chat.ReactionRequest) *shared.HttpError); ok {
+		r1 = rf(userID, chatID, messageID, req)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
+}
+
+// CancelScheduled provides a mock function with given fields: userID, chatID, scheduledID
+func (_m *ChatService) CancelScheduled(userID uint, chatID uint, scheduledID uint) *shared.HttpError {
+	ret := _m.Called(userID, chatID, scheduledID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CancelScheduled")
+	}
+
+	var r0 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, uint) *shared.HttpError); ok {
+		r0 = rf(userID, chatID, scheduledID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*shared.HttpError)
+		}
+	}
+
+	return r0
+}
+
+// ClosePoll provides a mock function with given fields: actorID, chatID, messageID
+func (_m *ChatService) ClosePoll(actorID uint, chatID uint, messageID uint) *shared.HttpError {
+	ret := _m.Called(actorID, chatID, messageID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClosePoll")
+	}
+
+	var r0 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, uint) *shared.HttpError); ok {
+		r0 = rf(actorID, chatID, messageID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*shared.HttpError)
+		}
+	}
+
+	return r0
+}
+
+// Create provides a mock function with given fields: creatorID, req
+func (_m *ChatService) Create(creatorID uint, req chat.CreateRequest) *shared.HttpError {
+	ret := _m.Called(creatorID, req)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Create")
 	}
 
 	var r0 *shared.HttpError
-	if rf, ok := ret.Get(0).(func(chat.CreateRequest) *shared.HttpError); ok {
-		r0 = rf(req)
+	if rf, ok := ret.Get(0).(func(uint, chat.CreateRequest) *shared.HttpError); ok {
+		r0 = rf(creatorID, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*shared.HttpError)
+		}
+	}
+
+	return r0
+}
+
+// CreateInvite provides a mock function with given fields: actorID, chatID, req
+func (_m *ChatService) CreateInvite(actorID uint, chatID uint, req chat.CreateInviteRequest) (*repository.ChatInviteDTO, *shared.HttpError) {
+	ret := _m.Called(actorID, chatID, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateInvite")
+	}
+
+	var r0 *repository.ChatInviteDTO
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, chat.CreateInviteRequest) (*repository.ChatInviteDTO, *shared.HttpError)); ok {
+		return rf(actorID, chatID, req)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, chat.CreateInviteRequest) *repository.ChatInviteDTO); ok {
+		r0 = rf(actorID, chatID, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.ChatInviteDTO)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, chat.CreateInviteRequest) *shared.HttpError); ok {
+		r1 = rf(actorID, chatID, req)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
+}
+
+// CreatePoll provides a mock function with given fields: userID, chatID, req
+func (_m *ChatService) CreatePoll(userID uint, chatID uint, req chat.CreatePollRequest) (*repository.MessageDTO, *shared.HttpError) {
+	ret := _m.Called(userID, chatID, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreatePoll")
+	}
+
+	var r0 *repository.MessageDTO
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, chat.CreatePollRequest) (*repository.MessageDTO, *shared.HttpError)); ok {
+		return rf(userID, chatID, req)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, chat.CreatePollRequest) *repository.MessageDTO); ok {
+		r0 = rf(userID, chatID, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.MessageDTO)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, chat.CreatePollRequest) *shared.HttpError); ok {
+		r1 = rf(userID, chatID, req)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
+}
+
+// DeleteMessage provides a mock function with given fields: userID, chatID, messageID
+func (_m *ChatService) DeleteMessage(userID uint, chatID uint, messageID uint) *shared.HttpError {
+	ret := _m.Called(userID, chatID, messageID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteMessage")
+	}
+
+	var r0 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, uint) *shared.HttpError); ok {
+		r0 = rf(userID, chatID, messageID)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*shared.HttpError)
@@ -38,29 +215,189 @@ func (_m *ChatService) Create(req chat.CreateRequest) *shared.HttpError {
 	return r0
 }
 
-// GetAll provides a mock function with no fields
-func (_m *ChatService) GetAll() (*[]repository.ChatDTO, *shared.HttpError) {
-	ret := _m.Called()
+// EditMessage provides a mock function with given fields: userID, chatID, messageID, req
+func (_m *ChatService) EditMessage(userID uint, chatID uint, messageID uint, req chat.EditMessageRequest) (*repository.MessageDTO, *shared.HttpError) {
+	ret := _m.Called(userID, chatID, messageID, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EditMessage")
+	}
+
+	var r0 *repository.MessageDTO
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, chat.EditMessageRequest) (*repository.MessageDTO, *shared.HttpError)); ok {
+		return rf(userID, chatID, messageID, req)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, chat.EditMessageRequest) *repository.MessageDTO); ok {
+		r0 = rf(userID, chatID, messageID, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.MessageDTO)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, uint, chat.EditMessageRequest) *shared.HttpError); ok {
+		r1 = rf(userID, chatID, messageID, req)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
+}
+
+// EditScheduled provides a mock function with given fields: userID, chatID, scheduledID, req
+func (_m *ChatService) EditScheduled(userID uint, chatID uint, scheduledID uint, req chat.EditScheduledRequest) (*repository.ScheduledMessageDTO, *shared.HttpError) {
+	ret := _m.Called(userID, chatID, scheduledID, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EditScheduled")
+	}
+
+	var r0 *repository.ScheduledMessageDTO
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, chat.EditScheduledRequest) (*repository.ScheduledMessageDTO, *shared.HttpError)); ok {
+		return rf(userID, chatID, scheduledID, req)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, chat.EditScheduledRequest) *repository.ScheduledMessageDTO); ok {
+		r0 = rf(userID, chatID, scheduledID, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.ScheduledMessageDTO)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, uint, chat.EditScheduledRequest) *shared.HttpError); ok {
+		r1 = rf(userID, chatID, scheduledID, req)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
+}
+
+// ForwardMessage provides a mock function with given fields: userID, chatID, messageID, req
+func (_m *ChatService) ForwardMessage(userID uint, chatID uint, messageID uint, req chat.ForwardRequest) ([]chat.ForwardedMessageDTO, *shared.HttpError) {
+	ret := _m.Called(userID, chatID, messageID, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ForwardMessage")
+	}
+
+	var r0 []chat.ForwardedMessageDTO
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, chat.ForwardRequest) ([]chat.ForwardedMessageDTO, *shared.HttpError)); ok {
+		return rf(userID, chatID, messageID, req)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, chat.ForwardRequest) []chat.ForwardedMessageDTO); ok {
+		r0 = rf(userID, chatID, messageID, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]chat.ForwardedMessageDTO)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, uint, chat.ForwardRequest) *shared.HttpError); ok {
+		r1 = rf(userID, chatID, messageID, req)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
+}
+
+// GetAll provides a mock function with given fields: userID, archived
+func (_m *ChatService) GetAll(userID uint, archived bool) (*[]repository.ChatSummaryDTO, *shared.HttpError) {
+	ret := _m.Called(userID, archived)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetAll")
 	}
 
-	var r0 *[]repository.ChatDTO
+	var r0 *[]repository.ChatSummaryDTO
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, bool) (*[]repository.ChatSummaryDTO, *shared.HttpError)); ok {
+		return rf(userID, archived)
+	}
+	if rf, ok := ret.Get(0).(func(uint, bool) *[]repository.ChatSummaryDTO); ok {
+		r0 = rf(userID, archived)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*[]repository.ChatSummaryDTO)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, bool) *shared.HttpError); ok {
+		r1 = rf(userID, archived)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
+}
+
+// GetInvites provides a mock function with given fields: actorID, chatID
+func (_m *ChatService) GetInvites(actorID uint, chatID uint) ([]repository.ChatInviteDTO, *shared.HttpError) {
+	ret := _m.Called(actorID, chatID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetInvites")
+	}
+
+	var r0 []repository.ChatInviteDTO
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint) ([]repository.ChatInviteDTO, *shared.HttpError)); ok {
+		return rf(actorID, chatID)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint) []repository.ChatInviteDTO); ok {
+		r0 = rf(actorID, chatID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.ChatInviteDTO)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint) *shared.HttpError); ok {
+		r1 = rf(actorID, chatID)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
+}
+
+// GetMentions provides a mock function with given fields: userID, beforeID, limit
+func (_m *ChatService) GetMentions(userID uint, beforeID uint, limit int) (*chat.MentionsResponse, *shared.HttpError) {
+	ret := _m.Called(userID, beforeID, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMentions")
+	}
+
+	var r0 *chat.MentionsResponse
 	var r1 *shared.HttpError
-	if rf, ok := ret.Get(0).(func() (*[]repository.ChatDTO, *shared.HttpError)); ok {
-		return rf()
+	if rf, ok := ret.Get(0).(func(uint, uint, int) (*chat.MentionsResponse, *shared.HttpError)); ok {
+		return rf(userID, beforeID, limit)
 	}
-	if rf, ok := ret.Get(0).(func() *[]repository.ChatDTO); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(uint, uint, int) *chat.MentionsResponse); ok {
+		r0 = rf(userID, beforeID, limit)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*[]repository.ChatDTO)
+			r0 = ret.Get(0).(*chat.MentionsResponse)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func() *shared.HttpError); ok {
-		r1 = rf()
+	if rf, ok := ret.Get(1).(func(uint, uint, int) *shared.HttpError); ok {
+		r1 = rf(userID, beforeID, limit)
 	} else {
 		if ret.Get(1) != nil {
 			r1 = ret.Get(1).(*shared.HttpError)
@@ -70,9 +407,9 @@ func (_m *ChatService) GetAll() (*[]repository.ChatDTO, *shared.HttpError) {
 	return r0, r1
 }
 
-// GetOne provides a mock function with given fields: chatID
-func (_m *ChatService) GetOne(chatID uint) (*repository.ChatDTO, *shared.HttpError) {
-	ret := _m.Called(chatID)
+// GetOne provides a mock function with given fields: userID, chatID
+func (_m *ChatService) GetOne(userID uint, chatID uint) (*repository.ChatDTO, *shared.HttpError) {
+	ret := _m.Called(userID, chatID)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetOne")
@@ -80,19 +417,19 @@ func (_m *ChatService) GetOne(chatID uint) (*repository.ChatDTO, *shared.HttpErr
 
 	var r0 *repository.ChatDTO
 	var r1 *shared.HttpError
-	if rf, ok := ret.Get(0).(func(uint) (*repository.ChatDTO, *shared.HttpError)); ok {
-		return rf(chatID)
+	if rf, ok := ret.Get(0).(func(uint, uint) (*repository.ChatDTO, *shared.HttpError)); ok {
+		return rf(userID, chatID)
 	}
-	if rf, ok := ret.Get(0).(func(uint) *repository.ChatDTO); ok {
-		r0 = rf(chatID)
+	if rf, ok := ret.Get(0).(func(uint, uint) *repository.ChatDTO); ok {
+		r0 = rf(userID, chatID)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*repository.ChatDTO)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(uint) *shared.HttpError); ok {
-		r1 = rf(chatID)
+	if rf, ok := ret.Get(1).(func(uint, uint) *shared.HttpError); ok {
+		r1 = rf(userID, chatID)
 	} else {
 		if ret.Get(1) != nil {
 			r1 = ret.Get(1).(*shared.HttpError)
@@ -102,44 +439,652 @@ func (_m *ChatService) GetOne(chatID uint) (*repository.ChatDTO, *shared.HttpErr
 	return r0, r1
 }
 
-// HandleWebSocket provides a mock function with given fields: userID, w, r
-func (_m *ChatService) HandleWebSocket(userID uint, w http.ResponseWriter, r *http.Request) *shared.HttpError {
-	ret := _m.Called(userID, w, r)
+// GetOrCreateDirect provides a mock function with given fields: userID, peerID
+func (_m *ChatService) GetOrCreateDirect(userID uint, peerID uint) (*chat.DirectChatResponse, *shared.HttpError) {
+	ret := _m.Called(userID, peerID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for HandleWebSocket")
+		panic("no return value specified for GetOrCreateDirect")
 	}
 
-	var r0 *shared.HttpError
-	if rf, ok := ret.Get(0).(func(uint, http.ResponseWriter, *http.Request) *shared.HttpError); ok {
-		r0 = rf(userID, w, r)
+	var r0 *chat.DirectChatResponse
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint) (*chat.DirectChatResponse, *shared.HttpError)); ok {
+		return rf(userID, peerID)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint) *chat.DirectChatResponse); ok {
+		r0 = rf(userID, peerID)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*shared.HttpError)
+			r0 = ret.Get(0).(*chat.DirectChatResponse)
 		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(uint, uint) *shared.HttpError); ok {
+		r1 = rf(userID, peerID)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
 }
 
-// Update provides a mock function with given fields: id, req
-func (_m *ChatService) Update(id uint, req chat.CreateRequest) *shared.HttpError {
-	ret := _m.Called(id, req)
+// GetPins provides a mock function with given fields: userID, chatID
+func (_m *ChatService) GetPins(userID uint, chatID uint) ([]repository.PinnedMessageDTO, *shared.HttpError) {
+	ret := _m.Called(userID, chatID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Update")
+		panic("no return value specified for GetPins")
 	}
 
-	var r0 *shared.HttpError
-	if rf, ok := ret.Get(0).(func(uint, chat.CreateRequest) *shared.HttpError); ok {
-		r0 = rf(id, req)
+	var r0 []repository.PinnedMessageDTO
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint) ([]repository.PinnedMessageDTO, *shared.HttpError)); ok {
+		return rf(userID, chatID)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint) []repository.PinnedMessageDTO); ok {
+		r0 = rf(userID, chatID)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*shared.HttpError)
+			r0 = ret.Get(0).([]repository.PinnedMessageDTO)
 		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(uint, uint) *shared.HttpError); ok {
+		r1 = rf(userID, chatID)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
+}
+
+// GetScheduled provides a mock function with given fields: userID, chatID
+func (_m *ChatService) GetScheduled(userID uint, chatID uint) ([]repository.ScheduledMessageDTO, *shared.HttpError) {
+	ret := _m.Called(userID, chatID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetScheduled")
+	}
+
+	var r0 []repository.ScheduledMessageDTO
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint) ([]repository.ScheduledMessageDTO, *shared.HttpError)); ok {
+		return rf(userID, chatID)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint) []repository.ScheduledMessageDTO); ok {
+		r0 = rf(userID, chatID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.ScheduledMessageDTO)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint) *shared.HttpError); ok {
+		r1 = rf(userID, chatID)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
+}
+
+// GetThread provides a mock function with given fields: userID, chatID, messageID, afterID, limit
+func (_m *ChatService) GetThread(userID uint, chatID uint, messageID uint, afterID uint, limit int) (*chat.ThreadResponse, *shared.HttpError) {
+	ret := _m.Called(userID, chatID, messageID, afterID, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetThread")
+	}
+
+	var r0 *chat.ThreadResponse
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, uint, int) (*chat.ThreadResponse, *shared.HttpError)); ok {
+		return rf(userID, chatID, messageID, afterID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, uint, int) *chat.ThreadResponse); ok {
+		r0 = rf(userID, chatID, messageID, afterID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*chat.ThreadResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, uint, uint, int) *shared.HttpError); ok {
+		r1 = rf(userID, chatID, messageID, afterID, limit)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
+}
+
+// HandleWebSocket provides a mock function with given fields: userID, expiresAt, w, r
+func (_m *ChatService) HandleWebSocket(userID uint, expiresAt time.Time, w http.ResponseWriter, r *http.Request) *shared.HttpError {
+	ret := _m.Called(userID, expiresAt, w, r)
+
+	if len(ret) == 0 {
+		panic("no return value specified for HandleWebSocket")
+	}
+
+	var r0 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, time.Time, http.ResponseWriter, *http.Request) *shared.HttpError); ok {
+		r0 = rf(userID, expiresAt, w, r)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*shared.HttpError)
+		}
+	}
+
+	return r0
+}
+
+// IssueTicket provides a mock function with given fields: userID, expiresAt
+func (_m *ChatService) IssueTicket(userID uint, expiresAt time.Time) (*chat.TicketResponse, *shared.HttpError) {
+	ret := _m.Called(userID, expiresAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IssueTicket")
+	}
+
+	var r0 *chat.TicketResponse
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, time.Time) (*chat.TicketResponse, *shared.HttpError)); ok {
+		return rf(userID, expiresAt)
+	}
+	if rf, ok := ret.Get(0).(func(uint, time.Time) *chat.TicketResponse); ok {
+		r0 = rf(userID, expiresAt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*chat.TicketResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, time.Time) *shared.HttpError); ok {
+		r1 = rf(userID, expiresAt)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
+}
+
+// JoinByInvite provides a mock function with given fields: userID, code
+func (_m *ChatService) JoinByInvite(userID uint, code string) (*chat.JoinResponse, *shared.HttpError) {
+	ret := _m.Called(userID, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for JoinByInvite")
+	}
+
+	var r0 *chat.JoinResponse
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, string) (*chat.JoinResponse, *shared.HttpError)); ok {
+		return rf(userID, code)
+	}
+	if rf, ok := ret.Get(0).(func(uint, string) *chat.JoinResponse); ok {
+		r0 = rf(userID, code)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*chat.JoinResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, string) *shared.HttpError); ok {
+		r1 = rf(userID, code)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
+}
+
+// Leave provides a mock function with given fields: userID, chatID
+func (_m *ChatService) Leave(userID uint, chatID uint) *shared.HttpError {
+	ret := _m.Called(userID, chatID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Leave")
+	}
+
+	var r0 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint) *shared.HttpError); ok {
+		r0 = rf(userID, chatID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*shared.HttpError)
+		}
+	}
+
+	return r0
+}
+
+// MarkRead provides a mock function with given fields: userID, chatID, req
+func (_m *ChatService) MarkRead(userID uint, chatID uint, req chat.ReadRequest) *shared.HttpError {
+	ret := _m.Called(userID, chatID, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkRead")
+	}
+
+	var r0 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, chat.ReadRequest) *shared.HttpError); ok {
+		r0 = rf(userID, chatID, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*shared.HttpError)
+		}
+	}
+
+	return r0
+}
+
+// OpenAttachment provides a mock function with given fields: userID, chatID, attachmentID, thumbnail
+func (_m *ChatService) OpenAttachment(userID uint, chatID uint, attachmentID uint, thumbnail bool) (*chat.AttachmentFile, *shared.HttpError) {
+	ret := _m.Called(userID, chatID, attachmentID, thumbnail)
+
+	if len(ret) == 0 {
+		panic("no return value specified for OpenAttachment")
+	}
+
+	var r0 *chat.AttachmentFile
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, bool) (*chat.AttachmentFile, *shared.HttpError)); ok {
+		return rf(userID, chatID, attachmentID, thumbnail)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, bool) *chat.AttachmentFile); ok {
+		r0 = rf(userID, chatID, attachmentID, thumbnail)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*chat.AttachmentFile)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, uint, bool) *shared.HttpError); ok {
+		r1 = rf(userID, chatID, attachmentID, thumbnail)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
+}
+
+// PinMessage provides a mock function with given fields: actorID, chatID, messageID
+func (_m *ChatService) PinMessage(actorID uint, chatID uint, messageID uint) *shared.HttpError {
+	ret := _m.Called(actorID, chatID, messageID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PinMessage")
+	}
+
+	var r0 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, uint) *shared.HttpError); ok {
+		r0 = rf(actorID, chatID, messageID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*shared.HttpError)
+		}
+	}
+
+	return r0
+}
+
+// RemoveMember provides a mock function with given fields: actorID, chatID, userID
+func (_m *ChatService) RemoveMember(actorID uint, chatID uint, userID uint) *shared.HttpError {
+	ret := _m.Called(actorID, chatID, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveMember")
+	}
+
+	var r0 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, uint) *shared.HttpError); ok {
+		r0 = rf(actorID, chatID, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*shared.HttpError)
+		}
+	}
+
+	return r0
+}
+
+// RemoveReaction provides a mock function with given fields: userID, chatID, messageID, emoji
+func (_m *ChatService) RemoveReaction(userID uint, chatID uint, messageID uint, emoji string) (*ws.Reaction, *shared.HttpError) {
+	ret := _m.Called(userID, chatID, messageID, emoji)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveReaction")
+	}
+
+	var r0 *ws.Reaction
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, string) (*ws.Reaction, *shared.HttpError)); ok {
+		return rf(userID, chatID, messageID, emoji)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, string) *ws.Reaction); ok {
+		r0 = rf(userID, chatID, messageID, emoji)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ws.Reaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, uint, string) *shared.HttpError); ok {
+		r1 = rf(userID, chatID, messageID, emoji)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
+}
+
+// RetractVote provides a mock function with given fields: userID, chatID, messageID
+func (_m *ChatService) RetractVote(userID uint, chatID uint, messageID uint) (*repository.PollDTO, *shared.HttpError) {
+	ret := _m.Called(userID, chatID, messageID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RetractVote")
+	}
+
+	var r0 *repository.PollDTO
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, uint) (*repository.PollDTO, *shared.HttpError)); ok {
+		return rf(userID, chatID, messageID)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, uint) *repository.PollDTO); ok {
+		r0 = rf(userID, chatID, messageID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.PollDTO)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, uint) *shared.HttpError); ok {
+		r1 = rf(userID, chatID, messageID)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
+}
+
+// RevokeInvite provides a mock function with given fields: actorID, chatID, inviteID
+func (_m *ChatService) RevokeInvite(actorID uint, chatID uint, inviteID uint) *shared.HttpError {
+	ret := _m.Called(actorID, chatID, inviteID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeInvite")
+	}
+
+	var r0 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, uint) *shared.HttpError); ok {
+		r0 = rf(actorID, chatID, inviteID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*shared.HttpError)
+		}
+	}
+
+	return r0
+}
+
+// ScheduleMessage provides a mock function with given fields: userID, chatID, req
+func (_m *ChatService) ScheduleMessage(userID uint, chatID uint, req chat.ScheduleMessageRequest) (*repository.ScheduledMessageDTO, *shared.HttpError) {
+	ret := _m.Called(userID, chatID, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ScheduleMessage")
+	}
+
+	var r0 *repository.ScheduledMessageDTO
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, chat.ScheduleMessageRequest) (*repository.ScheduledMessageDTO, *shared.HttpError)); ok {
+		return rf(userID, chatID, req)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, chat.ScheduleMessageRequest) *repository.ScheduledMessageDTO); ok {
+		r0 = rf(userID, chatID, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.ScheduledMessageDTO)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, chat.ScheduleMessageRequest) *shared.HttpError); ok {
+		r1 = rf(userID, chatID, req)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
+}
+
+// Search provides a mock function with given fields: userID, req
+func (_m *ChatService) Search(userID uint, req chat.SearchRequest) (*chat.SearchResponse, *shared.HttpError) {
+	ret := _m.Called(userID, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Search")
+	}
+
+	var r0 *chat.SearchResponse
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, chat.SearchRequest) (*chat.SearchResponse, *shared.HttpError)); ok {
+		return rf(userID, req)
+	}
+	if rf, ok := ret.Get(0).(func(uint, chat.SearchRequest) *chat.SearchResponse); ok {
+		r0 = rf(userID, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*chat.SearchResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, chat.SearchRequest) *shared.HttpError); ok {
+		r1 = rf(userID, req)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
+}
+
+// SetRetention provides a mock function with given fields: actorID, chatID, req
+func (_m *ChatService) SetRetention(actorID uint, chatID uint, req chat.RetentionRequest) *shared.HttpError {
+	ret := _m.Called(actorID, chatID, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetRetention")
+	}
+
+	var r0 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, chat.RetentionRequest) *shared.HttpError); ok {
+		r0 = rf(actorID, chatID, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*shared.HttpError)
+		}
+	}
+
+	return r0
+}
+
+// SetRole provides a mock function with given fields: actorID, chatID, userID, req
+func (_m *ChatService) SetRole(actorID uint, chatID uint, userID uint, req chat.RoleRequest) *shared.HttpError {
+	ret := _m.Called(actorID, chatID, userID, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetRole")
+	}
+
+	var r0 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, chat.RoleRequest) *shared.HttpError); ok {
+		r0 = rf(actorID, chatID, userID, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*shared.HttpError)
+		}
+	}
+
+	return r0
+}
+
+// UnpinMessage provides a mock function with given fields: actorID, chatID, messageID
+func (_m *ChatService) UnpinMessage(actorID uint, chatID uint, messageID uint) *shared.HttpError {
+	ret := _m.Called(actorID, chatID, messageID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UnpinMessage")
+	}
+
+	var r0 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, uint) *shared.HttpError); ok {
+		r0 = rf(actorID, chatID, messageID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*shared.HttpError)
+		}
+	}
+
+	return r0
+}
+
+// Update provides a mock function with given fields: actorID, chatID, req
+func (_m *ChatService) Update(actorID uint, chatID uint, req chat.UpdateRequest) *shared.HttpError {
+	ret := _m.Called(actorID, chatID, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, chat.UpdateRequest) *shared.HttpError); ok {
+		r0 = rf(actorID, chatID, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*shared.HttpError)
+		}
+	}
+
+	return r0
+}
+
+// UpdateSettings provides a mock function with given fields: userID, chatID, req
+func (_m *ChatService) UpdateSettings(userID uint, chatID uint, req chat.SettingsRequest) (*repository.ChatSettingsDTO, *shared.HttpError) {
+	ret := _m.Called(userID, chatID, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateSettings")
+	}
+
+	var r0 *repository.ChatSettingsDTO
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, chat.SettingsRequest) (*repository.ChatSettingsDTO, *shared.HttpError)); ok {
+		return rf(userID, chatID, req)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, chat.SettingsRequest) *repository.ChatSettingsDTO); ok {
+		r0 = rf(userID, chatID, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.ChatSettingsDTO)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, chat.SettingsRequest) *shared.HttpError); ok {
+		r1 = rf(userID, chatID, req)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
+}
+
+// UploadAttachment provides a mock function with given fields: userID, chatID, fileName, size, file
+func (_m *ChatService) UploadAttachment(userID uint, chatID uint, fileName string, size int64, file io.Reader) (*repository.AttachmentDTO, *shared.HttpError) {
+	ret := _m.Called(userID, chatID, fileName, size, file)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UploadAttachment")
+	}
+
+	var r0 *repository.AttachmentDTO
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, string, int64, io.Reader) (*repository.AttachmentDTO, *shared.HttpError)); ok {
+		return rf(userID, chatID, fileName, size, file)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, string, int64, io.Reader) *repository.AttachmentDTO); ok {
+		r0 = rf(userID, chatID, fileName, size, file)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.AttachmentDTO)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, string, int64, io.Reader) *shared.HttpError); ok {
+		r1 = rf(userID, chatID, fileName, size, file)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
+}
+
+// Vote provides a mock function with given fields: userID, chatID, messageID, req
+func (_m *ChatService) Vote(userID uint, chatID uint, messageID uint, req chat.VoteRequest) (*repository.PollDTO, *shared.HttpError) {
+	ret := _m.Called(userID, chatID, messageID, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Vote")
+	}
+
+	var r0 *repository.PollDTO
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, chat.VoteRequest) (*repository.PollDTO, *shared.HttpError)); ok {
+		return rf(userID, chatID, messageID, req)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, chat.VoteRequest) *repository.PollDTO); ok {
+		r0 = rf(userID, chatID, messageID, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.PollDTO)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, uint, chat.VoteRequest) *shared.HttpError); ok {
+		r1 = rf(userID, chatID, messageID, req)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
 }
 
 // NewChatService creates a new instance of ChatService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
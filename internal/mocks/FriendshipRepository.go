@@ -13,6 +13,34 @@ type FriendshipRepository struct {
 	mock.Mock
 }
 
+// AreFriends provides a mock function with given fields: userID, otherID
+func (_m *FriendshipRepository) AreFriends(userID uint, otherID uint) (bool, error) {
+	ret := _m.Called(userID, otherID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AreFriends")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint) (bool, error)); ok {
+		return rf(userID, otherID)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint) bool); ok {
+		r0 = rf(userID, otherID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint) error); ok {
+		r1 = rf(userID, otherID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Exists provides a mock function with given fields: senderID, receiverID
 func (_m *FriendshipRepository) Exists(senderID uint, receiverID uint) (bool, error) {
 	ret := _m.Called(senderID, receiverID)
@@ -4,6 +4,7 @@ package mocks
 
 import (
 	ws "socialAPI/internal/api/chat/ws"
+	repository "socialAPI/internal/storage/repository"
 
 	mock "github.com/stretchr/testify/mock"
 )
@@ -18,6 +19,94 @@ func (_m *Hub) BroadcastMessage(msg ws.Message) {
 	_m.Called(msg)
 }
 
+// MarkRead provides a mock function with given fields: userID, chatID, messageID
+func (_m *Hub) MarkRead(userID uint, chatID uint, messageID uint) (bool, error) {
+	ret := _m.Called(userID, chatID, messageID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkRead")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint, uint) (bool, error)); ok {
+		return rf(userID, chatID, messageID)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, uint) bool); ok {
+		r0 = rf(userID, chatID, messageID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, uint) error); ok {
+		r1 = rf(userID, chatID, messageID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PublishExpired provides a mock function with given fields: chatID, messageIDs
+func (_m *Hub) PublishExpired(chatID uint, messageIDs []uint) {
+	_m.Called(chatID, messageIDs)
+}
+
+// PublishMessage provides a mock function with given fields: record
+func (_m *Hub) PublishMessage(record repository.Message) {
+	_m.Called(record)
+}
+
+// PublishMessageChange provides a mock function with given fields: eventType, record
+func (_m *Hub) PublishMessageChange(eventType ws.EventType, record repository.Message) {
+	_m.Called(eventType, record)
+}
+
+// PublishPin provides a mock function with given fields: eventType, chatID, pin
+func (_m *Hub) PublishPin(eventType ws.EventType, chatID uint, pin ws.Pin) {
+	_m.Called(eventType, chatID, pin)
+}
+
+// PublishPoll provides a mock function with given fields: chatID, messageID, poll
+func (_m *Hub) PublishPoll(chatID uint, messageID uint, poll *repository.Poll) {
+	_m.Called(chatID, messageID, poll)
+}
+
+// PublishSaved provides a mock function with given fields: records
+func (_m *Hub) PublishSaved(records []repository.Message) {
+	_m.Called(records)
+}
+
+// React provides a mock function with given fields: userID, chatID, messageID, emoji, add
+func (_m *Hub) React(userID uint, chatID uint, messageID uint, emoji string, add bool) (*ws.Reaction, error) {
+	ret := _m.Called(userID, chatID, messageID, emoji, add)
+
+	if len(ret) == 0 {
+		panic("no return value specified for React")
+	}
+
+	var r0 *ws.Reaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, string, bool) (*ws.Reaction, error)); ok {
+		return rf(userID, chatID, messageID, emoji, add)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, string, bool) *ws.Reaction); ok {
+		r0 = rf(userID, chatID, messageID, emoji, add)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ws.Reaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, uint, string, bool) error); ok {
+		r1 = rf(userID, chatID, messageID, emoji, add)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RegisterClient provides a mock function with given fields: client
 func (_m *Hub) RegisterClient(client *ws.Client) {
 	_m.Called(client)
@@ -28,11 +117,51 @@ func (_m *Hub) Run() {
 	_m.Called()
 }
 
+// SetTyping provides a mock function with given fields: client, chatID, typing
+func (_m *Hub) SetTyping(client *ws.Client, chatID uint, typing bool) {
+	_m.Called(client, chatID, typing)
+}
+
 // UnregisterClient provides a mock function with given fields: client
 func (_m *Hub) UnregisterClient(client *ws.Client) {
 	_m.Called(client)
 }
 
+// UpdateMembership provides a mock function with given fields: chatID, afterID, joined, left
+func (_m *Hub) UpdateMembership(chatID uint, afterID uint, joined []uint, left []uint) {
+	_m.Called(chatID, afterID, joined, left)
+}
+
+// Vote provides a mock function with given fields: userID, chatID, messageID, optionIDs
+func (_m *Hub) Vote(userID uint, chatID uint, messageID uint, optionIDs []uint) (*repository.PollDTO, error) {
+	ret := _m.Called(userID, chatID, messageID, optionIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Vote")
+	}
+
+	var r0 *repository.PollDTO
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, []uint) (*repository.PollDTO, error)); ok {
+		return rf(userID, chatID, messageID, optionIDs)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, []uint) *repository.PollDTO); ok {
+		r0 = rf(userID, chatID, messageID, optionIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.PollDTO)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, uint, []uint) error); ok {
+		r1 = rf(userID, chatID, messageID, optionIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewHub creates a new instance of Hub. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewHub(t interface {
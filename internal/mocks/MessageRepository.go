@@ -2,13 +2,104 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	repository "socialAPI/internal/storage/repository"
+	time "time"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // MessageRepository is an autogenerated mock type for the MessageRepository type
 type MessageRepository struct {
 	mock.Mock
 }
 
+// AddReaction provides a mock function with given fields: messageID, userID, emoji
+func (_m *MessageRepository) AddReaction(messageID uint, userID uint, emoji string) (bool, error) {
+	ret := _m.Called(messageID, userID, emoji)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddReaction")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint, string) (bool, error)); ok {
+		return rf(messageID, userID, emoji)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, string) bool); ok {
+		r0 = rf(messageID, userID, emoji)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, string) error); ok {
+		r1 = rf(messageID, userID, emoji)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ClosePoll provides a mock function with given fields: messageID
+func (_m *MessageRepository) ClosePoll(messageID uint) (*repository.Poll, error) {
+	ret := _m.Called(messageID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClosePoll")
+	}
+
+	var r0 *repository.Poll
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint) (*repository.Poll, error)); ok {
+		return rf(messageID)
+	}
+	if rf, ok := ret.Get(0).(func(uint) *repository.Poll); ok {
+		r0 = rf(messageID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Poll)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint) error); ok {
+		r1 = rf(messageID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountReactions provides a mock function with given fields: messageID, emoji
+func (_m *MessageRepository) CountReactions(messageID uint, emoji string) (int64, error) {
+	ret := _m.Called(messageID, emoji)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountReactions")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, string) (int64, error)); ok {
+		return rf(messageID, emoji)
+	}
+	if rf, ok := ret.Get(0).(func(uint, string) int64); ok {
+		r0 = rf(messageID, emoji)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, string) error); ok {
+		r1 = rf(messageID, emoji)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Create provides a mock function with given fields: chatID, senderID, content
 func (_m *MessageRepository) Create(chatID uint, senderID uint, content string) error {
 	ret := _m.Called(chatID, senderID, content)
@@ -27,6 +118,449 @@ func (_m *MessageRepository) Create(chatID uint, senderID uint, content string)
 	return r0
 }
 
+// CreateBatch provides a mock function with given fields: messages
+func (_m *MessageRepository) CreateBatch(messages []*repository.Message) error {
+	ret := _m.Called(messages)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateBatch")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]*repository.Message) error); ok {
+		r0 = rf(messages)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteExpired provides a mock function with given fields: now, maxAge, limit
+func (_m *MessageRepository) DeleteExpired(now time.Time, maxAge time.Duration, limit int) ([]repository.ExpiredMessage, []string, error) {
+	ret := _m.Called(now, maxAge, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteExpired")
+	}
+
+	var r0 []repository.ExpiredMessage
+	var r1 []string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(time.Time, time.Duration, int) ([]repository.ExpiredMessage, []string, error)); ok {
+		return rf(now, maxAge, limit)
+	}
+	if rf, ok := ret.Get(0).(func(time.Time, time.Duration, int) []repository.ExpiredMessage); ok {
+		r0 = rf(now, maxAge, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.ExpiredMessage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(time.Time, time.Duration, int) []string); ok {
+		r1 = rf(now, maxAge, limit)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(time.Time, time.Duration, int) error); ok {
+		r2 = rf(now, maxAge, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Edit provides a mock function with given fields: messageID, editorID, content
+func (_m *MessageRepository) Edit(messageID uint, editorID uint, content string) (*repository.Message, error) {
+	ret := _m.Called(messageID, editorID, content)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Edit")
+	}
+
+	var r0 *repository.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint, string) (*repository.Message, error)); ok {
+		return rf(messageID, editorID, content)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, string) *repository.Message); ok {
+		r0 = rf(messageID, editorID, content)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, string) error); ok {
+		r1 = rf(messageID, editorID, content)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Forward provides a mock function with given fields: source, senderID, chatIDs
+func (_m *MessageRepository) Forward(source *repository.Message, senderID uint, chatIDs []uint) ([]repository.Message, error) {
+	ret := _m.Called(source, senderID, chatIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Forward")
+	}
+
+	var r0 []repository.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*repository.Message, uint, []uint) ([]repository.Message, error)); ok {
+		return rf(source, senderID, chatIDs)
+	}
+	if rf, ok := ret.Get(0).(func(*repository.Message, uint, []uint) []repository.Message); ok {
+		r0 = rf(source, senderID, chatIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*repository.Message, uint, []uint) error); ok {
+		r1 = rf(source, senderID, chatIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByID provides a mock function with given fields: messageID
+func (_m *MessageRepository) GetByID(messageID uint) (*repository.Message, error) {
+	ret := _m.Called(messageID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByID")
+	}
+
+	var r0 *repository.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint) (*repository.Message, error)); ok {
+		return rf(messageID)
+	}
+	if rf, ok := ret.Get(0).(func(uint) *repository.Message); ok {
+		r0 = rf(messageID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint) error); ok {
+		r1 = rf(messageID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByIDs provides a mock function with given fields: messageIDs
+func (_m *MessageRepository) GetByIDs(messageIDs []uint) ([]repository.Message, error) {
+	ret := _m.Called(messageIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByIDs")
+	}
+
+	var r0 []repository.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]uint) ([]repository.Message, error)); ok {
+		return rf(messageIDs)
+	}
+	if rf, ok := ret.Get(0).(func([]uint) []repository.Message); ok {
+		r0 = rf(messageIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]uint) error); ok {
+		r1 = rf(messageIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListAfter provides a mock function with given fields: cursors, limit
+func (_m *MessageRepository) ListAfter(cursors map[uint]uint, limit int) ([]repository.Message, error) {
+	ret := _m.Called(cursors, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAfter")
+	}
+
+	var r0 []repository.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(map[uint]uint, int) ([]repository.Message, error)); ok {
+		return rf(cursors, limit)
+	}
+	if rf, ok := ret.Get(0).(func(map[uint]uint, int) []repository.Message); ok {
+		r0 = rf(cursors, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(map[uint]uint, int) error); ok {
+		r1 = rf(cursors, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListMentions provides a mock function with given fields: userID, beforeID, limit
+func (_m *MessageRepository) ListMentions(userID uint, beforeID uint, limit int) ([]repository.Message, error) {
+	ret := _m.Called(userID, beforeID, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListMentions")
+	}
+
+	var r0 []repository.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint, int) ([]repository.Message, error)); ok {
+		return rf(userID, beforeID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, int) []repository.Message); ok {
+		r0 = rf(userID, beforeID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, int) error); ok {
+		r1 = rf(userID, beforeID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListPins provides a mock function with given fields: chatID
+func (_m *MessageRepository) ListPins(chatID uint) ([]repository.PinnedMessage, error) {
+	ret := _m.Called(chatID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListPins")
+	}
+
+	var r0 []repository.PinnedMessage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint) ([]repository.PinnedMessage, error)); ok {
+		return rf(chatID)
+	}
+	if rf, ok := ret.Get(0).(func(uint) []repository.PinnedMessage); ok {
+		r0 = rf(chatID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.PinnedMessage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint) error); ok {
+		r1 = rf(chatID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListReplies provides a mock function with given fields: parentID, afterID, limit
+func (_m *MessageRepository) ListReplies(parentID uint, afterID uint, limit int) ([]repository.Message, error) {
+	ret := _m.Called(parentID, afterID, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListReplies")
+	}
+
+	var r0 []repository.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint, int) ([]repository.Message, error)); ok {
+		return rf(parentID, afterID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, int) []repository.Message); ok {
+		r0 = rf(parentID, afterID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, int) error); ok {
+		r1 = rf(parentID, afterID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Pin provides a mock function with given fields: chatID, messageID, userID, limit
+func (_m *MessageRepository) Pin(chatID uint, messageID uint, userID uint, limit int) (*repository.PinnedMessage, error) {
+	ret := _m.Called(chatID, messageID, userID, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Pin")
+	}
+
+	var r0 *repository.PinnedMessage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, int) (*repository.PinnedMessage, error)); ok {
+		return rf(chatID, messageID, userID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, uint, int) *repository.PinnedMessage); ok {
+		r0 = rf(chatID, messageID, userID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.PinnedMessage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, uint, int) error); ok {
+		r1 = rf(chatID, messageID, userID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RemoveReaction provides a mock function with given fields: messageID, userID, emoji
+func (_m *MessageRepository) RemoveReaction(messageID uint, userID uint, emoji string) (bool, error) {
+	ret := _m.Called(messageID, userID, emoji)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveReaction")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint, string) (bool, error)); ok {
+		return rf(messageID, userID, emoji)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, string) bool); ok {
+		r0 = rf(messageID, userID, emoji)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, string) error); ok {
+		r1 = rf(messageID, userID, emoji)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SoftDelete provides a mock function with given fields: messageID, editorID
+func (_m *MessageRepository) SoftDelete(messageID uint, editorID uint) (*repository.Message, error) {
+	ret := _m.Called(messageID, editorID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SoftDelete")
+	}
+
+	var r0 *repository.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint) (*repository.Message, error)); ok {
+		return rf(messageID, editorID)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint) *repository.Message); ok {
+		r0 = rf(messageID, editorID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint) error); ok {
+		r1 = rf(messageID, editorID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Unpin provides a mock function with given fields: chatID, messageID
+func (_m *MessageRepository) Unpin(chatID uint, messageID uint) (bool, error) {
+	ret := _m.Called(chatID, messageID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Unpin")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint) (bool, error)); ok {
+		return rf(chatID, messageID)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint) bool); ok {
+		r0 = rf(chatID, messageID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint) error); ok {
+		r1 = rf(chatID, messageID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Vote provides a mock function with given fields: messageID, userID, optionIDs
+func (_m *MessageRepository) Vote(messageID uint, userID uint, optionIDs []uint) (*repository.Poll, error) {
+	ret := _m.Called(messageID, userID, optionIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Vote")
+	}
+
+	var r0 *repository.Poll
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint, uint, []uint) (*repository.Poll, error)); ok {
+		return rf(messageID, userID, optionIDs)
+	}
+	if rf, ok := ret.Get(0).(func(uint, uint, []uint) *repository.Poll); ok {
+		r0 = rf(messageID, userID, optionIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Poll)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, uint, []uint) error); ok {
+		r1 = rf(messageID, userID, optionIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewMessageRepository creates a new instance of MessageRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMessageRepository(t interface {
@@ -13,6 +13,26 @@ type Repository struct {
 	mock.Mock
 }
 
+// Attachments provides a mock function with no fields
+func (_m *Repository) Attachments() repository.AttachmentRepository {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Attachments")
+	}
+
+	var r0 repository.AttachmentRepository
+	if rf, ok := ret.Get(0).(func() repository.AttachmentRepository); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(repository.AttachmentRepository)
+		}
+	}
+
+	return r0
+}
+
 // Chats provides a mock function with no fields
 func (_m *Repository) Chats() repository.ChatRepository {
 	ret := _m.Called()
@@ -93,6 +113,26 @@ func (_m *Repository) RefreshTokens() repository.RefreshTokenService {
 	return r0
 }
 
+// ScheduledMessages provides a mock function with no fields
+func (_m *Repository) ScheduledMessages() repository.ScheduledMessageRepository {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ScheduledMessages")
+	}
+
+	var r0 repository.ScheduledMessageRepository
+	if rf, ok := ret.Get(0).(func() repository.ScheduledMessageRepository); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(repository.ScheduledMessageRepository)
+		}
+	}
+
+	return r0
+}
+
 // Users provides a mock function with no fields
 func (_m *Repository) Users() repository.UserRepository {
 	ret := _m.Called()
@@ -5,14 +5,11 @@ package mocks
 import (
 	auth "socialAPI/internal/api/auth"
 	chat "socialAPI/internal/api/chat"
-
 	friendship "socialAPI/internal/api/friendship"
-
-	mock "github.com/stretchr/testify/mock"
-
+	user "socialAPI/internal/api/user"
 	shared "socialAPI/internal/shared"
 
-	user "socialAPI/internal/api/user"
+	mock "github.com/stretchr/testify/mock"
 )
 
 // Service is an autogenerated mock type for the Service type
@@ -120,6 +117,26 @@ func (_m *Service) User() user.UserService {
 	return r0
 }
 
+// WSAuth provides a mock function with no fields
+func (_m *Service) WSAuth() shared.WSAuthService {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for WSAuth")
+	}
+
+	var r0 shared.WSAuthService
+	if rf, ok := ret.Get(0).(func() shared.WSAuthService); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(shared.WSAuthService)
+		}
+	}
+
+	return r0
+}
+
 // NewService creates a new instance of Service. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewService(t interface {
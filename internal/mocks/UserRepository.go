@@ -4,6 +4,7 @@ package mocks
 
 import (
 	repository "socialAPI/internal/storage/repository"
+	time "time"
 
 	mock "github.com/stretchr/testify/mock"
 )
@@ -119,6 +120,66 @@ func (_m *UserRepository) GetAll(excludeID *uint) ([]repository.User, error) {
 	return r0, r1
 }
 
+// GetContactIDs provides a mock function with given fields: userID
+func (_m *UserRepository) GetContactIDs(userID uint) ([]uint, error) {
+	ret := _m.Called(userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetContactIDs")
+	}
+
+	var r0 []uint
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint) ([]uint, error)); ok {
+		return rf(userID)
+	}
+	if rf, ok := ret.Get(0).(func(uint) []uint); ok {
+		r0 = rf(userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]uint)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint) error); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPresence provides a mock function with given fields: IDs
+func (_m *UserRepository) GetPresence(IDs []uint) ([]repository.User, error) {
+	ret := _m.Called(IDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPresence")
+	}
+
+	var r0 []repository.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]uint) ([]repository.User, error)); ok {
+		return rf(IDs)
+	}
+	if rf, ok := ret.Get(0).(func([]uint) []repository.User); ok {
+		r0 = rf(IDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]uint) error); ok {
+		r1 = rf(IDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // IDsExists provides a mock function with given fields: IDs
 func (_m *UserRepository) IDsExists(IDs []uint) (bool, error) {
 	ret := _m.Called(IDs)
@@ -147,6 +208,42 @@ func (_m *UserRepository) IDsExists(IDs []uint) (bool, error) {
 	return r0, r1
 }
 
+// SetHidePresence provides a mock function with given fields: userID, hide
+func (_m *UserRepository) SetHidePresence(userID uint, hide bool) error {
+	ret := _m.Called(userID, hide)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetHidePresence")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uint, bool) error); ok {
+		r0 = rf(userID, hide)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetLastSeen provides a mock function with given fields: userID, at
+func (_m *UserRepository) SetLastSeen(userID uint, at time.Time) error {
+	ret := _m.Called(userID, at)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetLastSeen")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uint, time.Time) error); ok {
+		r0 = rf(userID, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewUserRepository creates a new instance of UserRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewUserRepository(t interface {
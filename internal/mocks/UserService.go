@@ -3,11 +3,11 @@
 package mocks
 
 import (
+	user "socialAPI/internal/api/user"
+	shared "socialAPI/internal/shared"
 	repository "socialAPI/internal/storage/repository"
 
 	mock "github.com/stretchr/testify/mock"
-
-	shared "socialAPI/internal/shared"
 )
 
 // UserService is an autogenerated mock type for the UserService type
@@ -47,6 +47,58 @@ func (_m *UserService) GetAllUsers(excludeID *uint) ([]repository.User, *shared.
 	return r0, r1
 }
 
+// GetPresence provides a mock function with given fields: viewerID, userIDs
+func (_m *UserService) GetPresence(viewerID uint, userIDs []uint) ([]user.PresenceResponse, *shared.HttpError) {
+	ret := _m.Called(viewerID, userIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPresence")
+	}
+
+	var r0 []user.PresenceResponse
+	var r1 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, []uint) ([]user.PresenceResponse, *shared.HttpError)); ok {
+		return rf(viewerID, userIDs)
+	}
+	if rf, ok := ret.Get(0).(func(uint, []uint) []user.PresenceResponse); ok {
+		r0 = rf(viewerID, userIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]user.PresenceResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint, []uint) *shared.HttpError); ok {
+		r1 = rf(viewerID, userIDs)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*shared.HttpError)
+		}
+	}
+
+	return r0, r1
+}
+
+// SetPrivacy provides a mock function with given fields: userID, req
+func (_m *UserService) SetPrivacy(userID uint, req user.PrivacyRequest) *shared.HttpError {
+	ret := _m.Called(userID, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetPrivacy")
+	}
+
+	var r0 *shared.HttpError
+	if rf, ok := ret.Get(0).(func(uint, user.PrivacyRequest) *shared.HttpError); ok {
+		r0 = rf(userID, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*shared.HttpError)
+		}
+	}
+
+	return r0
+}
+
 // NewUserService creates a new instance of UserService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewUserService(t interface {
@@ -8,18 +8,25 @@ type Config struct {
 	Auth   AuthConfig
 	DB     DBConfig
 	Redis  RedisConfig
+	Hub    HubConfig
+	Blob   BlobConfig
+	Upload UploadConfig
+	Chat   ChatConfig
 }
 
 type ServerConfig struct {
 	Addr             string
 	OriginsSeparator string
 	AllowedOrigins   []string
+	// Адрес внутреннего листенера для /debug/vars, пустой - отключён
+	DebugAddr string
 }
 
 type AuthConfig struct {
 	AccessTTL    time.Duration
 	RefreshTTL   time.Duration
 	AccessSecret string
+	WSTicketTTL  time.Duration
 }
 
 type DBConfig struct {
@@ -37,3 +44,47 @@ type RedisConfig struct {
 	Password string
 	DB       int
 }
+
+type HubConfig struct {
+	Backplane        string
+	PersistWorkers   int
+	PersistBatchSize int
+	PersistQueueSize int
+	ReplayLimit      int
+	PongWait         time.Duration
+	PingPeriod       time.Duration
+	WriteWait        time.Duration
+	TypingTTL        time.Duration
+	TypingThrottle   time.Duration
+	PresenceTTL      time.Duration
+	AllowedReactions []string
+}
+
+type BlobConfig struct {
+	Backend     string
+	LocalDir    string
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+}
+
+type UploadConfig struct {
+	MaxSize          int64
+	ThumbnailSize    int
+	ThumbnailWorkers int
+	// Сколько живёт загруженное, но не отправленное вложение
+	UnattachedTTL time.Duration
+}
+
+type ChatConfig struct {
+	DirectFriendsOnly  bool
+	MaxPins            int
+	SchedulerInterval  time.Duration
+	SchedulerBatchSize int
+	// Глобальный предел хранения сообщений для всех чатов, 0 - без предела
+	RetentionMaxAge time.Duration
+	SweepInterval   time.Duration
+	SweepBatchSize  int
+}
@@ -19,6 +19,8 @@ func NewUpgrader(allowedOrigins []string) Upgrader {
 		upgrader: &websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
+			// Тикет может прийти парой подпротоколов ["ticket", "<ticket>"], в ответ выбираем "ticket"
+			Subprotocols: []string{"ticket"},
 			CheckOrigin: func(r *http.Request) bool {
 				origin := r.Header.Get("Origin")
 				for _, allowedOrigin := range allowedOrigins {
@@ -1,6 +1,7 @@
 package setting
 
 import (
+	"expvar"
 	"fmt"
 	"net/http"
 	"socialAPI/internal/api"
@@ -13,8 +14,10 @@ import (
 	"socialAPI/internal/setting/cfg"
 	"socialAPI/internal/shared"
 	"socialAPI/internal/storage"
+	"socialAPI/internal/storage/blob"
 	"socialAPI/internal/storage/cache"
 	"socialAPI/internal/storage/repository"
+	"socialAPI/internal/storage/search"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -26,6 +29,7 @@ import (
 type WebSocket struct {
 	hub      ws.Hub
 	upgrader cfg.Upgrader
+	presence cache.PresenceStore
 }
 
 type App struct {
@@ -47,11 +51,13 @@ func (a *App) LoadConfig() {
 			Addr:             addr,
 			OriginsSeparator: originsSeparator,
 			AllowedOrigins:   lib.GetListFromEnv("ALLOWED_ORIGINS", originsSeparator, []string{"localhost" + addr}),
+			DebugAddr:        lib.GetStringFromEnv("DEBUG_ADDR", "localhost:6060"),
 		},
 		Auth: cfg.AuthConfig{
 			AccessTTL:    lib.GetDurationFromEnv("ACCESS_TTL", 15*time.Minute),
 			RefreshTTL:   lib.GetDurationFromEnv("REFRESH_TTL", 720*time.Hour),
 			AccessSecret: lib.GetStringFromEnv("ACCESS_SECRET", "supersecretaccess"),
+			WSTicketTTL:  lib.GetDurationFromEnv("WS_TICKET_TTL", 30*time.Second),
 		},
 		DB: cfg.DBConfig{
 			Host:     lib.GetStringFromEnv("DB_HOST", "localhost"),
@@ -67,6 +73,44 @@ func (a *App) LoadConfig() {
 			Password: lib.GetStringFromEnv("REDIS_PASSWORD", ""),
 			DB:       lib.GetIntFromEnv("REDIS_DB", 0),
 		},
+		Hub: cfg.HubConfig{
+			Backplane:        lib.GetStringFromEnv("HUB_BACKPLANE", "memory"),
+			PersistWorkers:   lib.GetIntFromEnv("HUB_PERSIST_WORKERS", 4),
+			PersistBatchSize: lib.GetIntFromEnv("HUB_PERSIST_BATCH_SIZE", 100),
+			PersistQueueSize: lib.GetIntFromEnv("HUB_PERSIST_QUEUE_SIZE", 1024),
+			ReplayLimit:      lib.GetIntFromEnv("HUB_REPLAY_LIMIT", 500),
+			PongWait:         lib.GetDurationFromEnv("WS_PONG_WAIT", 60*time.Second),
+			PingPeriod:       lib.GetDurationFromEnv("WS_PING_PERIOD", 54*time.Second),
+			WriteWait:        lib.GetDurationFromEnv("WS_WRITE_WAIT", 10*time.Second),
+			TypingTTL:        lib.GetDurationFromEnv("WS_TYPING_TTL", 5*time.Second),
+			TypingThrottle:   lib.GetDurationFromEnv("WS_TYPING_THROTTLE", time.Second),
+			PresenceTTL:      lib.GetDurationFromEnv("WS_PRESENCE_TTL", time.Minute),
+			AllowedReactions: lib.GetListFromEnv("CHAT_ALLOWED_REACTIONS", ",", []string{"👍", "👎", "❤️", "😂", "😮", "😢", "🔥"}),
+		},
+		Blob: cfg.BlobConfig{
+			Backend:     lib.GetStringFromEnv("BLOB_BACKEND", "local"),
+			LocalDir:    lib.GetStringFromEnv("BLOB_LOCAL_DIR", "./data/attachments"),
+			S3Endpoint:  lib.GetStringFromEnv("S3_ENDPOINT", "http://localhost:9000"),
+			S3Region:    lib.GetStringFromEnv("S3_REGION", "us-east-1"),
+			S3Bucket:    lib.GetStringFromEnv("S3_BUCKET", "attachments"),
+			S3AccessKey: lib.GetStringFromEnv("S3_ACCESS_KEY", "minioadmin"),
+			S3SecretKey: lib.GetStringFromEnv("S3_SECRET_KEY", "minioadmin"),
+		},
+		Upload: cfg.UploadConfig{
+			MaxSize:          int64(lib.GetIntFromEnv("ATTACHMENT_MAX_SIZE", 10<<20)),
+			ThumbnailSize:    lib.GetIntFromEnv("ATTACHMENT_THUMBNAIL_SIZE", 320),
+			ThumbnailWorkers: lib.GetIntFromEnv("ATTACHMENT_THUMBNAIL_WORKERS", 2),
+			UnattachedTTL:    lib.GetDurationFromEnv("ATTACHMENT_UNATTACHED_TTL", 24*time.Hour),
+		},
+		Chat: cfg.ChatConfig{
+			DirectFriendsOnly:  lib.GetBoolFromEnv("CHAT_DIRECT_FRIENDS_ONLY", false),
+			MaxPins:            lib.GetIntFromEnv("CHAT_MAX_PINS", 50),
+			SchedulerInterval:  lib.GetDurationFromEnv("CHAT_SCHEDULER_INTERVAL", time.Second),
+			SchedulerBatchSize: lib.GetIntFromEnv("CHAT_SCHEDULER_BATCH_SIZE", 100),
+			RetentionMaxAge:    lib.GetDurationFromEnv("CHAT_RETENTION_MAX_AGE", 0),
+			SweepInterval:      lib.GetDurationFromEnv("CHAT_SWEEP_INTERVAL", time.Minute),
+			SweepBatchSize:     lib.GetIntFromEnv("CHAT_SWEEP_BATCH_SIZE", 500),
+		},
 	}
 }
 
@@ -79,10 +123,58 @@ func (a *App) SetupLogger() {
 	}
 }
 
-func (a *App) setupWS(messageRepo repository.MessageRepository, chatRepo repository.ChatRepository) {
+// Backplane и хранилище присутствия выбираются вместе: при нескольких узлах оба работают через Redis
+func (a *App) setupBackplane() (ws.Backplane, cache.PresenceStore) {
+	switch a.cfg.Hub.Backplane {
+	case "memory":
+		return ws.NewMemoryBackplane(), cache.NewMemoryPresenceStore(a.cfg.Hub.PresenceTTL)
+	case "redis":
+		client, err := cache.NewRedisClient(a.cfg.Redis)
+		if err != nil {
+			a.logger.Panicw("Failed to initialize Redis backplane", "error", err)
+		}
+		return ws.NewRedisBackplane(client, a.logger), cache.NewRedisPresenceStore(client, a.cfg.Hub.PresenceTTL)
+	default:
+		a.logger.Panicw("Unknown hub backplane", "backplane", a.cfg.Hub.Backplane)
+		return nil, nil
+	}
+}
+
+func (a *App) setupBlobStore() blob.BlobStore {
+	var (
+		store blob.BlobStore
+		err   error
+	)
+
+	switch a.cfg.Blob.Backend {
+	case "local":
+		store, err = blob.NewLocalBlobStore(a.cfg.Blob.LocalDir)
+	case "s3":
+		store, err = blob.NewS3BlobStore(blob.S3Options{
+			Endpoint:  a.cfg.Blob.S3Endpoint,
+			Region:    a.cfg.Blob.S3Region,
+			Bucket:    a.cfg.Blob.S3Bucket,
+			AccessKey: a.cfg.Blob.S3AccessKey,
+			SecretKey: a.cfg.Blob.S3SecretKey,
+		})
+	default:
+		a.logger.Panicw("Unknown blob backend", "backend", a.cfg.Blob.Backend)
+	}
+
+	if err != nil {
+		a.logger.Panicw("Failed to initialize blob store", "backend", a.cfg.Blob.Backend, "error", err)
+	}
+	return store
+}
+
+func (a *App) setupWS(messageRepo repository.MessageRepository, chatRepo repository.ChatRepository, userRepo repository.UserRepository) {
+	backplane, presenceStore := a.setupBackplane()
+	presence := ws.NewPresence(presenceStore, userRepo, backplane, a.logger)
+
 	a.webSocket = WebSocket{
-		hub:      ws.NewHub(messageRepo, chatRepo, a.logger),
+		hub:      ws.NewHub(messageRepo, chatRepo, backplane, presence, a.cfg.Hub, a.logger),
 		upgrader: cfg.NewUpgrader(a.cfg.Server.AllowedOrigins),
+		presence: presenceStore,
 	}
 
 	go a.webSocket.hub.Run()
@@ -122,27 +214,33 @@ func (a *App) InitStorages(doMigrations bool) {
 func (a *App) MountServices() {
 	repo := repository.NewPostgresRepo(a.db)
 
-	a.setupWS(repo.Messages(), repo.Chats())
+	a.setupWS(repo.Messages(), repo.Chats(), repo.Users())
 
 	tokenService := shared.NewTokenService(a.cfg.Auth.AccessSecret, a.cfg.Auth.AccessTTL)
+	wsAuthService := shared.NewWSAuthService(a.cache, a.cfg.Auth.WSTicketTTL)
 	authService := auth.NewAuthService(repo.Users(), repo.RefreshTokens(), a.cfg.Auth, a.cache, tokenService, &lib.BcryptHasher{}, a.logger)
-	userService := user.NewUserService(repo.Users(), a.logger)
+	userService := user.NewUserService(repo.Users(), a.webSocket.presence, a.logger)
 	friendshipService := friendship.NewFriendshipService(repo.Friendship(), a.logger)
-	chatService := chat.NewChatService(repo.Chats(), repo.Users(), a.webSocket.hub, a.webSocket.upgrader, a.logger)
+	blobStore := a.setupBlobStore()
+	thumbnailer := chat.NewThumbnailer(blobStore, repo.Attachments(), a.cfg.Upload.ThumbnailSize, a.cfg.Upload.ThumbnailWorkers, a.logger)
+	go thumbnailer.Run()
+	go chat.NewScheduler(repo.ScheduledMessages(), a.webSocket.hub, a.cfg.Chat.SchedulerInterval, a.cfg.Chat.SchedulerBatchSize, a.logger).Run()
+	go chat.NewSweeper(repo.Messages(), repo.Attachments(), blobStore, a.webSocket.hub, a.cfg.Chat, a.cfg.Upload, a.logger).Run()
+	chatService := chat.NewChatService(repo.Chats(), repo.Users(), repo.Friendship(), repo.Messages(), repo.Attachments(), repo.ScheduledMessages(), a.webSocket.hub, blobStore, thumbnailer, search.NewPostgresSearcher(a.db), a.cfg.Chat, a.cfg.Upload, a.webSocket.upgrader, wsAuthService, a.logger)
 
-	a.service = api.NewService(authService, tokenService, userService, friendshipService, chatService)
+	a.service = api.NewService(authService, tokenService, wsAuthService, userService, friendshipService, chatService)
 }
 
 func (a App) MountRouter() *chi.Mux {
 	authController := auth.NewAuthController(a.service.Auth(), a.service.Token(), a.logger)
 	userController := user.NewUserController(a.service.User(), a.service.Token(), a.logger)
 	friendshipController := friendship.NewFriendshipController(a.service.Friendship(), a.service.Token(), a.logger)
-	chatController := chat.NewChatController(a.service.Chat(), a.service.Token(), a.logger)
+	chatController := chat.NewChatController(a.service.Chat(), a.service.Token(), a.service.WSAuth(), a.cfg.Upload.MaxSize, a.logger)
 
 	r := chi.NewRouter()
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins: a.cfg.Server.AllowedOrigins,
-		AllowedMethods: []string{"GET", "POST", "PATCH"},
+		AllowedMethods: []string{"GET", "POST", "PATCH", "DELETE"},
 		AllowedHeaders: []string{
 			"Accept",
 			"Authorization",
@@ -166,6 +264,19 @@ func (a App) MountRouter() *chi.Mux {
 	return r
 }
 
+// Метрики отдаются на отдельном адресе, недоступном снаружи, а не в публичном роутере
+func (a App) runDebugServer() {
+	r := chi.NewRouter()
+	r.Handle("/debug/vars", expvar.Handler())
+
+	if err := http.ListenAndServe(a.cfg.Server.DebugAddr, r); err != nil {
+		a.logger.Errorw("Debug server stopped", "addr", a.cfg.Server.DebugAddr, "error", err)
+	}
+}
+
 func (a App) RunServer(r *chi.Mux) {
+	if a.cfg.Server.DebugAddr != "" {
+		go a.runDebugServer()
+	}
 	http.ListenAndServe(a.cfg.Server.Addr, r)
 }
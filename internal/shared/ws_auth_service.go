@@ -0,0 +1,94 @@
+package shared
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"socialAPI/internal/storage/cache"
+	"time"
+)
+
+// WSAuthService выдаёт одноразовые тикеты для WebSocket-рукопожатия
+// (браузер не может передать заголовок Authorization) и проверяет, что сессия пользователя не отозвана
+type WSAuthService interface {
+	IssueTicket(userID uint, expiresAt time.Time) (*WSTicket, error)
+	RedeemTicket(ticket string) (*WSTicket, error)
+	IsSessionActive(userID uint) (bool, error)
+}
+
+// WSTicket описывает тикет. ExpiresAt - время истечения access токена, по которому тикет выдан:
+// открытое по тикету соединение закрывается в этот момент
+type WSTicket struct {
+	Ticket    string    `json:"-"`
+	UserID    uint      `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+var ErrInvalidTicket = errors.New("invalid or already used ticket")
+
+type cacheWSAuthService struct {
+	cache     cache.CacheStore
+	ticketTTL time.Duration
+}
+
+// NewWSAuthService creates a new WSAuthService instance
+func NewWSAuthService(cache cache.CacheStore, ticketTTL time.Duration) WSAuthService {
+	return &cacheWSAuthService{cache: cache, ticketTTL: ticketTTL}
+}
+
+// AccessTokenCacheKey returns the cache key of the user's current access token
+func AccessTokenCacheKey(userID uint) string {
+	return fmt.Sprintf("access_token:%d", userID)
+}
+
+func ticketCacheKey(ticket string) string {
+	return fmt.Sprintf("ws_ticket:%s", ticket)
+}
+
+// IssueTicket stores a random single-use ticket for ticketTTL
+func (s *cacheWSAuthService) IssueTicket(userID uint, expiresAt time.Time) (*WSTicket, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	ticket := &WSTicket{Ticket: hex.EncodeToString(b), UserID: userID, ExpiresAt: expiresAt}
+
+	payload, err := json.Marshal(ticket)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cache.Set(ticketCacheKey(ticket.Ticket), payload, s.ticketTTL); err != nil {
+		return nil, err
+	}
+
+	return ticket, nil
+}
+
+// RedeemTicket returns the ticket data and deletes it, so a ticket can be used only once
+func (s *cacheWSAuthService) RedeemTicket(ticket string) (*WSTicket, error) {
+	payload, err := s.cache.Take(ticketCacheKey(ticket))
+	if err != nil {
+		return nil, ErrInvalidTicket
+	}
+
+	var data WSTicket
+	if err := json.Unmarshal([]byte(payload), &data); err != nil {
+		return nil, err
+	}
+	data.Ticket = ticket
+
+	if data.ExpiresAt.Before(time.Now()) {
+		return nil, ErrInvalidTicket
+	}
+
+	return &data, nil
+}
+
+// IsSessionActive reports whether the user still has a non-revoked access token
+func (s *cacheWSAuthService) IsSessionActive(userID uint) (bool, error) {
+	return s.cache.Exists(AccessTokenCacheKey(userID))
+}
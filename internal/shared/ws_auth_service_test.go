@@ -0,0 +1,81 @@
+package shared_test
+
+import (
+	"encoding/json"
+	"errors"
+	"socialAPI/internal/mocks"
+	"socialAPI/internal/shared"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWSAuthService_IssueTicket(t *testing.T) {
+	cache := new(mocks.CacheStore)
+	srv := shared.NewWSAuthService(cache, 30*time.Second)
+	expiresAt := time.Now().Add(time.Hour)
+
+	cache.On("Set", mock.MatchedBy(func(key string) bool { return len(key) > len("ws_ticket:") }), mock.Anything, 30*time.Second).Return(nil)
+
+	ticket, err := srv.IssueTicket(1, expiresAt)
+
+	assert.NoError(t, err)
+	assert.Len(t, ticket.Ticket, 64)
+	assert.Equal(t, uint(1), ticket.UserID)
+	cache.AssertExpectations(t)
+}
+
+func TestWSAuthService_RedeemTicket(t *testing.T) {
+	payload := func(expiresAt time.Time) string {
+		b, _ := json.Marshal(shared.WSTicket{UserID: 1, ExpiresAt: expiresAt})
+		return string(b)
+	}
+
+	tests := []struct {
+		name    string
+		setup   func(cache *mocks.CacheStore)
+		wantErr error
+	}{
+		{
+			name: "unknown or already used ticket",
+			setup: func(cache *mocks.CacheStore) {
+				cache.On("Take", "ws_ticket:abc").Return("", errors.New("redis: nil"))
+			},
+			wantErr: shared.ErrInvalidTicket,
+		},
+		{
+			name: "ticket issued for an expired token",
+			setup: func(cache *mocks.CacheStore) {
+				cache.On("Take", "ws_ticket:abc").Return(payload(time.Now().Add(-time.Minute)), nil)
+			},
+			wantErr: shared.ErrInvalidTicket,
+		},
+		{
+			name: "valid ticket",
+			setup: func(cache *mocks.CacheStore) {
+				cache.On("Take", "ws_ticket:abc").Return(payload(time.Now().Add(time.Hour)), nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := new(mocks.CacheStore)
+			tt.setup(cache)
+			srv := shared.NewWSAuthService(cache, 30*time.Second)
+
+			ticket, err := srv.RedeemTicket("abc")
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "abc", ticket.Ticket)
+				assert.Equal(t, uint(1), ticket.UserID)
+			}
+			cache.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+//go:build integration
+
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// Хранилище на MinIO из docker-compose. Бакет создаётся, если его ещё нет
+func newTestS3Store(t *testing.T) BlobStore {
+	store, err := NewS3BlobStore(S3Options{
+		Endpoint:  getEnv("S3_ENDPOINT", "http://localhost:9000"),
+		Region:    getEnv("S3_REGION", "us-east-1"),
+		Bucket:    getEnv("S3_BUCKET", "attachments-test"),
+		AccessKey: getEnv("S3_ACCESS_KEY", "minioadmin"),
+		SecretKey: getEnv("S3_SECRET_KEY", "minioadmin"),
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := store.(s3BlobStore).do(ctx, http.MethodPut, "", nil, 0, "")
+	if err != nil {
+		t.Skipf("minio is not available: %v", err)
+	}
+	resp.Body.Close()
+	require.Contains(t, []int{http.StatusOK, http.StatusConflict}, resp.StatusCode)
+
+	return store
+}
+
+func TestS3BlobStore(t *testing.T) {
+	ctx := context.Background()
+	store := newTestS3Store(t)
+	key := fmt.Sprintf("chats/1/%d file.txt", time.Now().UnixNano())
+
+	require.NoError(t, store.Put(ctx, key, strings.NewReader("hello"), 5, "text/plain"))
+
+	body, err := store.Get(ctx, key)
+	require.NoError(t, err)
+	content, err := io.ReadAll(body)
+	body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(content))
+
+	require.NoError(t, store.Delete(ctx, key))
+	_, err = store.Get(ctx, key)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -14,6 +14,7 @@ type CacheStore interface {
 	Get(key string) (string, error)
 	Delete(key string) error
 	Exists(key string) (bool, error)
+	Take(key string) (string, error)
 }
 
 type Redis struct {
@@ -21,6 +22,16 @@ type Redis struct {
 }
 
 func NewRedis(cfg cfg.RedisConfig) (CacheStore, error) {
+	rdb, err := NewRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Redis{client: rdb}, nil
+}
+
+// NewRedisClient создаёт клиент Redis и проверяет подключение
+func NewRedisClient(cfg cfg.RedisConfig) (*redis.Client, error) {
 	rdb := redis.NewClient(&redis.Options{
 		Addr:     fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
 		Password: cfg.Password,
@@ -33,7 +44,7 @@ func NewRedis(cfg cfg.RedisConfig) (CacheStore, error) {
 		return nil, fmt.Errorf("could not connect to Redis: %v", err)
 	}
 
-	return &Redis{client: rdb}, nil
+	return rdb, nil
 }
 
 func (r *Redis) Set(key string, value interface{}, expiration time.Duration) error {
@@ -52,3 +63,8 @@ func (r *Redis) Exists(key string) (bool, error) {
 	result, err := r.client.Exists(context.Background(), key).Result()
 	return result > 0, err
 }
+
+// Take атомарно читает и удаляет ключ
+func (r *Redis) Take(key string) (string, error) {
+	return r.client.GetDel(context.Background(), key).Result()
+}
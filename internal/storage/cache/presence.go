@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// PresenceStore хранит живые соединения пользователей. Каждое соединение живёт ttl
+// и продлевается через Refresh, поэтому соединения упавшего узла со временем исчезают сами
+type PresenceStore interface {
+	// Connect регистрирует соединение и сообщает, стал ли пользователь онлайн
+	Connect(userID uint, connID string) (bool, error)
+	// Disconnect удаляет соединение и сообщает, стал ли пользователь офлайн
+	Disconnect(userID uint, connID string) (bool, error)
+	Refresh(userID uint, connID string) error
+	Online(userIDs []uint) (map[uint]bool, error)
+}
+
+// Реализация для одного узла
+type memoryPresenceStore struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	conns map[uint]map[string]time.Time // userID -> connID -> момент истечения
+}
+
+func NewMemoryPresenceStore(ttl time.Duration) PresenceStore {
+	return &memoryPresenceStore{ttl: ttl, conns: make(map[uint]map[string]time.Time)}
+}
+
+// Удаляет истёкшие соединения пользователя и возвращает количество оставшихся
+func (s *memoryPresenceStore) live(userID uint, now time.Time) int {
+	for connID, expiresAt := range s.conns[userID] {
+		if !expiresAt.After(now) {
+			delete(s.conns[userID], connID)
+		}
+	}
+	if len(s.conns[userID]) == 0 {
+		delete(s.conns, userID)
+	}
+	return len(s.conns[userID])
+}
+
+func (s *memoryPresenceStore) Connect(userID uint, connID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	wasOnline := s.live(userID, now) > 0
+
+	if s.conns[userID] == nil {
+		s.conns[userID] = make(map[string]time.Time)
+	}
+	s.conns[userID][connID] = now.Add(s.ttl)
+
+	return !wasOnline, nil
+}
+
+func (s *memoryPresenceStore) Disconnect(userID uint, connID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, existed := s.conns[userID][connID]
+	delete(s.conns[userID], connID)
+
+	return existed && s.live(userID, time.Now()) == 0, nil
+}
+
+func (s *memoryPresenceStore) Refresh(userID uint, connID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conns[userID] == nil {
+		s.conns[userID] = make(map[string]time.Time)
+	}
+	s.conns[userID][connID] = time.Now().Add(s.ttl)
+	return nil
+}
+
+func (s *memoryPresenceStore) Online(userIDs []uint) (map[uint]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	online := make(map[uint]bool, len(userIDs))
+	for _, id := range userIDs {
+		online[id] = s.live(id, now) > 0
+	}
+	return online, nil
+}
+
+// Реализация для нескольких узлов: соединения пользователя лежат в sorted set,
+// score - момент истечения в миллисекундах
+type redisPresenceStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRedisPresenceStore(client *redis.Client, ttl time.Duration) PresenceStore {
+	return &redisPresenceStore{client: client, ttl: ttl}
+}
+
+func presenceKey(userID uint) string {
+	return fmt.Sprintf("presence:%d", userID)
+}
+
+func scoreOf(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
+
+func (s *redisPresenceStore) Connect(userID uint, connID string) (bool, error) {
+	ctx, key, now := context.Background(), presenceKey(userID), time.Now()
+
+	var count *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, key, "-inf", scoreOf(now))
+		pipe.ZAdd(ctx, key, &redis.Z{Score: float64(now.Add(s.ttl).UnixMilli()), Member: connID})
+		count = pipe.ZCard(ctx, key)
+		pipe.Expire(ctx, key, s.ttl)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return count.Val() == 1, nil
+}
+
+func (s *redisPresenceStore) Disconnect(userID uint, connID string) (bool, error) {
+	ctx, key := context.Background(), presenceKey(userID)
+
+	var removed, count *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, key, "-inf", scoreOf(time.Now()))
+		removed = pipe.ZRem(ctx, key, connID)
+		count = pipe.ZCard(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return removed.Val() == 1 && count.Val() == 0, nil
+}
+
+func (s *redisPresenceStore) Refresh(userID uint, connID string) error {
+	ctx, key := context.Background(), presenceKey(userID)
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, key, &redis.Z{Score: float64(time.Now().Add(s.ttl).UnixMilli()), Member: connID})
+		pipe.Expire(ctx, key, s.ttl)
+		return nil
+	})
+	return err
+}
+
+func (s *redisPresenceStore) Online(userIDs []uint) (map[uint]bool, error) {
+	ctx, min := context.Background(), "("+scoreOf(time.Now())
+
+	counts := make(map[uint]*redis.IntCmd, len(userIDs))
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, id := range userIDs {
+			counts[id] = pipe.ZCount(ctx, presenceKey(id), min, "+inf")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	online := make(map[uint]bool, len(userIDs))
+	for id, count := range counts {
+		online[id] = count.Val() > 0
+	}
+	return online, nil
+}
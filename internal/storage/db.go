@@ -3,6 +3,7 @@ package storage
 import (
 	"fmt"
 	repo "socialAPI/internal/storage/repository"
+	"socialAPI/internal/storage/search"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm/logger"
@@ -18,6 +19,14 @@ func BootstrapDatabase(dsn string) (*gorm.DB, error) {
 		return nil, err
 	}
 
+	// user_chats хранит не только связь, но и позицию прочтения участника
+	if err := db.SetupJoinTable(&repo.User{}, "Chats", &repo.ChatMember{}); err != nil {
+		return nil, err
+	}
+	if err := db.SetupJoinTable(&repo.Chat{}, "Users", &repo.ChatMember{}); err != nil {
+		return nil, err
+	}
+
 	return db, nil
 }
 
@@ -35,9 +44,30 @@ func MadeMigrations(db *gorm.DB) {
 		panic(fmt.Sprintf("Error creating enum type: %v", err))
 	}
 
-	if err := db.AutoMigrate(&repo.User{}, &repo.Chat{}, &repo.Message{}, &repo.Friendship{}, &repo.RefreshToken{}); err != nil {
+	if err := db.AutoMigrate(&repo.User{}, &repo.Chat{}, &repo.ChatMember{}, &repo.Message{}, &repo.MessageRevision{}, &repo.MessageReaction{}, &repo.MessageMention{}, &repo.Poll{}, &repo.PollOption{}, &repo.PollVote{}, &repo.Attachment{}, &repo.PinnedMessage{}, &repo.ScheduledMessage{}, &repo.ChatInvite{}, &repo.ChatInviteUse{}, &repo.Friendship{}, &repo.RefreshToken{}); err != nil {
 		panic(fmt.Sprintf("Migrations went wrong: %v", err))
 	}
 
+	// Групповые чаты, созданные до появления ролей, остались без владельца.
+	// Владельцем становится самый ранний участник; повторный запуск ничего не меняет
+	err = db.Exec(`
+		UPDATE user_chats uc SET role = ?
+		FROM (
+			SELECT DISTINCT ON (uc.chat_id) uc.chat_id, uc.user_id
+			FROM user_chats uc
+			JOIN chats c ON c.id = uc.chat_id AND c.type = ?
+			WHERE NOT EXISTS (SELECT 1 FROM user_chats o WHERE o.chat_id = uc.chat_id AND o.role = ?)
+			ORDER BY uc.chat_id, uc.joined_at, uc.user_id
+		) earliest
+		WHERE uc.chat_id = earliest.chat_id AND uc.user_id = earliest.user_id
+	`, repo.ChatRoleOwner, repo.ChatTypeGroup, repo.ChatRoleOwner).Error
+	if err != nil {
+		panic(fmt.Sprintf("Error backfilling chat owners: %v", err))
+	}
+
+	if err := db.Exec(search.CreateIndexSQL).Error; err != nil {
+		panic(fmt.Sprintf("Error creating search index: %v", err))
+	}
+
 	fmt.Println("Migration success")
 }
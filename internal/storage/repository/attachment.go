@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AttachmentDTO - описание вложения для клиента. Содержимое отдаётся по URL
+type AttachmentDTO struct {
+	ID           uint   `json:"id"`
+	FileName     string `json:"file_name"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+}
+
+// ConvertToDTO преобразует Attachment в AttachmentDTO
+func (attachment *Attachment) ConvertToDTO() AttachmentDTO {
+	url := fmt.Sprintf("/v1/chat/%d/attachments/%d", attachment.ChatID, attachment.ID)
+
+	dto := AttachmentDTO{
+		ID:          attachment.ID,
+		FileName:    attachment.FileName,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		URL:         url,
+	}
+	if attachment.ThumbnailKey != nil {
+		dto.ThumbnailURL = url + "?thumbnail=true"
+	}
+
+	return dto
+}
+
+// ConvertAttachmentsToDTO преобразует вложения сообщения, для пустого списка - nil
+func ConvertAttachmentsToDTO(attachments []Attachment) []AttachmentDTO {
+	var dtos []AttachmentDTO
+	for i := range attachments {
+		dtos = append(dtos, attachments[i].ConvertToDTO())
+	}
+	return dtos
+}
+
+type AttachmentRepository interface {
+	Create(attachment *Attachment) error
+	GetByID(attachmentID uint) (*Attachment, error)
+	SetThumbnail(attachmentID uint, key string) error
+	DeleteUnattached(before time.Time, limit int) (int, []string, error)
+}
+
+type attachmentPostgresRepo struct {
+	db *gorm.DB
+}
+
+func NewPostgresAttachmentRepo(db *gorm.DB) AttachmentRepository {
+	return attachmentPostgresRepo{db: db}
+}
+
+func (repo attachmentPostgresRepo) Create(attachment *Attachment) error {
+	return repo.db.Create(attachment).Error
+}
+
+func (repo attachmentPostgresRepo) GetByID(attachmentID uint) (*Attachment, error) {
+	var attachment Attachment
+	if err := repo.db.First(&attachment, attachmentID).Error; err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (repo attachmentPostgresRepo) SetThumbnail(attachmentID uint, key string) error {
+	return repo.db.Model(&Attachment{}).Where("id = ?", attachmentID).Update("thumbnail_key", key).Error
+}
+
+// DeleteUnattached удаляет до limit вложений, которые загрузили раньше before, но так и не
+// отправили ни в одном сообщении. Возвращает число удалённых записей и ключи файлов,
+// которые нужно удалить из хранилища после коммита
+func (repo attachmentPostgresRepo) DeleteUnattached(before time.Time, limit int) (int, []string, error) {
+	var attachments []Attachment
+	var keys []string
+
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("message_id IS NULL AND created_at < ?", before).
+			Order("id").
+			Limit(limit).
+			Find(&attachments).Error
+		if err != nil || len(attachments) == 0 {
+			return err
+		}
+
+		ids := make([]uint, 0, len(attachments))
+		for _, attachment := range attachments {
+			ids = append(ids, attachment.ID)
+			keys = append(keys, attachment.StorageKey)
+			if attachment.ThumbnailKey != nil {
+				keys = append(keys, *attachment.ThumbnailKey)
+			}
+		}
+
+		if err := tx.Delete(&Attachment{}, ids).Error; err != nil {
+			return err
+		}
+
+		keys, err = unusedKeys(tx, keys)
+		return err
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return len(attachments), keys, nil
+}
@@ -1,27 +1,124 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrNotAllowed - роль участника на момент изменения не позволяет его выполнить
+var ErrNotAllowed = errors.New("not allowed by the member role")
+
 // ChatDTO - это структура для отправки данных о чате без лишней информации
 type ChatDTO struct {
+	ID        uint            `json:"id"`
+	Type      ChatType        `json:"type"`
+	Name      string          `json:"name,omitempty"`
+	Members   []ChatMemberDTO `json:"members,omitempty"`
+	Pins      []PinSummaryDTO `json:"pins,omitempty"`
+	Messages  []MessageDTO    `json:"messages,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+
+	RetentionSeconds  int64 `json:"retention_seconds"`
+	MessageTTLSeconds int64 `json:"message_ttl_seconds"`
+}
+
+// ChatMemberDTO - участник чата с ролью
+type ChatMemberDTO struct {
+	UserID   uint      `json:"user_id"`
+	Role     ChatRole  `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// MessageDTO - это структура для отправки данных о сообщении без лишней информации
+type MessageDTO struct {
 	ID        uint         `json:"id"`
-	Messages  []MessageDTO `json:"messages,omitempty"`
+	Kind      MessageKind  `json:"kind"`
+	System    *SystemEvent `json:"system,omitempty"`
+	Content   string       `json:"content"`
+	Sender    SenderDTO    `json:"sender"`
 	CreatedAt time.Time    `json:"created_at"`
 	UpdatedAt time.Time    `json:"updated_at"`
+	EditedAt  *time.Time   `json:"edited_at,omitempty"`
+	Deleted   bool         `json:"deleted"`
+	ExpiresAt *time.Time   `json:"expires_at,omitempty"`
+
+	ForwardedFrom *ForwardOrigin `json:"forwarded_from,omitempty"`
+
+	Mentions    []uint `json:"mentions,omitempty"`
+	MentionsAll bool   `json:"mentions_all,omitempty"`
+
+	ReplyTo    *MessagePreviewDTO `json:"reply_to,omitempty"`
+	ReplyCount int                `json:"reply_count"`
+	Reactions  []ReactionDTO      `json:"reactions"`
+
+	// Содержимое, зависящее от kind: system - для системных сообщений, poll - для опросов
+	Poll *PollDTO `json:"poll,omitempty"`
+
+	Attachments []AttachmentDTO `json:"attachments,omitempty"`
 }
 
-// MessageDTO - это структура для отправки данных о сообщении без лишней информации
-type MessageDTO struct {
-	ID        uint      `json:"id"`
-	Content   string    `json:"content"`
-	Sender    SenderDTO `json:"sender"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+// ReactionDTO - количество реакций одним эмодзи. Reacted - реагировал ли запросивший пользователь
+type ReactionDTO struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	Reacted bool   `json:"reacted"`
+}
+
+// MessagePreviewDTO - краткое представление цитируемого сообщения
+type MessagePreviewDTO struct {
+	ID       uint   `json:"id"`
+	SenderID uint   `json:"sender_id"`
+	Content  string `json:"content"`
+	Deleted  bool   `json:"deleted"`
+}
+
+// Длина текста цитаты в превью, в символах
+const previewLength = 100
+
+// ChatSummaryDTO - элемент списка чатов пользователя: последнее сообщение и счётчик непрочитанных
+type ChatSummaryDTO struct {
+	ID                uint        `json:"id"`
+	Type              ChatType    `json:"type"`
+	Name              string      `json:"name,omitempty"`
+	UnreadCount       int64       `json:"unread_count"`
+	LastReadMessageID uint        `json:"last_read_message_id"`
+	LastMessage       *MessageDTO `json:"last_message,omitempty"`
+	CreatedAt         time.Time   `json:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at"`
+
+	ChatSettingsDTO
+}
+
+// ChatSummary - строка выборки списка чатов
+type ChatSummary struct {
+	ID                uint
+	Type              ChatType
+	Name              string
+	UnreadCount       int64
+	LastReadMessageID uint
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+
+	Muted      bool
+	MutedUntil *time.Time
+	Archived   bool
+	Pinned     bool
+
+	LastMessageID        *uint
+	LastMessageKind      *MessageKind
+	LastMessageSystem    *SystemEvent
+	LastMessageContent   *string
+	LastMessageSenderID  *uint
+	LastMessageSender    *string
+	LastMessageCreatedAt *time.Time
+	LastMessageUpdatedAt *time.Time
+	LastMessageEditedAt  *time.Time
+	LastMessageDeletedAt *time.Time
 }
 
 // SenderDTO - структура для отправки данных о пользователе (отправителе)
@@ -30,27 +127,150 @@ type SenderDTO struct {
 	Email string `json:"email"`
 }
 
-// ConvertToDTO преобразует Chat в ChatDTO
-func (chat *Chat) ConvertToDTO() *ChatDTO {
+// ConvertToDTO преобразует Chat в ChatDTO с точки зрения пользователя viewerID
+func (chat *Chat) ConvertToDTO(viewerID uint) *ChatDTO {
 	var messageDTOs []MessageDTO
 	for _, message := range chat.Messages {
-		messageDTOs = append(messageDTOs, MessageDTO{
-			ID:        message.ID,
-			Content:   message.Content,
-			Sender:    message.Sender.ConvertToDTO(), // Преобразуем отправителя
-			CreatedAt: message.CreatedAt,
-			UpdatedAt: message.UpdatedAt,
-		})
+		messageDTOs = append(messageDTOs, message.ConvertToDTO(viewerID))
+	}
+
+	var memberDTOs []ChatMemberDTO
+	for _, member := range chat.Members {
+		memberDTOs = append(memberDTOs, ChatMemberDTO{UserID: member.UserID, Role: member.Role, JoinedAt: member.JoinedAt})
+	}
+
+	var pinDTOs []PinSummaryDTO
+	for i := range chat.Pins {
+		pinDTOs = append(pinDTOs, chat.Pins[i].ConvertToSummaryDTO())
 	}
 
 	return &ChatDTO{
 		ID:        chat.ID,
+		Type:      chat.Type,
+		Name:      chat.Name,
+		Members:   memberDTOs,
+		Pins:      pinDTOs,
 		Messages:  messageDTOs,
 		CreatedAt: chat.CreatedAt,
 		UpdatedAt: chat.UpdatedAt,
+
+		RetentionSeconds:  chat.RetentionSeconds,
+		MessageTTLSeconds: chat.MessageTTLSeconds,
 	}
 }
 
+// ConvertToDTO преобразует Message в MessageDTO, отмечая реакции и голоса пользователя viewerID.
+// Вложения и опрос удалённого сообщения не отдаются
+func (message *Message) ConvertToDTO(viewerID uint) MessageDTO {
+	var attachments []AttachmentDTO
+	var poll *PollDTO
+	var mentions []uint
+	if message.DeletedAt == nil {
+		attachments = ConvertAttachmentsToDTO(message.Attachments)
+		poll = message.Poll.ConvertToDTO(viewerID)
+		mentions = message.Mentions
+	}
+
+	return MessageDTO{
+		ID:        message.ID,
+		Kind:      message.Kind,
+		System:    message.System,
+		Content:   message.Content,
+		Sender:    message.Sender.ConvertToDTO(), // Преобразуем отправителя
+		CreatedAt: message.CreatedAt,
+		UpdatedAt: message.UpdatedAt,
+		EditedAt:  message.EditedAt,
+		Deleted:   message.DeletedAt != nil,
+		ExpiresAt: message.ExpiresAt,
+
+		ForwardedFrom: message.ForwardedFrom,
+
+		Mentions:    mentions,
+		MentionsAll: message.MentionsAll && message.DeletedAt == nil,
+
+		ReplyTo:    message.ReplyTo.ConvertToPreviewDTO(),
+		ReplyCount: message.ReplyCount,
+		Reactions:  aggregateReactions(message.Reactions, viewerID),
+		Poll:       poll,
+
+		Attachments: attachments,
+	}
+}
+
+// aggregateReactions группирует реакции по эмодзи в порядке появления первой реакции
+func aggregateReactions(reactions []MessageReaction, viewerID uint) []ReactionDTO {
+	dtos := []ReactionDTO{}
+	positions := make(map[string]int)
+	for _, reaction := range reactions {
+		position, ok := positions[reaction.Emoji]
+		if !ok {
+			position = len(dtos)
+			positions[reaction.Emoji] = position
+			dtos = append(dtos, ReactionDTO{Emoji: reaction.Emoji})
+		}
+
+		dtos[position].Count++
+		if reaction.UserID == viewerID {
+			dtos[position].Reacted = true
+		}
+	}
+	return dtos
+}
+
+// ConvertToPreviewDTO возвращает превью сообщения с обрезанным текстом, для nil - nil
+func (message *Message) ConvertToPreviewDTO() *MessagePreviewDTO {
+	if message == nil {
+		return nil
+	}
+
+	content := []rune(message.Content)
+	if len(content) > previewLength {
+		content = append(content[:previewLength], '…')
+	}
+
+	return &MessagePreviewDTO{
+		ID:       message.ID,
+		SenderID: message.SenderID,
+		Content:  string(content),
+		Deleted:  message.DeletedAt != nil,
+	}
+}
+
+// ConvertToDTO преобразует ChatSummary в ChatSummaryDTO
+func (summary *ChatSummary) ConvertToDTO() ChatSummaryDTO {
+	dto := ChatSummaryDTO{
+		ID:                summary.ID,
+		Type:              summary.Type,
+		Name:              summary.Name,
+		UnreadCount:       summary.UnreadCount,
+		LastReadMessageID: summary.LastReadMessageID,
+		CreatedAt:         summary.CreatedAt,
+		UpdatedAt:         summary.UpdatedAt,
+		ChatSettingsDTO: ChatSettingsDTO{
+			Muted:      summary.Muted,
+			MutedUntil: summary.MutedUntil,
+			Archived:   summary.Archived,
+			Pinned:     summary.Pinned,
+		},
+	}
+
+	if summary.LastMessageID != nil {
+		dto.LastMessage = &MessageDTO{
+			ID:        *summary.LastMessageID,
+			Kind:      *summary.LastMessageKind,
+			System:    summary.LastMessageSystem,
+			Content:   *summary.LastMessageContent,
+			Sender:    SenderDTO{ID: *summary.LastMessageSenderID, Email: *summary.LastMessageSender},
+			CreatedAt: *summary.LastMessageCreatedAt,
+			UpdatedAt: *summary.LastMessageUpdatedAt,
+			EditedAt:  summary.LastMessageEditedAt,
+			Deleted:   summary.LastMessageDeletedAt != nil,
+		}
+	}
+
+	return dto
+}
+
 func (user *User) ConvertToDTO() SenderDTO {
 	return SenderDTO{
 		ID:    user.ID,
@@ -60,12 +280,29 @@ func (user *User) ConvertToDTO() SenderDTO {
 
 type ChatRepository interface {
 	GetOne(chatID uint) (*Chat, error)
-	GetAll() ([]*Chat, error)
-	Create(name *string, userIDs []uint) error
-	ExistsSetUserIDs(userIDs []uint) (bool, error)
+	GetSummaries(userID uint, archived bool) ([]ChatSummary, error)
+	Create(creatorID uint, name *string, userIDs []uint) (*Message, error)
+	GetOrCreateDirect(userID, peerID uint) (uint, bool, error)
+	GetType(chatID uint) (ChatType, error)
 	ExistsID(chatID uint) (bool, error)
-	Update(id uint, name *string, userIDs []uint) error
+	FilterActiveSenders(senders []ChatSender) (map[ChatSender]bool, error)
+	Rename(chatID, actorID uint, name string) (*Message, error)
+	AddMembers(chatID, actorID uint, userIDs []uint) ([]uint, *Message, error)
+	RemoveMember(chatID, actorID, userID uint) ([]Message, error)
+	SetRole(chatID, actorID, userID uint, role ChatRole) (*Message, error)
+	GetMembers(chatID uint) ([]ChatMember, error)
 	GetChatIDsByUserID(userID uint) ([]uint, error)
+	GetUserIDs(chatID uint) ([]uint, error)
+	IsMember(chatID, userID uint) (bool, error)
+	GetRole(chatID, userID uint) (ChatRole, error)
+	MarkRead(chatID, userID, messageID uint) (bool, error)
+	CreateInvite(invite *ChatInvite) error
+	GetInvites(chatID uint) ([]ChatInvite, error)
+	RevokeInvite(chatID, inviteID uint) (bool, error)
+	JoinByInvite(code string, userID uint) (uint, *Message, error)
+	UpdateSettings(chatID, userID uint, changes map[string]interface{}) (*ChatMember, error)
+	GetNotifiableUserIDs(chatIDs []uint) (map[uint][]uint, error)
+	SetRetention(chatID, actorID uint, retentionSeconds, messageTTLSeconds *int64) (*Message, error)
 }
 
 type chatPostgresRepo struct {
@@ -78,7 +315,7 @@ func NewPostgresChatRepo(db *gorm.DB) ChatRepository {
 
 func (repo chatPostgresRepo) GetOne(chatID uint) (*Chat, error) {
 	var chat *Chat
-	err := repo.db.Preload("Messages.Sender").First(&chat, chatID).Error
+	err := repo.db.Preload("Members", orderMembers).Preload("Pins", orderPins, livePins).Preload("Pins.Message").Preload("Messages", liveMessages).Preload("Messages.Sender").Preload("Messages.ReplyTo").Preload("Messages.Reactions", orderReactions).Preload("Messages.Attachments").Scopes(preloadPoll("Messages.")).First(&chat, chatID).Error
 	if err != nil {
 		return nil, err
 	}
@@ -86,58 +323,144 @@ func (repo chatPostgresRepo) GetOne(chatID uint) (*Chat, error) {
 	return chat, nil
 }
 
-func (repo chatPostgresRepo) GetAll() ([]*Chat, error) {
-	var chats []*Chat
-	err := repo.db.Preload("Messages.Sender").Find(&chats).Error
-	if err != nil {
-		return nil, err
-	}
-	return chats, nil
+// GetSummaries возвращает архивные или неархивные чаты пользователя: сначала закреплённые им,
+// затем недавно активные. Непрочитанные считаются по индексу (chat_id, id) только после позиции
+// прочтения, поэтому стоимость зависит от числа непрочитанных сообщений, а не от длины истории.
+// Истёкшие сообщения, которые сборщик ещё не удалил, не считаются ни последними, ни непрочитанными
+func (repo chatPostgresRepo) GetSummaries(userID uint, archived bool) ([]ChatSummary, error) {
+	var summaries []ChatSummary
+	err := repo.db.Raw(fmt.Sprintf(`
+		SELECT
+			c.id, c.type, c.name, c.created_at, c.updated_at,
+			uc.last_read_message_id,
+			uc.muted AND (uc.muted_until IS NULL OR uc.muted_until > NOW()) AS muted,
+			CASE WHEN uc.muted AND uc.muted_until > NOW() THEN uc.muted_until END AS muted_until,
+			uc.archived_at IS NOT NULL AS archived,
+			uc.pinned_at IS NOT NULL AS pinned,
+			(
+				SELECT COUNT(*) FROM messages
+				WHERE messages.chat_id = uc.chat_id AND messages.id > uc.last_read_message_id AND messages.sender_id <> uc.user_id
+					AND messages.deleted_at IS NULL AND (%[1]s)
+			) AS unread_count,
+			lm.id AS last_message_id,
+			lm.kind AS last_message_kind,
+			lm.system AS last_message_system,
+			lm.content AS last_message_content,
+			lm.sender_id AS last_message_sender_id,
+			u.email AS last_message_sender,
+			lm.created_at AS last_message_created_at,
+			lm.updated_at AS last_message_updated_at,
+			lm.edited_at AS last_message_edited_at,
+			lm.deleted_at AS last_message_deleted_at
+		FROM user_chats uc
+		JOIN chats c ON c.id = uc.chat_id
+		LEFT JOIN LATERAL (
+			SELECT id, kind, system, content, sender_id, created_at, updated_at, edited_at, deleted_at FROM messages
+			WHERE chat_id = uc.chat_id AND (%[1]s)
+			ORDER BY id DESC
+			LIMIT 1
+		) lm ON true
+		LEFT JOIN users u ON u.id = lm.sender_id
+		WHERE uc.user_id = ? AND (uc.archived_at IS NOT NULL) = ?
+		ORDER BY uc.pinned_at IS NOT NULL DESC, COALESCE(lm.id, 0) DESC, c.id DESC
+	`, liveMessagesSQL), userID, archived).Scan(&summaries).Error
+
+	return summaries, err
 }
 
-func (repo chatPostgresRepo) Create(name *string, userIDs []uint) error {
-	chat := Chat{
-		Name:  "",
-		Users: make([]User, len(userIDs)),
-	}
-
+// Create создаёт чат, где создатель становится владельцем, а остальные - участниками.
+// Возвращает системное сообщение chat_created, ChatID которого - ID нового чата
+func (repo chatPostgresRepo) Create(creatorID uint, name *string, userIDs []uint) (*Message, error) {
+	chat := Chat{Type: ChatTypeGroup, Name: ""}
 	if name != nil {
 		chat.Name = *name
 	}
 
-	for i, id := range userIDs {
-		chat.Users[i] = User{ID: id}
+	var message Message
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&chat).Error; err != nil {
+			return err
+		}
+
+		members := make([]ChatMember, len(userIDs))
+		var invited []uint
+		for i, id := range userIDs {
+			members[i] = ChatMember{UserID: id, ChatID: chat.ID, Role: ChatRoleMember}
+			if id == creatorID {
+				members[i].Role = ChatRoleOwner
+			} else {
+				invited = append(invited, id)
+			}
+		}
+
+		if err := tx.Create(&members).Error; err != nil {
+			return err
+		}
+
+		message = systemMessage(chat.ID, creatorID, SystemEvent{Action: SystemChatCreated, UserIDs: invited, Name: chat.Name})
+		return tx.Create(&message).Error
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if err := repo.db.Create(&chat).Error; err != nil {
-		return err
+	return &message, nil
+}
+
+// GetOrCreateDirect возвращает личный чат двух пользователей, создавая его при первом обращении.
+// Параллельные запросы упираются в уникальный индекс по паре и получают один и тот же чат.
+// Второе значение - был ли чат создан этим вызовом
+func (repo chatPostgresRepo) GetOrCreateDirect(userID, peerID uint) (uint, bool, error) {
+	low, high := min(userID, peerID), max(userID, peerID)
+
+	var chatID uint
+	created := false
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		chat := Chat{Type: ChatTypeDirect, DirectUserLowID: &low, DirectUserHighID: &high}
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "direct_user_low_id"}, {Name: "direct_user_high_id"}},
+			DoNothing: true,
+		}).Create(&chat)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			var existing Chat
+			err := tx.Select("id").
+				Where("direct_user_low_id = ? AND direct_user_high_id = ?", low, high).
+				Take(&existing).Error
+			chatID = existing.ID
+			return err
+		}
+
+		chatID = chat.ID
+		created = true
+		members := []ChatMember{
+			{UserID: low, ChatID: chat.ID, Role: ChatRoleMember},
+			{UserID: high, ChatID: chat.ID, Role: ChatRoleMember},
+		}
+		return tx.Create(&members).Error
+	})
+	if err != nil {
+		return 0, false, err
 	}
 
-	return nil
+	return chatID, created, nil
 }
 
-func (repo chatPostgresRepo) ExistsSetUserIDs(userIDs []uint) (bool, error) {
-	var chatCount int64
-
+// GetType возвращает тип чата или пустую строку, если чата нет
+func (repo chatPostgresRepo) GetType(chatID uint) (ChatType, error) {
+	var types []ChatType
 	err := repo.db.
-		Table("user_chats").
-		Select("chat_id").
-		Where("user_id IN ?", userIDs).
-		Group("chat_id").
-		Having(
-			"COUNT(user_id) = ? AND COUNT(user_id) = (SELECT COUNT(*) FROM user_chats WHERE chat_id = user_chats.chat_id)",
-			len(userIDs),
-		).Count(&chatCount).Error
-
-	if err != nil {
-		return true, err
-	}
+		Model(&Chat{}).
+		Where("id = ?", chatID).
+		Pluck("type", &types).Error
 
-	if chatCount == 0 {
-		return false, nil
+	if err != nil || len(types) == 0 {
+		return "", err
 	}
-
-	return true, fmt.Errorf("chat with same userIDs already exists")
+	return types[0], nil
 }
 
 func (repo chatPostgresRepo) ExistsID(chatID uint) (bool, error) {
@@ -154,30 +477,255 @@ func (repo chatPostgresRepo) ExistsID(chatID uint) (bool, error) {
 	return count > 0, nil
 }
 
-func (repo chatPostgresRepo) Update(id uint, name *string, userIDs []uint) error {
-	var chat Chat
-	if err := repo.db.Preload("Users").First(&chat, id).Error; err != nil {
-		return err
+// ChatSender - отправитель сообщения в чат
+type ChatSender struct {
+	ChatID   uint
+	SenderID uint
+}
+
+// FilterActiveSenders возвращает пары, в которых отправитель состоит в чате, а чат не в архиве
+func (repo chatPostgresRepo) FilterActiveSenders(senders []ChatSender) (map[ChatSender]bool, error) {
+	return activeSenders(repo.db, senders)
+}
+
+func activeSenders(tx *gorm.DB, senders []ChatSender) (map[ChatSender]bool, error) {
+	pairs := make([][]interface{}, 0, len(senders))
+	for _, sender := range senders {
+		pairs = append(pairs, []interface{}{sender.ChatID, sender.SenderID})
 	}
 
-	if name != nil {
-		chat.Name = *name
+	var members []ChatMember
+	err := tx.Select("user_chats.chat_id", "user_chats.user_id").
+		Joins("JOIN chats ON chats.id = user_chats.chat_id AND chats.archived_at IS NULL").
+		Where("(user_chats.chat_id, user_chats.user_id) IN ?", pairs).
+		Find(&members).Error
+	if err != nil {
+		return nil, err
 	}
 
-	if err := repo.db.Model(&chat).Update("name", chat.Name).Error; err != nil {
-		return err
+	active := make(map[ChatSender]bool, len(members))
+	for _, member := range members {
+		active[ChatSender{ChatID: member.ChatID, SenderID: member.UserID}] = true
 	}
+	return active, nil
+}
+
+// Rename переименовывает чат от имени actorID. Возвращает nil без ошибки, если название не изменилось,
+// и ErrNotAllowed, если actorID уже не владелец и не админ
+func (repo chatPostgresRepo) Rename(chatID, actorID uint, name string) (*Message, error) {
+	var message *Message
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockChat(tx, chatID); err != nil {
+			return err
+		}
+
+		roles, err := lockRoles(tx, chatID, actorID)
+		if err != nil {
+			return err
+		}
+		if !roles[actorID].CanModerate() {
+			return ErrNotAllowed
+		}
+
+		result := tx.Model(&Chat{ID: chatID}).Where("name <> ?", name).Update("name", name)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		record := systemMessage(chatID, actorID, SystemEvent{Action: SystemChatRenamed, Name: name})
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		message = &record
+		return nil
+	})
+
+	return message, err
+}
+
+// lockChat блокирует строку чата до конца транзакции, чтобы изменения состава одного чата
+// выполнялись по очереди. Для архивного чата возвращает gorm.ErrRecordNotFound
+func lockChat(tx *gorm.DB, chatID uint) error {
+	var chat Chat
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		Where("archived_at IS NULL").
+		First(&chat, chatID).Error
+}
 
-	users := make([]User, len(userIDs))
-	for i, id := range userIDs {
-		users[i] = User{ID: id}
+// lockRoles читает роли участников FOR UPDATE, чтобы проверка прав и само изменение видели
+// одни и те же роли. Не состоящих в чате в результате нет
+func lockRoles(tx *gorm.DB, chatID uint, userIDs ...uint) (map[uint]ChatRole, error) {
+	var members []ChatMember
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("user_id", "role").
+		Where("chat_id = ? AND user_id IN ?", chatID, userIDs).
+		Find(&members).Error
+	if err != nil {
+		return nil, err
 	}
 
-	if err := repo.db.Model(&chat).Association("Users").Replace(users); err != nil {
-		return err
+	roles := make(map[uint]ChatRole, len(members))
+	for _, member := range members {
+		roles[member.UserID] = member.Role
 	}
+	return roles, nil
+}
 
-	return nil
+// AddMembers добавляет существующих пользователей, которых ещё нет в чате. Возвращает
+// фактически добавленных и системное сообщение members_added, если добавлен хоть кто-то
+func (repo chatPostgresRepo) AddMembers(chatID, actorID uint, userIDs []uint) ([]uint, *Message, error) {
+	var added []uint
+	var message *Message
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockChat(tx, chatID); err != nil {
+			return err
+		}
+
+		err := tx.Raw(`
+			INSERT INTO user_chats (chat_id, user_id, role)
+			SELECT ?, id, ? FROM users WHERE id IN ?
+			ON CONFLICT DO NOTHING
+			RETURNING user_id
+		`, chatID, ChatRoleMember, userIDs).Scan(&added).Error
+		if err != nil || len(added) == 0 {
+			return err
+		}
+
+		record := systemMessage(chatID, actorID, SystemEvent{Action: SystemMembersAdded, UserIDs: added})
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		message = &record
+		return nil
+	})
+
+	return added, message, err
+}
+
+// RemoveMember исключает userID из чата, а при actorID == userID - выходит из него.
+// Если уходит владелец, владельцем становится самый давний админ, а без админов - самый давний
+// участник. После ухода последнего участника чат архивируется. Возвращает системные сообщения
+// в порядке создания; пустой результат без ошибки - пользователь уже не состоит в чате.
+// ErrNotAllowed - роль actorID не позволяет исключить userID
+func (repo chatPostgresRepo) RemoveMember(chatID, actorID, userID uint) ([]Message, error) {
+	var messages []Message
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockChat(tx, chatID); err != nil {
+			return err
+		}
+
+		roles, err := lockRoles(tx, chatID, actorID, userID)
+		if err != nil {
+			return err
+		}
+		role, ok := roles[userID]
+		if !ok {
+			return nil
+		}
+		if actorID != userID && !roles[actorID].CanManage(role) {
+			return ErrNotAllowed
+		}
+
+		if err := tx.Where("chat_id = ? AND user_id = ?", chatID, userID).Delete(&ChatMember{}).Error; err != nil {
+			return err
+		}
+
+		event := SystemEvent{Action: SystemMembersRemoved, UserIDs: []uint{userID}}
+		if actorID == userID {
+			event = SystemEvent{Action: SystemMemberLeft}
+		}
+		messages = append(messages, systemMessage(chatID, actorID, event))
+
+		var remaining int64
+		if err := tx.Model(&ChatMember{}).Where("chat_id = ?", chatID).Count(&remaining).Error; err != nil {
+			return err
+		}
+
+		switch {
+		case remaining == 0:
+			if err := tx.Model(&Chat{ID: chatID}).Update("archived_at", time.Now()).Error; err != nil {
+				return err
+			}
+		case role == ChatRoleOwner:
+			var successor ChatMember
+			err := tx.Where("chat_id = ?", chatID).
+				Order(clause.Expr{SQL: "role = ? DESC, joined_at, user_id", Vars: []interface{}{ChatRoleAdmin}}).
+				Take(&successor).Error
+			if err != nil {
+				return err
+			}
+
+			err = tx.Model(&ChatMember{}).
+				Where("chat_id = ? AND user_id = ?", chatID, successor.UserID).
+				Update("role", ChatRoleOwner).Error
+			if err != nil {
+				return err
+			}
+			messages = append(messages, systemMessage(chatID, actorID, SystemEvent{Action: SystemRoleChanged, UserIDs: []uint{successor.UserID}, Role: ChatRoleOwner}))
+		}
+
+		return tx.Create(&messages).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// SetRole меняет роль участника. Возвращает nil без ошибки, если роль уже такая
+// или пользователь не состоит в чате, и ErrNotAllowed, если роль actorID этого не позволяет
+func (repo chatPostgresRepo) SetRole(chatID, actorID, userID uint, role ChatRole) (*Message, error) {
+	var message *Message
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockChat(tx, chatID); err != nil {
+			return err
+		}
+
+		roles, err := lockRoles(tx, chatID, actorID, userID)
+		if err != nil {
+			return err
+		}
+		current, ok := roles[userID]
+		if !ok || current == role {
+			return nil
+		}
+		if !roles[actorID].CanManage(current) {
+			return ErrNotAllowed
+		}
+
+		err = tx.Model(&ChatMember{}).
+			Where("chat_id = ? AND user_id = ?", chatID, userID).
+			Update("role", role).Error
+		if err != nil {
+			return err
+		}
+
+		record := systemMessage(chatID, actorID, SystemEvent{Action: SystemRoleChanged, UserIDs: []uint{userID}, Role: role})
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		message = &record
+		return nil
+	})
+
+	return message, err
+}
+
+// GetMembers возвращает участников чата в порядке вступления
+func (repo chatPostgresRepo) GetMembers(chatID uint) ([]ChatMember, error) {
+	var members []ChatMember
+	err := orderMembers(repo.db.Where("chat_id = ?", chatID)).Find(&members).Error
+	return members, err
+}
+
+func orderMembers(db *gorm.DB) *gorm.DB {
+	return db.Order("joined_at, user_id")
+}
+
+func systemMessage(chatID, actorID uint, event SystemEvent) Message {
+	return Message{ChatID: chatID, SenderID: actorID, Kind: MessageKindSystem, System: &event}
 }
 
 func (repo chatPostgresRepo) GetChatIDsByUserID(userID uint) ([]uint, error) {
@@ -188,3 +736,48 @@ func (repo chatPostgresRepo) GetChatIDsByUserID(userID uint) ([]uint, error) {
 		Pluck("chat_id", &chatIDs).Error
 	return chatIDs, err
 }
+
+func (repo chatPostgresRepo) GetUserIDs(chatID uint) ([]uint, error) {
+	var userIDs []uint
+	err := repo.db.
+		Table("user_chats").
+		Where("chat_id = ?", chatID).
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+func (repo chatPostgresRepo) IsMember(chatID, userID uint) (bool, error) {
+	var count int64
+	err := repo.db.
+		Model(&ChatMember{}).
+		Where("chat_id = ? AND user_id = ?", chatID, userID).
+		Count(&count).Error
+
+	return count > 0, err
+}
+
+// GetRole возвращает роль участника или пустую строку, если пользователь не состоит в чате
+func (repo chatPostgresRepo) GetRole(chatID, userID uint) (ChatRole, error) {
+	var roles []ChatRole
+	err := repo.db.
+		Model(&ChatMember{}).
+		Where("chat_id = ? AND user_id = ?", chatID, userID).
+		Pluck("role", &roles).Error
+
+	if err != nil || len(roles) == 0 {
+		return "", err
+	}
+	return roles[0], nil
+}
+
+// MarkRead сдвигает позицию прочтения вперёд. Возвращает false, если позиция не изменилась:
+// сообщение уже прочитано или не принадлежит чату
+func (repo chatPostgresRepo) MarkRead(chatID, userID, messageID uint) (bool, error) {
+	result := repo.db.
+		Model(&ChatMember{}).
+		Where("chat_id = ? AND user_id = ? AND last_read_message_id < ?", chatID, userID, messageID).
+		Where("EXISTS (SELECT 1 FROM messages WHERE id = ? AND chat_id = ?)", messageID, chatID).
+		Update("last_read_message_id", messageID)
+
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,127 @@
+//go:build integration
+
+package repository_test
+
+import (
+	"fmt"
+	"os"
+	"socialAPI/internal/storage"
+	"socialAPI/internal/storage/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// База из docker-compose. Схема мигрируется перед тестом
+func newTestDB(t *testing.T) *gorm.DB {
+	dsn := fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		getEnv("DB_HOST", "localhost"),
+		getEnv("DB_PORT", "5432"),
+		getEnv("DB_USER", "postgres"),
+		getEnv("DB_PASSWORD", "postgres"),
+		getEnv("DB_NAME", "socialdb"),
+	)
+
+	db, err := storage.BootstrapDatabase(dsn)
+	if err != nil {
+		t.Skipf("postgres is not available: %v", err)
+	}
+	storage.MadeMigrations(db)
+	return db
+}
+
+// Пользователи с уникальными адресами, чтобы тесты не мешали друг другу в общей базе
+func createTestUsers(t *testing.T, db *gorm.DB, count int) []repository.User {
+	users := make([]repository.User, count)
+	for i := range users {
+		users[i].Email = fmt.Sprintf("user%d-%d@example.com", i, time.Now().UnixNano())
+	}
+	require.NoError(t, db.Create(&users).Error)
+	return users
+}
+
+func createTestChat(t *testing.T, db *gorm.DB, retentionSeconds int64, members ...repository.User) repository.Chat {
+	chat := repository.Chat{Type: repository.ChatTypeGroup, Name: "test", RetentionSeconds: retentionSeconds}
+	require.NoError(t, db.Create(&chat).Error)
+	for _, member := range members {
+		require.NoError(t, db.Create(&repository.ChatMember{UserID: member.ID, ChatID: chat.ID, Role: repository.ChatRoleMember}).Error)
+	}
+	return chat
+}
+
+func TestChatRepo_GetSummariesSkipsExpiredMessages(t *testing.T) {
+	db := newTestDB(t)
+	repo := repository.NewPostgresRepo(db).Chats()
+	users := createTestUsers(t, db, 2)
+	sender, reader := users[0], users[1]
+
+	// Исчезающее сообщение истекло, а в чате со сроком хранения сообщение старше срока.
+	// Сборщик ещё не запускался, поэтому строки на месте
+	expiredAt := time.Now().Add(-time.Minute)
+	disappearing := createTestChat(t, db, 0, sender, reader)
+	require.NoError(t, db.Create(&repository.Message{ChatID: disappearing.ID, SenderID: sender.ID, Content: "gone", ExpiresAt: &expiredAt}).Error)
+
+	retained := createTestChat(t, db, 60, sender, reader)
+	require.NoError(t, db.Create(&repository.Message{ChatID: retained.ID, SenderID: sender.ID, Content: "old", CreatedAt: time.Now().Add(-time.Hour)}).Error)
+
+	summaries, err := repo.GetSummaries(reader.ID, false)
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+
+	for _, summary := range summaries {
+		assert.Nil(t, summary.LastMessageID, "chat %d", summary.ID)
+		assert.Nil(t, summary.LastMessageContent, "chat %d", summary.ID)
+		assert.Zero(t, summary.UnreadCount, "chat %d", summary.ID)
+	}
+}
+
+func TestChatRepo_RoleChecksUseCurrentRoles(t *testing.T) {
+	db := newTestDB(t)
+	repo := repository.NewPostgresRepo(db).Chats()
+	users := createTestUsers(t, db, 3)
+	owner, admin, member := users[0], users[1], users[2]
+
+	chat := createTestChat(t, db, 0, owner, admin, member)
+	setRole := func(user repository.User, role repository.ChatRole) {
+		err := db.Model(&repository.ChatMember{}).Where("chat_id = ? AND user_id = ?", chat.ID, user.ID).Update("role", role).Error
+		require.NoError(t, err)
+	}
+	setRole(owner, repository.ChatRoleOwner)
+	setRole(admin, repository.ChatRoleAdmin)
+
+	// Роль владельца не меняет никто
+	_, err := repo.SetRole(chat.ID, admin.ID, owner.ID, repository.ChatRoleMember)
+	assert.ErrorIs(t, err, repository.ErrNotAllowed)
+
+	// Админа понизили между проверкой в сервисе и изменением
+	setRole(admin, repository.ChatRoleMember)
+
+	_, err = repo.Rename(chat.ID, admin.ID, "renamed")
+	assert.ErrorIs(t, err, repository.ErrNotAllowed)
+
+	_, err = repo.SetRole(chat.ID, admin.ID, member.ID, repository.ChatRoleAdmin)
+	assert.ErrorIs(t, err, repository.ErrNotAllowed)
+
+	_, err = repo.RemoveMember(chat.ID, admin.ID, member.ID)
+	assert.ErrorIs(t, err, repository.ErrNotAllowed)
+
+	isMember, err := repo.IsMember(chat.ID, member.ID)
+	require.NoError(t, err)
+	assert.True(t, isMember)
+
+	// Выйти из чата можно с любой ролью
+	messages, err := repo.RemoveMember(chat.ID, admin.ID, admin.ID)
+	require.NoError(t, err)
+	assert.Len(t, messages, 1)
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInviteUnavailable - приглашение отозвано, истекло или исчерпало число входов
+var ErrInviteUnavailable = errors.New("invite is revoked, expired or used up")
+
+// ChatInviteDTO - приглашение для админов чата. Uses - сколько раз по нему вошли
+type ChatInviteDTO struct {
+	ID        uint       `json:"id"`
+	Code      string     `json:"code"`
+	CreatorID uint       `json:"creator_id"`
+	MaxUses   *int       `json:"max_uses,omitempty"`
+	Uses      int        `json:"uses"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ConvertToDTO преобразует ChatInvite в ChatInviteDTO
+func (invite *ChatInvite) ConvertToDTO() ChatInviteDTO {
+	return ChatInviteDTO{
+		ID:        invite.ID,
+		Code:      invite.Code,
+		CreatorID: invite.CreatorID,
+		MaxUses:   invite.MaxUses,
+		Uses:      invite.Uses,
+		ExpiresAt: invite.ExpiresAt,
+		RevokedAt: invite.RevokedAt,
+		CreatedAt: invite.CreatedAt,
+	}
+}
+
+// Available сообщает, можно ли войти по приглашению в момент now
+func (invite *ChatInvite) Available(now time.Time) bool {
+	if invite.RevokedAt != nil {
+		return false
+	}
+	if invite.ExpiresAt != nil && !invite.ExpiresAt.After(now) {
+		return false
+	}
+	return invite.MaxUses == nil || invite.Uses < *invite.MaxUses
+}
+
+func (repo chatPostgresRepo) CreateInvite(invite *ChatInvite) error {
+	return repo.db.Create(invite).Error
+}
+
+// GetInvites возвращает приглашения чата, начиная с новых
+func (repo chatPostgresRepo) GetInvites(chatID uint) ([]ChatInvite, error) {
+	var invites []ChatInvite
+	err := repo.db.Where("chat_id = ?", chatID).Order("id DESC").Find(&invites).Error
+	return invites, err
+}
+
+// RevokeInvite отзывает приглашение. Возвращает false, если его нет в чате или оно уже отозвано
+func (repo chatPostgresRepo) RevokeInvite(chatID, inviteID uint) (bool, error) {
+	result := repo.db.
+		Model(&ChatInvite{}).
+		Where("id = ? AND chat_id = ? AND revoked_at IS NULL", inviteID, chatID).
+		Update("revoked_at", time.Now())
+
+	return result.RowsAffected > 0, result.Error
+}
+
+// JoinByInvite добавляет userID в чат по коду приглашения. Строка приглашения блокируется, поэтому
+// лимит входов не превышается при параллельных запросах. Каждый вход пишется в chat_invite_uses.
+// Если пользователь уже в чате, вход не засчитывается и сообщение равно nil, даже если приглашение
+// больше не действует. Когда код найден, ID чата возвращается и вместе с ошибкой
+func (repo chatPostgresRepo) JoinByInvite(code string, userID uint) (uint, *Message, error) {
+	var chatID uint
+	var message *Message
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var invite ChatInvite
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).Take(&invite).Error
+		if err != nil {
+			return err
+		}
+		chatID = invite.ChatID
+
+		var member int64
+		err = tx.Model(&ChatMember{}).Where("chat_id = ? AND user_id = ?", invite.ChatID, userID).Count(&member).Error
+		if err != nil || member > 0 {
+			return err
+		}
+
+		if !invite.Available(time.Now()) {
+			return ErrInviteUnavailable
+		}
+
+		if err := lockChat(tx, invite.ChatID); errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInviteUnavailable
+		} else if err != nil {
+			return err
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&ChatMember{UserID: userID, ChatID: invite.ChatID, Role: ChatRoleMember})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		err = tx.Model(&invite).Update("uses", gorm.Expr("uses + 1")).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Create(&ChatInviteUse{InviteID: invite.ID, UserID: userID}).Error; err != nil {
+			return err
+		}
+
+		record := systemMessage(invite.ChatID, userID, SystemEvent{Action: SystemMemberJoined})
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		message = &record
+		return nil
+	})
+	return chatID, message, err
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ChatSettingsDTO - личные настройки чата пользователя. MutedUntil задан только у временно заглушённого чата
+type ChatSettingsDTO struct {
+	Muted      bool       `json:"muted"`
+	MutedUntil *time.Time `json:"muted_until,omitempty"`
+	Archived   bool       `json:"archived"`
+	Pinned     bool       `json:"pinned"`
+}
+
+// ConvertToSettingsDTO возвращает настройки участника на момент now: истёкшее заглушение не показывается
+func (member *ChatMember) ConvertToSettingsDTO(now time.Time) ChatSettingsDTO {
+	dto := ChatSettingsDTO{
+		Muted:    member.IsMuted(now),
+		Archived: member.ArchivedAt != nil,
+		Pinned:   member.PinnedAt != nil,
+	}
+	if dto.Muted {
+		dto.MutedUntil = member.MutedUntil
+	}
+	return dto
+}
+
+// UpdateSettings меняет личные настройки чата. changes - значения колонок user_chats.
+// Если пользователь не участник чата, возвращает gorm.ErrRecordNotFound
+func (repo chatPostgresRepo) UpdateSettings(chatID, userID uint, changes map[string]interface{}) (*ChatMember, error) {
+	var member ChatMember
+	result := repo.db.Model(&member).
+		Clauses(clause.Returning{}).
+		Where("chat_id = ? AND user_id = ?", chatID, userID).
+		Updates(changes)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &member, nil
+}
+
+// GetNotifiableUserIDs возвращает по каждому чату участников, которые его не заглушили
+func (repo chatPostgresRepo) GetNotifiableUserIDs(chatIDs []uint) (map[uint][]uint, error) {
+	var rows []ChatMember
+	err := repo.db.Select("chat_id", "user_id").
+		Where("chat_id IN ?", chatIDs).
+		Where("NOT muted OR (muted_until IS NOT NULL AND muted_until <= NOW())").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make(map[uint][]uint, len(chatIDs))
+	for _, row := range rows {
+		recipients[row.ChatID] = append(recipients[row.ChatID], row.UserID)
+	}
+	return recipients, nil
+}
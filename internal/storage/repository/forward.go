@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ForwardOrigin - исходное сообщение пересланного. При пересылке пересланного сохраняется первый источник
+type ForwardOrigin struct {
+	MessageID uint `json:"message_id"`
+	ChatID    uint `json:"chat_id"`
+	SenderID  uint `json:"sender_id"`
+}
+
+// Value сохраняет источник в колонку jsonb
+func (origin ForwardOrigin) Value() (driver.Value, error) {
+	return json.Marshal(origin)
+}
+
+func (origin *ForwardOrigin) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case []byte:
+		return json.Unmarshal(data, origin)
+	case string:
+		return json.Unmarshal([]byte(data), origin)
+	default:
+		return fmt.Errorf("unsupported forward origin type %T", value)
+	}
+}
+
+// Origin возвращает источник для пересылки сообщения
+func (message *Message) Origin() *ForwardOrigin {
+	if message.ForwardedFrom != nil {
+		return message.ForwardedFrom
+	}
+	return &ForwardOrigin{MessageID: message.ID, ChatID: message.ChatID, SenderID: message.SenderID}
+}
+
+// Forward пересылает сообщение от имени senderID в каждый из чатов chatIDs. Вложения копируются
+// в целевые чаты и ссылаются на те же файлы хранилища. Упоминания в пересланном тексте не срабатывают.
+// Возвращает созданные сообщения в порядке chatIDs
+func (repo messagePostgresRepo) Forward(source *Message, senderID uint, chatIDs []uint) ([]Message, error) {
+	messages := make([]*Message, 0, len(chatIDs))
+	for _, chatID := range chatIDs {
+		messages = append(messages, &Message{
+			ChatID:        chatID,
+			SenderID:      senderID,
+			Content:       source.Content,
+			Kind:          MessageKindText,
+			ForwardedFrom: source.Origin(),
+		})
+	}
+
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := createBatch(tx, messages); err != nil {
+			return err
+		}
+
+		for _, message := range messages {
+			for _, attachment := range source.Attachments {
+				messageID := message.ID
+				message.Attachments = append(message.Attachments, Attachment{
+					ChatID:       message.ChatID,
+					UploaderID:   senderID,
+					MessageID:    &messageID,
+					FileName:     attachment.FileName,
+					ContentType:  attachment.ContentType,
+					Size:         attachment.Size,
+					StorageKey:   attachment.StorageKey,
+					ThumbnailKey: attachment.ThumbnailKey,
+				})
+			}
+
+			if len(message.Attachments) == 0 {
+				continue
+			}
+			if err := tx.Create(&message.Attachments).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	forwarded := make([]Message, 0, len(messages))
+	for _, message := range messages {
+		forwarded = append(forwarded, *message)
+	}
+	return forwarded, nil
+}
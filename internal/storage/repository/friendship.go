@@ -14,6 +14,7 @@ type FriendshipRepository interface {
 	GetAllFriends(userID uint, status *FriendshipStatus) ([]*FriendWithID, error)
 	SetStatus(friendshipID uint, status FriendshipStatus) error
 	Exists(senderID, receiverID uint) (bool, error)
+	AreFriends(userID, otherID uint) (bool, error)
 }
 
 type friendshipPostgresRepo struct {
@@ -103,3 +104,15 @@ func (repo friendshipPostgresRepo) Exists(senderID, receiverID uint) (bool, erro
 
 	return count > 0, nil
 }
+
+// AreFriends проверяет, что между пользователями есть принятая заявка в друзья
+func (repo friendshipPostgresRepo) AreFriends(userID, otherID uint) (bool, error) {
+	var count int64
+	err := repo.db.Model(&Friendship{}).
+		Where(
+			"((sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)) AND status = ?",
+			userID, otherID, otherID, userID, StatusFriendship,
+		).Count(&count).Error
+
+	return count > 0, err
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// mentionPattern находит упоминания по email (@bob@example.com) и @all. Упоминание начинается
+// с начала строки или после символа, который не может быть частью адреса
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w.@+-])@([\w.%+-]+@[\w-]+(?:\.[\w-]+)+|all\b)`)
+
+// MentionList - ID упомянутых пользователей, хранится в колонке jsonb
+type MentionList []uint
+
+func (list MentionList) Value() (driver.Value, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return json.Marshal([]uint(list))
+}
+
+func (list *MentionList) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case nil:
+		*list = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(data, (*[]uint)(list))
+	case string:
+		return json.Unmarshal([]byte(data), (*[]uint)(list))
+	default:
+		return fmt.Errorf("unsupported mention list type %T", value)
+	}
+}
+
+// MentionDTO - сообщение с упоминанием пользователя, для GET /v1/me/mentions
+type MentionDTO struct {
+	ChatID  uint       `json:"chat_id"`
+	Message MessageDTO `json:"message"`
+}
+
+// ParseMentions возвращает email упомянутых в тексте пользователей в нижнем регистре и признак @all
+func ParseMentions(content string) ([]string, bool) {
+	var emails []string
+	all := false
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		if match[1] == "all" {
+			all = true
+			continue
+		}
+		emails = append(emails, strings.ToLower(match[1]))
+	}
+	return emails, all
+}
+
+// mentionCandidate - участник чата, которого можно упомянуть
+type mentionCandidate struct {
+	UserID uint
+	Email  string
+	Role   ChatRole
+}
+
+// resolveMentions проверяет упоминания сообщений до сохранения: упомянуть можно только участников чата,
+// кроме себя, а @all засчитывается только владельцу и админам. Остальные упоминания, как и упоминания
+// в пересланных сообщениях, остаются обычным текстом
+func resolveMentions(tx *gorm.DB, messages []*Message) error {
+	for _, message := range messages {
+		if message.Kind == MessageKindSystem || message.ForwardedFrom != nil {
+			continue
+		}
+
+		emails, all := ParseMentions(message.Content)
+		if len(emails) == 0 && len(message.MentionIDs) == 0 && !all {
+			continue
+		}
+
+		query := tx.Table("user_chats AS uc").
+			Select("uc.user_id, u.email, uc.role").
+			Joins("JOIN users u ON u.id = uc.user_id").
+			Where("uc.chat_id = ?", message.ChatID)
+		if !all {
+			query = query.Where("uc.user_id = ? OR uc.user_id IN ? OR LOWER(u.email) IN ?", message.SenderID, message.MentionIDs, emails)
+		}
+
+		var candidates []mentionCandidate
+		if err := query.Order("uc.user_id").Scan(&candidates).Error; err != nil {
+			return err
+		}
+
+		requested := make(map[uint]bool, len(message.MentionIDs))
+		for _, id := range message.MentionIDs {
+			requested[id] = true
+		}
+		named := make(map[string]bool, len(emails))
+		for _, email := range emails {
+			named[email] = true
+		}
+
+		var senderRole ChatRole
+		for _, candidate := range candidates {
+			if candidate.UserID == message.SenderID {
+				senderRole = candidate.Role
+			}
+		}
+		message.MentionsAll = all && senderRole.CanModerate()
+
+		for _, candidate := range candidates {
+			if candidate.UserID == message.SenderID {
+				continue
+			}
+
+			if requested[candidate.UserID] || named[strings.ToLower(candidate.Email)] {
+				message.Mentions = append(message.Mentions, candidate.UserID)
+				message.Mentioned = append(message.Mentioned, candidate.UserID)
+			} else if message.MentionsAll {
+				message.Mentioned = append(message.Mentioned, candidate.UserID)
+			}
+		}
+	}
+	return nil
+}
+
+// createMentions сохраняет получателей упоминаний уже созданных сообщений
+func createMentions(tx *gorm.DB, messages []*Message) error {
+	var mentions []MessageMention
+	for _, message := range messages {
+		explicit := make(map[uint]bool, len(message.Mentions))
+		for _, id := range message.Mentions {
+			explicit[id] = true
+		}
+
+		for _, userID := range message.Mentioned {
+			mentions = append(mentions, MessageMention{MessageID: message.ID, UserID: userID, ChatID: message.ChatID, All: !explicit[userID]})
+		}
+	}
+
+	if len(mentions) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(&mentions, 1000).Error
+}
+
+// ListMentions возвращает неудалённые сообщения с упоминанием пользователя из чатов, где он состоит,
+// с ID меньше beforeID (0 - начиная с последнего), от новых к старым
+func (repo messagePostgresRepo) ListMentions(userID, beforeID uint, limit int) ([]Message, error) {
+	query := repo.db.
+		Preload("Sender").
+		Preload("ReplyTo").
+		Preload("Reactions", orderReactions).
+		Preload("Attachments").
+		Scopes(preloadPoll(""), liveMessages).
+		Joins("JOIN message_mentions mm ON mm.message_id = messages.id AND mm.user_id = ?", userID).
+		Joins("JOIN user_chats uc ON uc.chat_id = messages.chat_id AND uc.user_id = ?", userID).
+		Where("messages.deleted_at IS NULL")
+	if beforeID > 0 {
+		query = query.Where("messages.id < ?", beforeID)
+	}
+
+	var messages []Message
+	err := query.Order("messages.id DESC").Limit(limit).Find(&messages).Error
+	return messages, err
+}
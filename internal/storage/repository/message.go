@@ -1,9 +1,37 @@
 package repository
 
-import "gorm.io/gorm"
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrMessageDeleted - сообщение удалено, пока ожидалась блокировка строки
+var ErrMessageDeleted = errors.New("message is deleted")
 
 type MessageRepository interface {
 	Create(chatID, senderID uint, content string) error
+	CreateBatch(messages []*Message) error
+	ListAfter(cursors map[uint]uint, limit int) ([]Message, error)
+	GetByID(messageID uint) (*Message, error)
+	GetByIDs(messageIDs []uint) ([]Message, error)
+	ListReplies(parentID, afterID uint, limit int) ([]Message, error)
+	Edit(messageID, editorID uint, content string) (*Message, error)
+	SoftDelete(messageID, editorID uint) (*Message, error)
+	AddReaction(messageID, userID uint, emoji string) (bool, error)
+	RemoveReaction(messageID, userID uint, emoji string) (bool, error)
+	CountReactions(messageID uint, emoji string) (int64, error)
+	Pin(chatID, messageID, userID uint, limit int) (*PinnedMessage, error)
+	Unpin(chatID, messageID uint) (bool, error)
+	ListPins(chatID uint) ([]PinnedMessage, error)
+	Vote(messageID, userID uint, optionIDs []uint) (*Poll, error)
+	ClosePoll(messageID uint) (*Poll, error)
+	ListMentions(userID, beforeID uint, limit int) ([]Message, error)
+	Forward(source *Message, senderID uint, chatIDs []uint) ([]Message, error)
+	DeleteExpired(now time.Time, maxAge time.Duration, limit int) ([]ExpiredMessage, []string, error)
 }
 
 type messagePostgresRepo struct {
@@ -19,3 +47,217 @@ func (repo messagePostgresRepo) Create(chatID, senderID uint, content string) er
 
 	return repo.db.Create(&message).Error
 }
+
+// CreateBatch сохраняет пачку сообщений одним INSERT, ID проставляются в порядке слайса.
+// В той же транзакции увеличиваются счётчики ответов у цитируемых сообщений и привязываются
+// вложения из AttachmentIDs. Привязываются только свободные вложения того же чата, загруженные
+// отправителем, привязанные попадают в Attachments
+func (repo messagePostgresRepo) CreateBatch(messages []*Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		return createBatch(tx, messages)
+	})
+}
+
+// createBatch - тело CreateBatch, выполняемое в транзакции вызывающего
+func createBatch(tx *gorm.DB, messages []*Message) error {
+	replies := make(map[uint]int)
+	for _, message := range messages {
+		if message.ReplyToID != nil {
+			replies[*message.ReplyToID]++
+		}
+	}
+
+	if err := stampExpiry(tx, messages); err != nil {
+		return err
+	}
+
+	if err := resolveMentions(tx, messages); err != nil {
+		return err
+	}
+
+	if err := tx.Omit("ReplyTo", "Attachments").Create(&messages).Error; err != nil {
+		return err
+	}
+
+	if err := createMentions(tx, messages); err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		if len(message.AttachmentIDs) == 0 {
+			continue
+		}
+
+		err := tx.Model(&message.Attachments).
+			Clauses(clause.Returning{}).
+			Where("id IN ? AND chat_id = ? AND uploader_id = ? AND message_id IS NULL", message.AttachmentIDs, message.ChatID, message.SenderID).
+			Update("message_id", message.ID).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	for parentID, count := range replies {
+		err := tx.Model(&Message{}).
+			Where("id = ?", parentID).
+			UpdateColumn("reply_count", gorm.Expr("reply_count + ?", count)).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ListAfter возвращает сообщения с ID больше курсора в каждом чате (chatID -> messageID),
+// не больше limit на чат, отсортированные по чату и ID
+func (repo messagePostgresRepo) ListAfter(cursors map[uint]uint, limit int) ([]Message, error) {
+	var messages []Message
+	if len(cursors) == 0 {
+		return messages, nil
+	}
+
+	conditions := make([]string, 0, len(cursors))
+	args := make([]interface{}, 0, len(cursors)*2)
+	for chatID, messageID := range cursors {
+		conditions = append(conditions, "(chat_id = ? AND id > ?)")
+		args = append(args, chatID, messageID)
+	}
+
+	ranked := repo.db.
+		Model(&Message{}).
+		Select("*, ROW_NUMBER() OVER (PARTITION BY chat_id ORDER BY id) AS rn").
+		Where(strings.Join(conditions, " OR "), args...).
+		Scopes(liveMessages)
+
+	err := repo.db.
+		Preload("ReplyTo").
+		Preload("Attachments").
+		Scopes(preloadPoll("")).
+		Table("(?) AS ranked", ranked).
+		Where("rn <= ?", limit).
+		Order("chat_id, id").
+		Find(&messages).Error
+
+	return messages, err
+}
+
+func (repo messagePostgresRepo) GetByID(messageID uint) (*Message, error) {
+	var message Message
+	if err := repo.db.Preload("Sender").Preload("Reactions", orderReactions).Preload("Attachments").Scopes(preloadPoll(""), liveMessages).First(&message, messageID).Error; err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// Edit сохраняет текущий текст в ревизию и заменяет его новым
+func (repo messagePostgresRepo) Edit(messageID, editorID uint, content string) (*Message, error) {
+	return repo.revise(messageID, editorID, func(message *Message, now time.Time) map[string]interface{} {
+		message.Content, message.EditedAt = content, &now
+		return map[string]interface{}{"content": content, "edited_at": now}
+	})
+}
+
+// SoftDelete сохраняет текущий текст в ревизию и оставляет вместо сообщения tombstone.
+// Счётчик ответов родителя уменьшается в той же транзакции
+func (repo messagePostgresRepo) SoftDelete(messageID, editorID uint) (*Message, error) {
+	return repo.revise(messageID, editorID, func(message *Message, now time.Time) map[string]interface{} {
+		message.Content, message.DeletedAt = "", &now
+		return map[string]interface{}{"content": "", "deleted_at": now}
+	})
+}
+
+func (repo messagePostgresRepo) revise(messageID, editorID uint, changes func(message *Message, now time.Time) map[string]interface{}) (*Message, error) {
+	var message Message
+
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&message, messageID).Error; err != nil {
+			return err
+		}
+		if message.DeletedAt != nil {
+			return ErrMessageDeleted
+		}
+
+		revision := MessageRevision{MessageID: message.ID, EditorID: editorID, Content: message.Content}
+		if err := tx.Create(&revision).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&message).Updates(changes(&message, time.Now())).Error; err != nil {
+			return err
+		}
+
+		// Удалённый ответ больше не считается в счётчике ответов родителя
+		if message.DeletedAt == nil || message.ReplyToID == nil {
+			return nil
+		}
+		return tx.Model(&Message{}).
+			Where("id = ? AND reply_count > 0", *message.ReplyToID).
+			UpdateColumn("reply_count", gorm.Expr("reply_count - 1")).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repo.db.Model(&message).Association("Sender").Find(&message.Sender); err != nil {
+		return nil, err
+	}
+	if err := orderReactions(repo.db).Where("message_id = ?", message.ID).Find(&message.Reactions).Error; err != nil {
+		return nil, err
+	}
+	if err := repo.db.Where("message_id = ?", message.ID).Find(&message.Attachments).Error; err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (repo messagePostgresRepo) GetByIDs(messageIDs []uint) ([]Message, error) {
+	var messages []Message
+	err := repo.db.Where("id IN ?", messageIDs).Find(&messages).Error
+	return messages, err
+}
+
+// ListReplies возвращает ответы на сообщение с ID больше afterID по возрастанию ID
+func (repo messagePostgresRepo) ListReplies(parentID, afterID uint, limit int) ([]Message, error) {
+	var messages []Message
+	err := repo.db.
+		Preload("Sender").
+		Preload("Reactions", orderReactions).
+		Preload("Attachments").
+		Scopes(preloadPoll(""), liveMessages).
+		Where("reply_to_id = ? AND id > ?", parentID, afterID).
+		Order("id").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
+
+// orderReactions сортирует подгружаемые реакции по времени, чтобы порядок эмодзи в DTO был стабильным
+func orderReactions(db *gorm.DB) *gorm.DB {
+	return db.Order("created_at")
+}
+
+// AddReaction добавляет реакцию, повторная реакция тем же эмодзи игнорируется. Возвращает true, если реакция добавлена
+func (repo messagePostgresRepo) AddReaction(messageID, userID uint, emoji string) (bool, error) {
+	reaction := MessageReaction{MessageID: messageID, UserID: userID, Emoji: emoji}
+	result := repo.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&reaction)
+	return result.RowsAffected > 0, result.Error
+}
+
+// RemoveReaction снимает реакцию. Возвращает true, если реакция была
+func (repo messagePostgresRepo) RemoveReaction(messageID, userID uint, emoji string) (bool, error) {
+	result := repo.db.
+		Where("message_id = ? AND user_id = ? AND emoji = ?", messageID, userID, emoji).
+		Delete(&MessageReaction{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (repo messagePostgresRepo) CountReactions(messageID uint, emoji string) (int64, error) {
+	var count int64
+	err := repo.db.Model(&MessageReaction{}).Where("message_id = ? AND emoji = ?", messageID, emoji).Count(&count).Error
+	return count, err
+}
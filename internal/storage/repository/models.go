@@ -1,6 +1,11 @@
 package repository
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 type FriendshipStatus string
 
@@ -15,6 +20,10 @@ type User struct {
 	Email    string `gorm:"unique;not null" json:"email"`
 	Password string `json:"-"`
 
+	// Присутствие отдаётся только через /v1/user/presence с учётом HidePresence
+	LastSeenAt   *time.Time `json:"-"`
+	HidePresence bool       `gorm:"not null;default:false" json:"-"`
+
 	Chats    []Chat    `json:"chats,omitempty" gorm:"many2many:user_chats;"`
 	Messages []Message `json:"messages,omitempty" gorm:"foreignKey:SenderID"`
 }
@@ -42,25 +51,292 @@ type RefreshToken struct {
 	User User `gorm:"foreignKey:UserID"`
 }
 
+type ChatType string
+
+const (
+	ChatTypeGroup  ChatType = "group"
+	ChatTypeDirect ChatType = "direct"
+)
+
 type Chat struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Name      string    `json:"name,omitempty"`
-	Users     []User    `gorm:"many2many:user_chats;" json:"users,omitempty"`
-	Messages  []Message `gorm:"foreignKey:ChatID" json:"messages,omitempty"`
+	ID       uint      `gorm:"primaryKey" json:"id"`
+	Type     ChatType  `gorm:"type:varchar(16);not null;default:'group'" json:"type"`
+	Name     string    `json:"name,omitempty"`
+	Users    []User    `gorm:"many2many:user_chats;" json:"users,omitempty"`
+	Messages []Message `gorm:"foreignKey:ChatID" json:"messages,omitempty"`
+	// Участники с ролями - те же строки user_chats, что и Users
+	Members   []ChatMember    `gorm:"foreignKey:ChatID" json:"-"`
+	Pins      []PinnedMessage `gorm:"foreignKey:ChatID" json:"-"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	// Чат архивируется, когда из него уходит последний участник
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+
+	// Сколько хранить сообщения чата, 0 - без ограничения чата (действует только глобальный предел).
+	// Ненулевой MessageTTLSeconds включает исчезающие сообщения: каждое новое получает ExpiresAt
+	RetentionSeconds  int64 `gorm:"not null;default:0" json:"retention_seconds"`
+	MessageTTLSeconds int64 `gorm:"not null;default:0" json:"message_ttl_seconds"`
+
+	// Участники личного чата в порядке возрастания ID. Уникальный индекс по паре не даёт
+	// создать второй личный чат, у групп оба поля NULL
+	DirectUserLowID  *uint `gorm:"uniqueIndex:idx_chats_direct_pair" json:"-"`
+	DirectUserHighID *uint `gorm:"uniqueIndex:idx_chats_direct_pair" json:"-"`
+}
+
+type ChatRole string
+
+const (
+	ChatRoleMember ChatRole = "member"
+	ChatRoleAdmin  ChatRole = "admin"
+	ChatRoleOwner  ChatRole = "owner"
+)
+
+// CanModerate сообщает, может ли участник с этой ролью менять состав и название чата
+func (role ChatRole) CanModerate() bool {
+	return role == ChatRoleOwner || role == ChatRoleAdmin
+}
+
+// CanManage сообщает, может ли участник с этой ролью исключить участника с ролью target
+// или сменить ему роль. Владельца не трогает никто, админами распоряжается только владелец
+func (role ChatRole) CanManage(target ChatRole) bool {
+	switch target {
+	case ChatRoleOwner:
+		return false
+	case ChatRoleAdmin:
+		return role == ChatRoleOwner
+	default:
+		return role.CanModerate()
+	}
+}
+
+// ChatMember - строка связи user_chats с ролью и позицией прочтения участника
+type ChatMember struct {
+	UserID            uint      `gorm:"primaryKey"`
+	ChatID            uint      `gorm:"primaryKey"`
+	Role              ChatRole  `gorm:"not null;default:'member'"`
+	LastReadMessageID uint      `gorm:"not null;default:0"`
+	JoinedAt          time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+
+	// Личные настройки чата, другие участники их не видят. Muted без MutedUntil - бессрочно
+	Muted      bool `gorm:"not null;default:false"`
+	MutedUntil *time.Time
+	ArchivedAt *time.Time
+	PinnedAt   *time.Time
+}
+
+// IsMuted сообщает, заглушён ли чат в момент now
+func (member *ChatMember) IsMuted(now time.Time) bool {
+	return member.Muted && (member.MutedUntil == nil || member.MutedUntil.After(now))
+}
+
+func (ChatMember) TableName() string {
+	return "user_chats"
+}
+
+type MessageKind string
+
+const (
+	MessageKindText   MessageKind = "text"
+	MessageKindSystem MessageKind = "system"
+	MessageKindPoll   MessageKind = "poll"
+)
+
+type SystemAction string
+
+const (
+	SystemChatCreated      SystemAction = "chat_created"
+	SystemChatRenamed      SystemAction = "chat_renamed"
+	SystemMembersAdded     SystemAction = "members_added"
+	SystemMembersRemoved   SystemAction = "members_removed"
+	SystemMemberLeft       SystemAction = "member_left"
+	SystemMemberJoined     SystemAction = "member_joined"
+	SystemRoleChanged      SystemAction = "role_changed"
+	SystemRetentionChanged SystemAction = "retention_changed"
+)
+
+// SystemEvent - содержимое системного сообщения об изменении чата. Автор изменения - отправитель сообщения
+type SystemEvent struct {
+	Action  SystemAction `json:"action"`
+	UserIDs []uint       `json:"user_ids,omitempty"`
+	Name    string       `json:"name,omitempty"`
+	Role    ChatRole     `json:"role,omitempty"`
+
+	RetentionSeconds  *int64 `json:"retention_seconds,omitempty"`
+	MessageTTLSeconds *int64 `json:"message_ttl_seconds,omitempty"`
+}
+
+// Value сохраняет событие в колонку jsonb
+func (event SystemEvent) Value() (driver.Value, error) {
+	return json.Marshal(event)
+}
+
+func (event *SystemEvent) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case []byte:
+		return json.Unmarshal(data, event)
+	case string:
+		return json.Unmarshal([]byte(data), event)
+	default:
+		return fmt.Errorf("unsupported system event type %T", value)
+	}
+}
+
+type Message struct {
+	ID       uint   `gorm:"primaryKey;index:idx_messages_chat_id_id,priority:2" json:"id"`
+	ChatID   uint   `gorm:"not null;index:idx_messages_chat_id_id,priority:1" json:"chat_id"`
+	SenderID uint   `gorm:"not null" json:"sender_id"`
+	Content  string `gorm:"not null" json:"content"`
+
+	// Системные сообщения пишет сервер при изменении чата, в System - что именно изменилось.
+	// У опроса вопрос хранится в Content, варианты и голоса - в Poll
+	Kind   MessageKind  `gorm:"type:varchar(16);not null;default:'text'" json:"kind"`
+	System *SystemEvent `gorm:"type:jsonb" json:"system,omitempty"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// Удалённое сообщение остаётся в истории как tombstone с пустым текстом
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	// Исчезающее сообщение удаляется сборщиком после ExpiresAt
+	ExpiresAt *time.Time `gorm:"index" json:"expires_at,omitempty"`
+
+	// Источник пересланного сообщения
+	ForwardedFrom *ForwardOrigin `gorm:"type:jsonb" json:"forwarded_from,omitempty"`
+
+	// Явно упомянутые участники и упоминание всех через @all. Все получатели упоминания - в message_mentions
+	Mentions    MentionList `gorm:"type:jsonb" json:"mentions,omitempty"`
+	MentionsAll bool        `gorm:"not null;default:false" json:"mentions_all,omitempty"`
+
+	// Ответ на сообщение того же чата. ReplyCount - число ответов на это сообщение
+	ReplyToID  *uint `gorm:"index" json:"reply_to_id,omitempty"`
+	ReplyCount int   `gorm:"not null;default:0" json:"reply_count"`
+
+	Chat      Chat              `gorm:"foreignKey:ChatID" json:"chat,omitempty"`
+	Sender    User              `gorm:"foreignKey:SenderID" json:"sender,omitempty"`
+	ReplyTo   *Message          `gorm:"foreignKey:ReplyToID" json:"reply_to,omitempty"`
+	Reactions []MessageReaction `gorm:"foreignKey:MessageID" json:"-"`
+	Poll      *Poll             `gorm:"foreignKey:MessageID" json:"-"`
+
+	Attachments []Attachment `gorm:"foreignKey:MessageID" json:"-"`
+	// Вложения, которые нужно привязать к сообщению при сохранении
+	AttachmentIDs []uint `gorm:"-" json:"-"`
+	// Пользователи, упомянутые клиентом явно. Проверяются при сохранении вместе с упоминаниями из текста
+	MentionIDs []uint `gorm:"-" json:"-"`
+	// Все упомянутые при сохранении, включая участников из @all
+	Mentioned []uint `gorm:"-" json:"-"`
 }
 
-type Message struct {
+// MessageMention - упоминание пользователя в сообщении. All - пользователь упомянут только через @all
+type MessageMention struct {
+	MessageID uint `gorm:"primaryKey;index:idx_message_mentions_user_id_message_id,priority:2"`
+	UserID    uint `gorm:"primaryKey;index:idx_message_mentions_user_id_message_id,priority:1"`
+	ChatID    uint `gorm:"not null"`
+	All       bool `gorm:"not null;default:false"`
+}
+
+// Attachment - загруженный в чат файл. Пока MessageID пуст, вложение видно только загрузившему
+type Attachment struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	ChatID       uint      `gorm:"not null;index" json:"chat_id"`
+	UploaderID   uint      `gorm:"not null" json:"uploader_id"`
+	MessageID    *uint     `gorm:"index" json:"message_id,omitempty"`
+	FileName     string    `gorm:"not null" json:"file_name"`
+	ContentType  string    `gorm:"not null" json:"content_type"`
+	Size         int64     `gorm:"not null" json:"size"`
+	StorageKey   string    `gorm:"not null" json:"-"`
+	ThumbnailKey *string   `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Poll - опрос, отправленный сообщением вида poll. Опрос закрыт, если задан ClosedAt или прошло ClosesAt
+type Poll struct {
+	MessageID uint `gorm:"primaryKey"`
+	Multiple  bool `gorm:"not null;default:false"`
+	Anonymous bool `gorm:"not null;default:false"`
+	ClosesAt  *time.Time
+	ClosedAt  *time.Time
+
+	Options []PollOption `gorm:"foreignKey:MessageID"`
+	Votes   []PollVote   `gorm:"foreignKey:MessageID"`
+}
+
+// PollOption - вариант ответа, Position задаёт порядок вывода
+type PollOption struct {
+	ID        uint   `gorm:"primaryKey"`
+	MessageID uint   `gorm:"not null;index"`
+	Position  int    `gorm:"not null"`
+	Text      string `gorm:"not null"`
+}
+
+// PollVote - голос пользователя за вариант. В опросе с одним ответом у пользователя не больше одного голоса
+type PollVote struct {
+	MessageID uint `gorm:"primaryKey"`
+	UserID    uint `gorm:"primaryKey"`
+	OptionID  uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+}
+
+// ScheduledMessage - сообщение, отложенное до SendAt. После отправки запись удаляется,
+// поэтому отредактировать или отменить можно только ещё не отправленное сообщение
+type ScheduledMessage struct {
+	ID        uint   `gorm:"primaryKey"`
+	ChatID    uint   `gorm:"not null;index"`
+	SenderID  uint   `gorm:"not null;index"`
+	Content   string `gorm:"not null"`
+	ReplyToID *uint
+	SendAt    time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// PinnedMessage - закреплённое в чате сообщение
+type PinnedMessage struct {
+	ChatID    uint      `gorm:"primaryKey"`
+	MessageID uint      `gorm:"primaryKey"`
+	PinnedBy  uint      `gorm:"not null"`
+	PinnedAt  time.Time `gorm:"not null"`
+
+	Message Message `gorm:"foreignKey:MessageID"`
+}
+
+// ChatInvite - ссылка-приглашение в групповой чат. MaxUses == nil - без ограничения числа входов
+type ChatInvite struct {
+	ID        uint   `gorm:"primaryKey"`
+	ChatID    uint   `gorm:"not null;index"`
+	CreatorID uint   `gorm:"not null"`
+	Code      string `gorm:"not null;uniqueIndex"`
+	MaxUses   *int
+	Uses      int `gorm:"not null;default:0"`
+	ExpiresAt *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// ChatInviteUse - журнал входов в чат по приглашениям
+type ChatInviteUse struct {
+	ID        uint `gorm:"primaryKey"`
+	InviteID  uint `gorm:"not null;index"`
+	UserID    uint `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+// MessageReaction - реакция пользователя на сообщение, один эмодзи от пользователя учитывается один раз
+type MessageReaction struct {
+	MessageID uint      `gorm:"primaryKey" json:"message_id"`
+	UserID    uint      `gorm:"primaryKey" json:"user_id"`
+	Emoji     string    `gorm:"primaryKey" json:"emoji"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MessageRevision - предыдущая версия сообщения, сохраняется при каждом изменении и удалении
+type MessageRevision struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
-	ChatID    uint      `gorm:"not null" json:"chat_id"`
-	SenderID  uint      `gorm:"not null" json:"sender_id"`
+	MessageID uint      `gorm:"not null;index" json:"message_id"`
+	EditorID  uint      `gorm:"not null" json:"editor_id"`
 	Content   string    `gorm:"not null" json:"content"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-
-	Chat   Chat `gorm:"foreignKey:ChatID" json:"chat,omitempty"`
-	Sender User `gorm:"foreignKey:SenderID" json:"sender,omitempty"`
 }
 
 type Notification struct {
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrPinLimitReached - в чате уже закреплено максимальное число сообщений
+var ErrPinLimitReached = errors.New("pinned messages limit reached")
+
+// PinnedMessageDTO - закреплённое сообщение целиком, для GET /v1/chat/{id}/pins
+type PinnedMessageDTO struct {
+	Message  MessageDTO `json:"message"`
+	PinnedBy uint       `json:"pinned_by"`
+	PinnedAt time.Time  `json:"pinned_at"`
+}
+
+// PinSummaryDTO - закреплённое сообщение в описании чата, с обрезанным текстом
+type PinSummaryDTO struct {
+	Message  *MessagePreviewDTO `json:"message"`
+	PinnedBy uint               `json:"pinned_by"`
+	PinnedAt time.Time          `json:"pinned_at"`
+}
+
+// ConvertToDTO преобразует PinnedMessage в PinnedMessageDTO с точки зрения пользователя viewerID
+func (pin *PinnedMessage) ConvertToDTO(viewerID uint) PinnedMessageDTO {
+	return PinnedMessageDTO{
+		Message:  pin.Message.ConvertToDTO(viewerID),
+		PinnedBy: pin.PinnedBy,
+		PinnedAt: pin.PinnedAt,
+	}
+}
+
+// ConvertToSummaryDTO преобразует PinnedMessage в PinSummaryDTO
+func (pin *PinnedMessage) ConvertToSummaryDTO() PinSummaryDTO {
+	return PinSummaryDTO{
+		Message:  pin.Message.ConvertToPreviewDTO(),
+		PinnedBy: pin.PinnedBy,
+		PinnedAt: pin.PinnedAt,
+	}
+}
+
+// orderPins ставит недавно закреплённые сообщения первыми
+func orderPins(db *gorm.DB) *gorm.DB {
+	return db.Order("pinned_at DESC, message_id DESC")
+}
+
+// Pin закрепляет сообщение чата. Строка чата блокируется, поэтому лимит не превышается при
+// параллельных запросах. Возвращает nil без ошибки, если сообщение уже закреплено
+func (repo messagePostgresRepo) Pin(chatID, messageID, userID uint, limit int) (*PinnedMessage, error) {
+	var pinned *PinnedMessage
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockChat(tx, chatID); err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&PinnedMessage{}).Where("chat_id = ?", chatID).Count(&count).Error; err != nil {
+			return err
+		}
+
+		var exists int64
+		err := tx.Model(&PinnedMessage{}).Where("chat_id = ? AND message_id = ?", chatID, messageID).Count(&exists).Error
+		if err != nil || exists > 0 {
+			return err
+		}
+
+		if count >= int64(limit) {
+			return ErrPinLimitReached
+		}
+
+		pin := PinnedMessage{ChatID: chatID, MessageID: messageID, PinnedBy: userID, PinnedAt: time.Now()}
+		if err := tx.Omit("Message").Clauses(clause.OnConflict{DoNothing: true}).Create(&pin).Error; err != nil {
+			return err
+		}
+		pinned = &pin
+		return nil
+	})
+
+	return pinned, err
+}
+
+// Unpin открепляет сообщение. Возвращает false, если оно не было закреплено
+func (repo messagePostgresRepo) Unpin(chatID, messageID uint) (bool, error) {
+	result := repo.db.Where("chat_id = ? AND message_id = ?", chatID, messageID).Delete(&PinnedMessage{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ListPins возвращает закреплённые сообщения чата, начиная с недавно закреплённых
+func (repo messagePostgresRepo) ListPins(chatID uint) ([]PinnedMessage, error) {
+	var pins []PinnedMessage
+	err := orderPins(repo.db).
+		Preload("Message.Sender").
+		Preload("Message.ReplyTo").
+		Preload("Message.Reactions", orderReactions).
+		Preload("Message.Attachments").
+		Scopes(preloadPoll("Message."), livePins).
+		Where("chat_id = ?", chatID).
+		Find(&pins).Error
+	return pins, err
+}
@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrPollClosed        = errors.New("poll is closed")
+	ErrPollSingleChoice  = errors.New("poll allows a single option")
+	ErrInvalidPollOption = errors.New("option does not belong to the poll")
+)
+
+// PollDTO - опрос в MessageDTO сообщения вида poll. Вопрос передаётся в content сообщения
+type PollDTO struct {
+	Multiple    bool            `json:"multiple"`
+	Anonymous   bool            `json:"anonymous"`
+	ClosesAt    *time.Time      `json:"closes_at,omitempty"`
+	Closed      bool            `json:"closed"`
+	TotalVoters int             `json:"total_voters"`
+	Options     []PollOptionDTO `json:"options"`
+}
+
+// PollOptionDTO - вариант с числом голосов. Voted - голосовал ли за него запросивший пользователь,
+// VoterIDs отдаются только для неанонимных опросов
+type PollOptionDTO struct {
+	ID       uint   `json:"id"`
+	Text     string `json:"text"`
+	Votes    int    `json:"votes"`
+	Voted    bool   `json:"voted"`
+	VoterIDs []uint `json:"voter_ids,omitempty"`
+}
+
+// IsClosed сообщает, закрыт ли опрос вручную или по времени на момент now
+func (poll *Poll) IsClosed(now time.Time) bool {
+	return poll.ClosedAt != nil || (poll.ClosesAt != nil && !poll.ClosesAt.After(now))
+}
+
+// ConvertToDTO подсчитывает голоса с точки зрения пользователя viewerID, для nil - nil
+func (poll *Poll) ConvertToDTO(viewerID uint) *PollDTO {
+	if poll == nil {
+		return nil
+	}
+
+	dto := &PollDTO{
+		Multiple:  poll.Multiple,
+		Anonymous: poll.Anonymous,
+		ClosesAt:  poll.ClosesAt,
+		Closed:    poll.IsClosed(time.Now()),
+		Options:   make([]PollOptionDTO, 0, len(poll.Options)),
+	}
+
+	positions := make(map[uint]int, len(poll.Options))
+	for _, option := range poll.Options {
+		positions[option.ID] = len(dto.Options)
+		dto.Options = append(dto.Options, PollOptionDTO{ID: option.ID, Text: option.Text})
+	}
+
+	voters := make(map[uint]bool)
+	for _, vote := range poll.Votes {
+		position, ok := positions[vote.OptionID]
+		if !ok {
+			continue
+		}
+
+		voters[vote.UserID] = true
+		option := &dto.Options[position]
+		option.Votes++
+		if vote.UserID == viewerID {
+			option.Voted = true
+		}
+		if !poll.Anonymous {
+			option.VoterIDs = append(option.VoterIDs, vote.UserID)
+		}
+	}
+	dto.TotalVoters = len(voters)
+
+	return dto
+}
+
+// preloadPoll подгружает опрос с вариантами и голосами по пути path ("" - у самих сообщений, "Messages." - у сообщений чата)
+func preloadPoll(path string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Preload(path+"Poll.Options", orderPollOptions).Preload(path+"Poll.Votes", orderPollVotes)
+	}
+}
+
+func orderPollOptions(db *gorm.DB) *gorm.DB {
+	return db.Order("position")
+}
+
+func orderPollVotes(db *gorm.DB) *gorm.DB {
+	return db.Order("created_at, user_id")
+}
+
+// Vote заменяет голоса пользователя в опросе на optionIDs, пустой список снимает голоса.
+// Строка опроса блокируется, поэтому голос не попадёт в опрос, закрытый параллельным запросом.
+// Возвращает опрос с вариантами и голосами после изменения
+func (repo messagePostgresRepo) Vote(messageID, userID uint, optionIDs []uint) (*Poll, error) {
+	var poll Poll
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := lockPoll(tx, messageID, &poll); err != nil {
+			return err
+		}
+
+		if poll.IsClosed(now) {
+			return ErrPollClosed
+		}
+
+		if err := tx.Where("message_id = ?", messageID).Scopes(orderPollOptions).Find(&poll.Options).Error; err != nil {
+			return err
+		}
+
+		votes, err := newVotes(&poll, userID, optionIDs, now)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("message_id = ? AND user_id = ?", messageID, userID).Delete(&PollVote{}).Error; err != nil {
+			return err
+		}
+
+		if len(votes) > 0 {
+			if err := tx.Create(&votes).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Where("message_id = ?", messageID).Scopes(orderPollVotes).Find(&poll.Votes).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &poll, nil
+}
+
+// newVotes проверяет выбор пользователя и строит голоса, повторяющиеся варианты учитываются один раз
+func newVotes(poll *Poll, userID uint, optionIDs []uint, now time.Time) ([]PollVote, error) {
+	known := make(map[uint]bool, len(poll.Options))
+	for _, option := range poll.Options {
+		known[option.ID] = true
+	}
+
+	votes := make([]PollVote, 0, len(optionIDs))
+	chosen := make(map[uint]bool, len(optionIDs))
+	for _, optionID := range optionIDs {
+		if !known[optionID] {
+			return nil, ErrInvalidPollOption
+		}
+		if chosen[optionID] {
+			continue
+		}
+
+		chosen[optionID] = true
+		votes = append(votes, PollVote{MessageID: poll.MessageID, UserID: userID, OptionID: optionID, CreatedAt: now})
+	}
+
+	if !poll.Multiple && len(votes) > 1 {
+		return nil, ErrPollSingleChoice
+	}
+
+	return votes, nil
+}
+
+// ClosePoll досрочно закрывает опрос. Возвращает nil без ошибки, если опрос уже закрыт
+func (repo messagePostgresRepo) ClosePoll(messageID uint) (*Poll, error) {
+	var closed *Poll
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var poll Poll
+		now := time.Now()
+		if err := lockPoll(tx, messageID, &poll); err != nil {
+			return err
+		}
+
+		if poll.IsClosed(now) {
+			return nil
+		}
+
+		if err := tx.Model(&poll).Update("closed_at", now).Error; err != nil {
+			return err
+		}
+		poll.ClosedAt = &now
+
+		if err := tx.Where("message_id = ?", messageID).Scopes(orderPollOptions).Find(&poll.Options).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("message_id = ?", messageID).Scopes(orderPollVotes).Find(&poll.Votes).Error; err != nil {
+			return err
+		}
+
+		closed = &poll
+		return nil
+	})
+	return closed, err
+}
+
+func lockPoll(tx *gorm.DB, messageID uint, poll *Poll) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(poll, "message_id = ?", messageID).Error
+}
@@ -8,6 +8,8 @@ type Repository interface {
 	Friendship() FriendshipRepository
 	Chats() ChatRepository
 	Messages() MessageRepository
+	Attachments() AttachmentRepository
+	ScheduledMessages() ScheduledMessageRepository
 	// Notifications() NotificationRepository
 }
 
@@ -17,6 +19,8 @@ type postgresRepo struct {
 	friendship    FriendshipRepository
 	chats         ChatRepository
 	messages      MessageRepository
+	attachments   AttachmentRepository
+	scheduled     ScheduledMessageRepository
 	// notifications NotificationRepository
 }
 
@@ -27,6 +31,8 @@ func NewPostgresRepo(db *gorm.DB) Repository {
 		friendship:    NewPostgresFriendshipRepo(db),
 		chats:         NewPostgresChatRepo(db),
 		messages:      NewPostgresMessageRepo(db),
+		attachments:   NewPostgresAttachmentRepo(db),
+		scheduled:     NewPostgresScheduledMessageRepo(db),
 		// notifications: NewPostgresNotificationRepo(db),
 	}
 }
@@ -51,6 +57,14 @@ func (r *postgresRepo) Messages() MessageRepository {
 	return r.messages
 }
 
+func (r *postgresRepo) Attachments() AttachmentRepository {
+	return r.attachments
+}
+
+func (r *postgresRepo) ScheduledMessages() ScheduledMessageRepository {
+	return r.scheduled
+}
+
 // func (r *PostgresRepo) Messages() MessageRepository {
 // 	return r.messages
 // }
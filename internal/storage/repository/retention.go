@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ExpiredMessage - сообщение, удалённое сборщиком по сроку хранения
+type ExpiredMessage struct {
+	ID     uint
+	ChatID uint
+}
+
+// SetRetention меняет сроки хранения сообщений чата, nil оставляет значение как есть.
+// Возвращает системное сообщение retention_changed или nil, если ничего не изменилось
+func (repo chatPostgresRepo) SetRetention(chatID, actorID uint, retentionSeconds, messageTTLSeconds *int64) (*Message, error) {
+	var message *Message
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var chat Chat
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("archived_at IS NULL").
+			First(&chat, chatID).Error
+		if err != nil {
+			return err
+		}
+
+		event := SystemEvent{Action: SystemRetentionChanged}
+		changes := make(map[string]interface{})
+		if retentionSeconds != nil && *retentionSeconds != chat.RetentionSeconds {
+			changes["retention_seconds"] = *retentionSeconds
+			event.RetentionSeconds = retentionSeconds
+		}
+		if messageTTLSeconds != nil && *messageTTLSeconds != chat.MessageTTLSeconds {
+			changes["message_ttl_seconds"] = *messageTTLSeconds
+			event.MessageTTLSeconds = messageTTLSeconds
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&chat).Updates(changes).Error; err != nil {
+			return err
+		}
+
+		record := systemMessage(chatID, actorID, event)
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		message = &record
+		return nil
+	})
+
+	return message, err
+}
+
+// stampExpiry задаёт ExpiresAt обычным сообщениям чатов с исчезающими сообщениями
+func stampExpiry(tx *gorm.DB, messages []*Message) error {
+	chatIDs := make([]uint, 0, len(messages))
+	for _, message := range messages {
+		chatIDs = append(chatIDs, message.ChatID)
+	}
+
+	var chats []Chat
+	err := tx.Select("id", "message_ttl_seconds").
+		Where("id IN ? AND message_ttl_seconds > 0", chatIDs).
+		Find(&chats).Error
+	if err != nil || len(chats) == 0 {
+		return err
+	}
+
+	ttls := make(map[uint]time.Duration, len(chats))
+	for _, chat := range chats {
+		ttls[chat.ID] = time.Duration(chat.MessageTTLSeconds) * time.Second
+	}
+
+	now := time.Now()
+	for _, message := range messages {
+		ttl, ok := ttls[message.ChatID]
+		if !ok || message.Kind == MessageKindSystem {
+			continue
+		}
+		expiresAt := now.Add(ttl)
+		message.ExpiresAt = &expiresAt
+	}
+	return nil
+}
+
+// liveMessagesSQL скрывает сообщения, у которых истёк ExpiresAt или срок хранения чата,
+// но сборщик их ещё не удалил. Условия те же, что в DeleteExpired, без глобального maxAge
+const liveMessagesSQL = `(messages.expires_at IS NULL OR messages.expires_at > NOW()) AND NOT EXISTS (
+	SELECT 1 FROM chats c
+	WHERE c.id = messages.chat_id AND c.retention_seconds > 0
+		AND messages.created_at < NOW() - c.retention_seconds * INTERVAL '1 second'
+)`
+
+func liveMessages(db *gorm.DB) *gorm.DB {
+	return db.Where(liveMessagesSQL)
+}
+
+// livePins оставляет закрепления только ещё видимых сообщений
+func livePins(db *gorm.DB) *gorm.DB {
+	return db.Where("message_id IN (SELECT id FROM messages WHERE " + liveMessagesSQL + ")")
+}
+
+// DeleteExpired безвозвратно удаляет до limit сообщений, у которых истёк ExpiresAt, срок хранения
+// чата или глобальный предел maxAge (0 - без предела), вместе с реакциями, правками, закреплениями
+// и записями вложений. Строки берутся через FOR UPDATE SKIP LOCKED, поэтому сборщики на разных
+// узлах не мешают друг другу. Возвращает удалённые сообщения и ключи файлов вложений, которые
+// нужно удалить из хранилища после коммита
+func (repo messagePostgresRepo) DeleteExpired(now time.Time, maxAge time.Duration, limit int) ([]ExpiredMessage, []string, error) {
+	var expired []ExpiredMessage
+	var keys []string
+
+	conditions := "m.expires_at <= @now OR (c.retention_seconds > 0 AND m.created_at < @now - c.retention_seconds * INTERVAL '1 second')"
+	if maxAge > 0 {
+		conditions += " OR m.created_at < @cutoff"
+	}
+
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Raw(fmt.Sprintf(`
+			SELECT m.id, m.chat_id FROM messages m
+			JOIN chats c ON c.id = m.chat_id
+			WHERE (%s)
+			ORDER BY m.id
+			LIMIT @limit
+			FOR UPDATE OF m SKIP LOCKED
+		`, conditions), map[string]interface{}{"now": now, "cutoff": now.Add(-maxAge), "limit": limit}).Scan(&expired).Error
+		if err != nil || len(expired) == 0 {
+			return err
+		}
+
+		ids := make([]uint, 0, len(expired))
+		for _, message := range expired {
+			ids = append(ids, message.ID)
+		}
+
+		var attachments []Attachment
+		if err := tx.Where("message_id IN ?", ids).Find(&attachments).Error; err != nil {
+			return err
+		}
+		for _, attachment := range attachments {
+			keys = append(keys, attachment.StorageKey)
+			if attachment.ThumbnailKey != nil {
+				keys = append(keys, *attachment.ThumbnailKey)
+			}
+		}
+
+		for _, dependent := range []interface{}{&Attachment{}, &MessageReaction{}, &MessageRevision{}, &PinnedMessage{}, &MessageMention{}, &PollVote{}, &PollOption{}, &Poll{}} {
+			if err := tx.Where("message_id IN ?", ids).Delete(dependent).Error; err != nil {
+				return err
+			}
+		}
+
+		keys, err = unusedKeys(tx, keys)
+		if err != nil {
+			return err
+		}
+
+		// Ответы, которые остаются в истории, теряют цитату, а их родители - счётчик ответов.
+		// Удалённые ответы из счётчика уже вычтены в SoftDelete
+		err = tx.Exec(`
+			UPDATE messages p SET reply_count = p.reply_count - r.count
+			FROM (
+				SELECT reply_to_id, COUNT(*) AS count FROM messages
+				WHERE id IN ? AND reply_to_id IS NOT NULL AND deleted_at IS NULL
+				GROUP BY reply_to_id
+			) r
+			WHERE p.id = r.reply_to_id AND p.id NOT IN ?
+		`, ids, ids).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&Message{}).
+			Where("reply_to_id IN ? AND id NOT IN ?", ids, ids).
+			UpdateColumn("reply_to_id", nil).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&ScheduledMessage{}).
+			Where("reply_to_id IN ?", ids).
+			UpdateColumn("reply_to_id", nil).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(&Message{}, ids).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return expired, keys, nil
+}
+
+// Файл пересланного вложения общий с копиями в других сообщениях и удаляется вместе с последней копией.
+// unusedKeys оставляет ключи, на которые после удаления записей больше никто не ссылается
+func unusedKeys(tx *gorm.DB, keys []string) ([]string, error) {
+	if len(keys) == 0 {
+		return keys, nil
+	}
+
+	var inUse []string
+	err := tx.Raw(
+		"SELECT storage_key FROM attachments WHERE storage_key IN @keys UNION SELECT thumbnail_key FROM attachments WHERE thumbnail_key IN @keys",
+		map[string]interface{}{"keys": keys},
+	).Scan(&inUse).Error
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(keys, func(key string) bool { return slices.Contains(inUse, key) }), nil
+}
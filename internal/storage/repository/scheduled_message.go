@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ScheduledMessageDTO - отложенное сообщение для его отправителя
+type ScheduledMessageDTO struct {
+	ID        uint      `json:"id"`
+	ChatID    uint      `json:"chat_id"`
+	Content   string    `json:"content"`
+	ReplyToID *uint     `json:"reply_to_id,omitempty"`
+	SendAt    time.Time `json:"send_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ConvertToDTO преобразует ScheduledMessage в ScheduledMessageDTO
+func (scheduled *ScheduledMessage) ConvertToDTO() ScheduledMessageDTO {
+	return ScheduledMessageDTO{
+		ID:        scheduled.ID,
+		ChatID:    scheduled.ChatID,
+		Content:   scheduled.Content,
+		ReplyToID: scheduled.ReplyToID,
+		SendAt:    scheduled.SendAt,
+		CreatedAt: scheduled.CreatedAt,
+		UpdatedAt: scheduled.UpdatedAt,
+	}
+}
+
+type ScheduledMessageRepository interface {
+	Create(scheduled *ScheduledMessage) error
+	List(chatID, senderID uint) ([]ScheduledMessage, error)
+	Update(scheduledID, chatID, senderID uint, changes map[string]interface{}) (*ScheduledMessage, error)
+	Delete(scheduledID, chatID, senderID uint) (bool, error)
+	DeliverDue(now time.Time, limit int) ([]Message, error)
+}
+
+type scheduledMessagePostgresRepo struct {
+	db *gorm.DB
+}
+
+func NewPostgresScheduledMessageRepo(db *gorm.DB) ScheduledMessageRepository {
+	return scheduledMessagePostgresRepo{db: db}
+}
+
+func (repo scheduledMessagePostgresRepo) Create(scheduled *ScheduledMessage) error {
+	return repo.db.Create(scheduled).Error
+}
+
+// List возвращает ещё не отправленные сообщения пользователя в чате, начиная с ближайших
+func (repo scheduledMessagePostgresRepo) List(chatID, senderID uint) ([]ScheduledMessage, error) {
+	var scheduled []ScheduledMessage
+	err := repo.db.
+		Where("chat_id = ? AND sender_id = ?", chatID, senderID).
+		Order("send_at, id").
+		Find(&scheduled).Error
+	return scheduled, err
+}
+
+// Update меняет неотправленное сообщение. Если оно уже отправлено, отменено или принадлежит
+// другому пользователю, возвращает nil без ошибки. Отправляемая прямо сейчас запись заблокирована
+// воркером, поэтому изменение дождётся конца отправки и не найдёт её
+func (repo scheduledMessagePostgresRepo) Update(scheduledID, chatID, senderID uint, changes map[string]interface{}) (*ScheduledMessage, error) {
+	var scheduled ScheduledMessage
+	result := repo.db.Model(&scheduled).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND chat_id = ? AND sender_id = ?", scheduledID, chatID, senderID).
+		Updates(changes)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+
+	return &scheduled, nil
+}
+
+// Delete отменяет неотправленное сообщение. Возвращает false, если отменять нечего
+func (repo scheduledMessagePostgresRepo) Delete(scheduledID, chatID, senderID uint) (bool, error) {
+	result := repo.db.Where("id = ? AND chat_id = ? AND sender_id = ?", scheduledID, chatID, senderID).Delete(&ScheduledMessage{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeliverDue превращает до limit наступивших отложенных сообщений в обычные и удаляет их записи
+// в одной транзакции. Строки берутся через FOR UPDATE SKIP LOCKED, поэтому несколько воркеров
+// на разных узлах разбирают очередь без пересечений, а после сбоя незакоммиченная пачка
+// достанется следующему воркеру. Сообщения от пользователей, покинувших чат, и в архивные чаты
+// отбрасываются. Возвращает созданные сообщения с превью цитат
+func (repo scheduledMessagePostgresRepo) DeliverDue(now time.Time, limit int) ([]Message, error) {
+	var delivered []Message
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var due []ScheduledMessage
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("send_at <= ?", now).
+			Order("send_at, id").
+			Limit(limit).
+			Find(&due).Error
+		if err != nil || len(due) == 0 {
+			return err
+		}
+
+		senders := make([]ChatSender, 0, len(due))
+		for _, scheduled := range due {
+			senders = append(senders, ChatSender{ChatID: scheduled.ChatID, SenderID: scheduled.SenderID})
+		}
+
+		allowed, err := activeSenders(tx, senders)
+		if err != nil {
+			return err
+		}
+
+		ids := make([]uint, 0, len(due))
+		messages := make([]*Message, 0, len(due))
+		for _, scheduled := range due {
+			ids = append(ids, scheduled.ID)
+			if !allowed[ChatSender{ChatID: scheduled.ChatID, SenderID: scheduled.SenderID}] {
+				continue
+			}
+			messages = append(messages, &Message{
+				ChatID:    scheduled.ChatID,
+				SenderID:  scheduled.SenderID,
+				Content:   scheduled.Content,
+				Kind:      MessageKindText,
+				ReplyToID: scheduled.ReplyToID,
+			})
+		}
+
+		if len(messages) > 0 {
+			if err := createBatch(tx, messages); err != nil {
+				return err
+			}
+			if err := loadReplyPreviews(tx, messages); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Delete(&ScheduledMessage{}, ids).Error; err != nil {
+			return err
+		}
+
+		for _, message := range messages {
+			delivered = append(delivered, *message)
+		}
+		return nil
+	})
+
+	return delivered, err
+}
+
+// loadReplyPreviews заполняет ReplyTo у сообщений-ответов одним запросом
+func loadReplyPreviews(tx *gorm.DB, messages []*Message) error {
+	var parentIDs []uint
+	for _, message := range messages {
+		if message.ReplyToID != nil {
+			parentIDs = append(parentIDs, *message.ReplyToID)
+		}
+	}
+	if len(parentIDs) == 0 {
+		return nil
+	}
+
+	var parents []Message
+	if err := tx.Find(&parents, parentIDs).Error; err != nil {
+		return err
+	}
+
+	byID := make(map[uint]*Message, len(parents))
+	for i := range parents {
+		byID[parents[i].ID] = &parents[i]
+	}
+	for _, message := range messages {
+		if message.ReplyToID != nil {
+			message.ReplyTo = byID[*message.ReplyToID]
+		}
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -10,6 +12,10 @@ type UserRepository interface {
 	EmailExists(email string) (bool, error)
 	GetAll(excludeID *uint) ([]User, error)
 	IDsExists(IDs []uint) (bool, error)
+	GetPresence(IDs []uint) ([]User, error)
+	GetContactIDs(userID uint) ([]uint, error)
+	SetLastSeen(userID uint, at time.Time) error
+	SetHidePresence(userID uint, hide bool) error
 }
 
 type userPostgresRepo struct {
@@ -71,3 +77,35 @@ func (repo userPostgresRepo) IDsExists(IDs []uint) (bool, error) {
 	}
 	return count == int64(len(IDs)), nil
 }
+
+func (repo userPostgresRepo) GetPresence(IDs []uint) ([]User, error) {
+	var users []User
+	err := repo.db.
+		Select("id", "last_seen_at", "hide_presence").
+		Where("id IN ?", IDs).
+		Find(&users).Error
+	return users, err
+}
+
+// GetContactIDs возвращает друзей пользователя и его собеседников по чатам
+func (repo userPostgresRepo) GetContactIDs(userID uint) ([]uint, error) {
+	var IDs []uint
+	err := repo.db.Raw(`
+		SELECT receiver_id FROM friendships WHERE sender_id = @user AND status = @status
+		UNION
+		SELECT sender_id FROM friendships WHERE receiver_id = @user AND status = @status
+		UNION
+		SELECT other.user_id FROM user_chats own
+		JOIN user_chats other ON other.chat_id = own.chat_id
+		WHERE own.user_id = @user AND other.user_id <> @user
+	`, map[string]interface{}{"user": userID, "status": StatusFriendship}).Scan(&IDs).Error
+	return IDs, err
+}
+
+func (repo userPostgresRepo) SetLastSeen(userID uint, at time.Time) error {
+	return repo.db.Model(&User{}).Where("id = ?", userID).Update("last_seen_at", at).Error
+}
+
+func (repo userPostgresRepo) SetHidePresence(userID uint, hide bool) error {
+	return repo.db.Model(&User{}).Where("id = ?", userID).Update("hide_presence", hide).Error
+}
@@ -0,0 +1,77 @@
+package search
+
+import (
+	"context"
+	"html"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Конфигурация текстового поиска. Должна совпадать с выражением индекса idx_messages_content_fts
+const textSearchConfig = "simple"
+
+// Маркеры совпадений в ts_headline. Управляющие символы не встречаются в обычном тексте,
+// поэтому после экранирования их можно безопасно заменить на теги
+const (
+	startSel = "\x02"
+	stopSel  = "\x03"
+)
+
+const headlineOptions = "StartSel=" + startSel + ", StopSel=" + stopSel + ", MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=\" … \""
+
+// Индекс создаётся при миграции, AutoMigrate не умеет индексы по выражению
+const CreateIndexSQL = "CREATE INDEX IF NOT EXISTS idx_messages_content_fts ON messages USING GIN (to_tsvector('" + textSearchConfig + "', content))"
+
+// Реализация на tsvector в Postgres
+type postgresSearcher struct {
+	db *gorm.DB
+}
+
+func NewPostgresSearcher(db *gorm.DB) Searcher {
+	return postgresSearcher{db: db}
+}
+
+func (s postgresSearcher) Search(ctx context.Context, query Query) ([]Hit, error) {
+	tx := s.db.WithContext(ctx).
+		Table("messages AS m").
+		Select("m.id AS message_id, m.chat_id, m.sender_id, m.created_at, ts_headline(?, m.content, q.query, ?) AS snippet", textSearchConfig, headlineOptions).
+		Joins("JOIN user_chats uc ON uc.chat_id = m.chat_id AND uc.user_id = ?", query.UserID).
+		Joins("CROSS JOIN websearch_to_tsquery(?, ?) AS q(query)", textSearchConfig, query.Text).
+		Where("to_tsvector('" + textSearchConfig + "', m.content) @@ q.query").
+		Where("m.deleted_at IS NULL AND m.kind = 'text'").
+		// Истёкшие сообщения, которые сборщик ещё не удалил, в выдачу не попадают
+		Where("m.expires_at IS NULL OR m.expires_at > NOW()").
+		Where("NOT EXISTS (SELECT 1 FROM chats c WHERE c.id = m.chat_id AND c.retention_seconds > 0 AND m.created_at < NOW() - c.retention_seconds * INTERVAL '1 second')")
+
+	if query.ChatID != nil {
+		tx = tx.Where("m.chat_id = ?", *query.ChatID)
+	}
+	if query.SenderID != nil {
+		tx = tx.Where("m.sender_id = ?", *query.SenderID)
+	}
+	if query.From != nil {
+		tx = tx.Where("m.created_at >= ?", *query.From)
+	}
+	if query.To != nil {
+		tx = tx.Where("m.created_at < ?", *query.To)
+	}
+	if query.BeforeID != nil {
+		tx = tx.Where("m.id < ?", *query.BeforeID)
+	}
+
+	var hits []Hit
+	if err := tx.Order("m.id DESC").Limit(query.Limit).Scan(&hits).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range hits {
+		hits[i].Snippet = highlight(hits[i].Snippet)
+	}
+	return hits, nil
+}
+
+// highlight экранирует фрагмент и заменяет маркеры ts_headline на <mark>
+func highlight(snippet string) string {
+	return strings.NewReplacer(startSel, "<mark>", stopSel, "</mark>").Replace(html.EscapeString(snippet))
+}
@@ -0,0 +1,108 @@
+//go:build integration
+
+package search_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"socialAPI/internal/storage"
+	"socialAPI/internal/storage/repository"
+	"socialAPI/internal/storage/search"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// База из docker-compose. Схема и индекс поиска создаются миграцией
+func newTestDB(t *testing.T) *gorm.DB {
+	dsn := fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		getEnv("DB_HOST", "localhost"),
+		getEnv("DB_PORT", "5432"),
+		getEnv("DB_USER", "postgres"),
+		getEnv("DB_PASSWORD", "postgres"),
+		getEnv("DB_NAME", "socialdb"),
+	)
+
+	db, err := storage.BootstrapDatabase(dsn)
+	if err != nil {
+		t.Skipf("postgres is not available: %v", err)
+	}
+	storage.MadeMigrations(db)
+	return db
+}
+
+func TestPostgresSearcher(t *testing.T) {
+	db := newTestDB(t)
+	searcher := search.NewPostgresSearcher(db)
+	ctx := context.Background()
+
+	// Уникальное слово, чтобы не находить сообщения других прогонов в общей базе
+	word := fmt.Sprintf("needle%d", time.Now().UnixNano())
+
+	users := []repository.User{
+		{Email: word + "-viewer@example.com"},
+		{Email: word + "-stranger@example.com"},
+	}
+	require.NoError(t, db.Create(&users).Error)
+	viewer, stranger := users[0], users[1]
+
+	createChat := func(retentionSeconds int64, member repository.User) repository.Chat {
+		chat := repository.Chat{Type: repository.ChatTypeGroup, Name: "search", RetentionSeconds: retentionSeconds}
+		require.NoError(t, db.Create(&chat).Error)
+		require.NoError(t, db.Create(&repository.ChatMember{UserID: member.ID, ChatID: chat.ID, Role: repository.ChatRoleMember}).Error)
+		return chat
+	}
+	createMessage := func(chat repository.Chat, sender repository.User, content string, createdAt time.Time, expiresAt *time.Time) repository.Message {
+		message := repository.Message{ChatID: chat.ID, SenderID: sender.ID, Kind: repository.MessageKindText, Content: content, CreatedAt: createdAt, ExpiresAt: expiresAt}
+		require.NoError(t, db.Create(&message).Error)
+		return message
+	}
+
+	now := time.Now()
+	expiredAt := now.Add(-time.Minute)
+
+	own := createChat(0, viewer)
+	first := createMessage(own, viewer, "first "+word, now, nil)
+	second := createMessage(own, viewer, "second "+word, now, nil)
+	third := createMessage(own, viewer, "third "+word, now, nil)
+	createMessage(own, viewer, word+" disappeared", now, &expiredAt)
+
+	// Сообщение старше срока хранения чата сборщик ещё не удалил
+	retained := createChat(60, viewer)
+	createMessage(retained, viewer, word+" too old", now.Add(-time.Hour), nil)
+
+	foreign := createChat(0, stranger)
+	createMessage(foreign, stranger, word+" not yours", now, nil)
+
+	hits, err := searcher.Search(ctx, search.Query{Text: word, UserID: viewer.ID, Limit: 10})
+	require.NoError(t, err)
+
+	var ids []uint
+	for _, hit := range hits {
+		ids = append(ids, hit.MessageID)
+	}
+	assert.Equal(t, []uint{third.ID, second.ID, first.ID}, ids)
+	assert.Contains(t, hits[0].Snippet, "<mark>"+word+"</mark>")
+
+	// Следующая страница начинается после последнего найденного сообщения
+	hits, err = searcher.Search(ctx, search.Query{Text: word, UserID: viewer.ID, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, hits, 2)
+
+	hits, err = searcher.Search(ctx, search.Query{Text: word, UserID: viewer.ID, BeforeID: &hits[1].MessageID, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, first.ID, hits[0].MessageID)
+}
@@ -0,0 +1,13 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlight(t *testing.T) {
+	snippet := "<b>tom</b> & " + startSel + "jerry" + stopSel + " …"
+
+	assert.Equal(t, "&lt;b&gt;tom&lt;/b&gt; &amp; <mark>jerry</mark> …", highlight(snippet))
+}
@@ -0,0 +1,33 @@
+package search
+
+import (
+	"context"
+	"time"
+)
+
+// Query - параметры поиска по сообщениям. Поиск идёт только в чатах UserID,
+// остальные фильтры необязательны. Результаты отдаются от новых к старым, BeforeID - курсор
+type Query struct {
+	Text     string
+	UserID   uint
+	ChatID   *uint
+	SenderID *uint
+	From     *time.Time
+	To       *time.Time
+	BeforeID *uint
+	Limit    int
+}
+
+// Hit - найденное сообщение. В Snippet текст экранирован для HTML, совпадения обёрнуты в <mark>
+type Hit struct {
+	MessageID uint
+	ChatID    uint
+	SenderID  uint
+	Snippet   string
+	CreatedAt time.Time
+}
+
+// Searcher - движок полнотекстового поиска по истории сообщений
+type Searcher interface {
+	Search(ctx context.Context, query Query) ([]Hit, error)
+}